@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jsaund/gorest/generate"
+)
+
+// runCLI generates a command line interface with a command per request builder of the input.
+// Returns the exit code of the command.
+func runCLI(args []string) int {
+	flags := flag.NewFlagSet("cli", flag.ExitOnError)
+	flags.StringVar(input, "input", "", "name of input file or package directory containing REST API to generate a command line interface for (if absent then Stdin is used)")
+	flags.StringVar(output, "output", "", "name of output file containing the generated command line interface")
+	flags.StringVar(pkg, "pkg", "", "name of the package containing the REST API")
+	flags.BoolVar(strict, "strict", true, "fail when an annotation is not known to gorest, which is usually a misspelled annotation")
+	flags.Parse(args)
+
+	if *output == "" {
+		flags.Usage()
+		fmt.Fprintln(os.Stderr, "Expects valid output filename")
+		return 1
+	}
+
+	if *pkg == "" {
+		flags.Usage()
+		fmt.Fprintln(os.Stderr, "Expects valid package name")
+		return 1
+	}
+
+	source, err := generate.GenerateCLI(parseInput(*input, *pkg))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to generate command line interface. %s\n", err)
+		return 1
+	}
+	if err := writeFile(*output, source); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write command line interface to file %s. Reason: %s\n", *output, err)
+		return 1
+	}
+	fmt.Println("Generated source written to file " + *output)
+	return 0
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/jsaund/gorest/restclient"
+)
+
+// runConformance runs the generated conformance tests of a package against a sandbox environment
+// and prints a report of the results. Returns the exit code of the command.
+func runConformance(args []string) int {
+	flags := flag.NewFlagSet("conformance", flag.ExitOnError)
+	dir := flags.String("dir", ".", "directory of the package containing the generated REST API implementation")
+	baseURL := flags.String("url", "", "base URL of the sandbox environment the requests are sent to")
+	report := flags.String("report", "conformance.jsonl", "name of the report file the results are written to")
+	flags.Parse(args)
+
+	if *baseURL == "" {
+		flags.Usage()
+		fmt.Fprintln(os.Stderr, "Expects valid sandbox URL")
+		return 1
+	}
+
+	reportPath, err := filepath.Abs(*report)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid report filename %s. Reason: %s\n", *report, err)
+		return 1
+	}
+	if err := os.Remove(reportPath); err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Failed to remove previous report %s. Reason: %s\n", reportPath, err)
+		return 1
+	}
+
+	cmd := exec.Command("go", "test", "-tags", "conformance", "-run", "^TestConformance", "-count", "1", ".")
+	cmd.Dir = *dir
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		restclient.SandboxURLEnv+"="+*baseURL,
+		restclient.ConformanceReportEnv+"="+reportPath,
+	)
+	testErr := cmd.Run()
+
+	results, err := restclient.ReadConformanceReport(reportPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read conformance report. Reason: %s\n", err)
+		return 1
+	}
+
+	failed := 0
+	for _, result := range results {
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("%s\t%s #%d\t%s %s\t%d\t%s\n", status, result.Endpoint, result.Example, result.Method, result.URL, result.Status, result.Error)
+	}
+	fmt.Printf("%d of %d examples passed. Report written to %s\n", len(results)-failed, len(results), reportPath)
+
+	if failed > 0 || testErr != nil {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jsaund/gorest/generate"
+)
+
+// runDocs generates the Markdown reference of the request builders of the input. Returns the exit
+// code of the command.
+func runDocs(args []string) int {
+	flags := flag.NewFlagSet("docs", flag.ExitOnError)
+	flags.StringVar(input, "input", "", "name of input file or package directory containing REST API to document (if absent then Stdin is used)")
+	flags.StringVar(output, "output", "", "name of output file containing the Markdown reference")
+	flags.StringVar(pkg, "pkg", "", "name of the package containing the REST API")
+	flags.BoolVar(strict, "strict", true, "fail when an annotation is not known to gorest, which is usually a misspelled annotation")
+	flags.Parse(args)
+
+	if *output == "" {
+		flags.Usage()
+		fmt.Fprintln(os.Stderr, "Expects valid output filename")
+		return 1
+	}
+
+	if *pkg == "" {
+		flags.Usage()
+		fmt.Fprintln(os.Stderr, "Expects valid package name")
+		return 1
+	}
+
+	docs, err := generate.GenerateDocs(parseInput(*input, *pkg))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to generate REST API reference. %s\n", err)
+		return 1
+	}
+	if err := writeFile(*output, docs); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write reference to file %s. Reason: %s\n", *output, err)
+		return 1
+	}
+	fmt.Println("Reference written to file " + *output)
+	return 0
+}
@@ -0,0 +1,236 @@
+package generate
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/jsaund/gorest/parse"
+)
+
+var _ = template.Must(templates.New("cli").Funcs(template.FuncMap{
+	"CLICommands": getCLICommands,
+}).Parse(`/*
+* CODE GENERATED AUTOMATICALLY WITH GOREST (github.com/jsaund/gorest)
+* THIS FILE SHOULD NOT BE EDITED BY HAND
+*/
+
+package {{ .PackageName }}
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jsaund/gorest/restclient"
+	"github.com/spf13/cobra"
+)
+
+// NewCommand returns a command line interface with a command per request builder, which prints the
+// response as JSON. The client is configured by the environment variables read by
+// restclient.NewClientFromEnv, such as GOREST_BASE_URL.
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "{{ .PackageName }}",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			client, err := restclient.NewClientFromEnv()
+			if err != nil {
+				return err
+			}
+			restclient.RegisterClient(client)
+			return nil
+		},
+	}
+{{- range CLICommands .Builders }}
+	cmd.AddCommand(new{{ .RequestType }}Command())
+{{- end }}
+	return cmd
+}
+{{ range CLICommands .Builders }}
+func new{{ .RequestType }}Command() *cobra.Command {
+{{- if .Flags }}
+	var params struct {
+{{- range .Flags }}
+		{{ .Setter }} {{ .Type }}
+{{- end }}
+	}
+{{- end }}
+	cmd := &cobra.Command{
+		Use:   "{{ .Use }}",
+		Short: {{ printf "%q" .Short }},
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			builder := New{{ .RequestType }}()
+{{- range .Flags }}
+			if cmd.Flags().Changed("{{ .Name }}") {
+				builder = builder.{{ .Setter }}(params.{{ .Setter }})
+			}
+{{- end }}
+{{- if .NoContent }}
+			return builder.{{ .Run }}
+{{- else }}
+			result, err := builder.{{ .Run }}
+			if err != nil {
+				return err
+			}
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(result)
+{{- end }}
+		},
+	}
+{{- range .Flags }}
+	cmd.Flags().{{ .Func }}(&params.{{ .Setter }}, "{{ .Name }}", {{ .Default }}, {{ printf "%q" .Usage }})
+{{- if .Required }}
+	cmd.MarkFlagRequired("{{ .Name }}")
+{{- end }}
+{{- end }}
+	return cmd
+}
+{{ end }}`))
+
+// cliFlagTypes are the types of setter parameters which can be set with a flag, along with the
+// function of the flag set defining the flag and its default value
+var cliFlagTypes = map[string][2]string{
+	"string":        {"StringVar", `""`},
+	"bool":          {"BoolVar", "false"},
+	"int":           {"IntVar", "0"},
+	"int8":          {"Int8Var", "0"},
+	"int16":         {"Int16Var", "0"},
+	"int32":         {"Int32Var", "0"},
+	"int64":         {"Int64Var", "0"},
+	"uint":          {"UintVar", "0"},
+	"uint8":         {"Uint8Var", "0"},
+	"uint16":        {"Uint16Var", "0"},
+	"uint32":        {"Uint32Var", "0"},
+	"uint64":        {"Uint64Var", "0"},
+	"float32":       {"Float32Var", "0"},
+	"float64":       {"Float64Var", "0"},
+	"[]string":      {"StringSliceVar", "nil"},
+	"[]int":         {"IntSliceVar", "nil"},
+	"time.Duration": {"DurationVar", "0"},
+}
+
+// cliCommand is the command of a request builder in the generated command line interface
+type cliCommand struct {
+	RequestType string
+	Use         string
+	Short       string
+	Run         string
+	// NoContent is true when the @SYNC method returns only an error, so there is nothing to print
+	NoContent bool
+	Flags     []cliFlag
+}
+
+// cliFlag is a flag of a command setting a parameter of the request
+type cliFlag struct {
+	Name     string
+	Setter   string
+	Type     string
+	Func     string
+	Default  string
+	Usage    string
+	Required bool
+}
+
+// GenerateCLI generates a command line interface based on cobra for the request builders, with a
+// command per request builder. Flags are derived from the @PATH, @QUERY and @FIELD setters whose
+// parameter is a basic type. Request builders without a @SYNC method are left out.
+func GenerateCLI(results []*parse.ParseResult) ([]byte, error) {
+	if len(results) == 0 {
+		return nil, fmt.Errorf("No request builders to generate")
+	}
+	return render("cli", newFileData(results, Options{}))
+}
+
+// getCLICommands returns the commands of the request builders which can be run from the command line
+func getCLICommands(builders []templateData) []cliCommand {
+	var commands []cliCommand
+	for _, b := range builders {
+		if command, ok := getCLICommand(b.ParseResult); ok {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// getCLICommand returns the command of the request builder, which is run by its @SYNC method.
+// Returns false when the request builder has no @SYNC method or it takes parameters other than a context.
+// Example: GetPhotosRequestBuilder -> get-photos
+func getCLICommand(r *parse.ParseResult) (cliCommand, bool) {
+	if r.SyncResponse == nil {
+		return cliCommand{}, false
+	}
+	var args []string
+	for _, p := range r.SyncResponse.Type.(*ast.FuncType).Params.List {
+		if getParamType(p.Type) != "context.Context" {
+			return cliCommand{}, false
+		}
+		args = append(args, "cmd.Context()")
+	}
+
+	short := getDocText(r.Doc)
+	if i := strings.Index(short, "\n"); i >= 0 {
+		short = short[:i]
+	}
+	command := cliCommand{
+		RequestType: r.RequestType,
+		Use:         getCLIName(strings.TrimSuffix(r.RequestType, "RequestBuilder")),
+		Short:       short,
+		Run:         getFunctionName(r.SyncResponse) + "(" + strings.Join(args, ", ") + ")",
+		NoContent:   r.ResponseType == "",
+	}
+
+	used := make(map[string]bool)
+	for _, g := range []struct {
+		in     string
+		params map[string]*ast.Field
+	}{
+		{"path", r.PathSubstitutions},
+		{"query", r.QueryParams},
+		{"field", r.PostFormParams},
+	} {
+		var flags []cliFlag
+		for _, f := range g.params {
+			function := f.Type.(*ast.FuncType)
+			paramType := getParamType(function.Params.List[0].Type)
+			flagType, ok := cliFlagTypes[paramType]
+			// Setters of embedded interfaces return the embedded interface, which can not be assigned to the builder
+			if !ok || len(function.Params.List) != 1 || getResultType(function) != r.RequestType {
+				continue
+			}
+			flags = append(flags, cliFlag{
+				Name:     getCLIName(getAnnotationValue(f)),
+				Setter:   getFunctionName(f),
+				Type:     paramType,
+				Func:     flagType[0],
+				Default:  flagType[1],
+				Usage:    strings.Join(strings.Fields(getDocText(f.Doc)), " "),
+				Required: isRequired(f) && !isOptionalPath(r.ApiEndpoint, f),
+			})
+		}
+		sort.Slice(flags, func(i, j int) bool {
+			return flags[i].Name < flags[j].Name || flags[i].Name == flags[j].Name && flags[i].Setter < flags[j].Setter
+		})
+		for _, flag := range flags {
+			// A parameter named like a parameter of another kind is prefixed with its kind
+			if used[flag.Name] {
+				flag.Name = g.in + "-" + flag.Name
+			}
+			used[flag.Name] = true
+			command.Flags = append(command.Flags, flag)
+		}
+	}
+	return command, true
+}
+
+// getCLIName returns the name of a command or flag in kebab case
+// Example: GetPhotos -> get-photos, image_size -> image-size
+func getCLIName(name string) string {
+	if name != "" && strings.ToLower(name[:1]) != name[:1] {
+		name = getSnakeCase(name)
+	}
+	return strings.ReplaceAll(name, "_", "-")
+}
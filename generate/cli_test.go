@@ -0,0 +1,112 @@
+package generate
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/jsaund/gorest/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateCLI(t *testing.T) {
+	src := `package test
+		import (
+			"context"
+			"time"
+		)
+
+		// GetPhotosRequestBuilder lists the photos of a user.
+		// Photos are sorted by date.
+		//
+		// @GET("/users/{id}/photos")
+		type GetPhotosRequestBuilder interface {
+			// UserID selects the user by ID.
+			// @PATH("id")
+			UserID(id string) GetPhotosRequestBuilder
+
+			// @QUERY("id")
+			PhotoID(id int64) GetPhotosRequestBuilder
+
+			// @QUERY("image_size")
+			ImageSize(size int) GetPhotosRequestBuilder
+
+			// @QUERY("since") @FORMAT("2006-01-02")
+			Since(t time.Time) GetPhotosRequestBuilder
+
+			// @SYNC("GetPhotosResponse")
+			Run(ctx context.Context) (GetPhotosResponse, error)
+		}
+
+		// @DELETE("/photos")
+		type DeletePhotosRequestBuilder interface {
+			// @SYNC("DeletePhotosResponse")
+			Run() (DeletePhotosResponse, error)
+		}
+
+		// @GET("/stream")
+		type StreamRequestBuilder interface {
+			// @ASYNC("StreamCallback")
+			RunAsync(callback StreamCallback)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := GenerateCLI(parse.NewParser(f, "test").ParseAll())
+	assert.NoError(t, err)
+	source := string(data)
+	assert.Contains(t, source, `	cmd.AddCommand(newGetPhotosRequestBuilderCommand())
+	cmd.AddCommand(newDeletePhotosRequestBuilderCommand())
+	return cmd`)
+	assert.NotContains(t, source, "Stream")
+	assert.NotContains(t, source, `"time"`)
+	assert.Contains(t, source, `func newGetPhotosRequestBuilderCommand() *cobra.Command {
+	var params struct {
+		UserID    string
+		PhotoID   int64
+		ImageSize int
+	}
+	cmd := &cobra.Command{
+		Use:   "get-photos",
+		Short: "GetPhotosRequestBuilder lists the photos of a user.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			builder := NewGetPhotosRequestBuilder()
+			if cmd.Flags().Changed("id") {
+				builder = builder.UserID(params.UserID)
+			}
+			if cmd.Flags().Changed("query-id") {
+				builder = builder.PhotoID(params.PhotoID)
+			}
+			if cmd.Flags().Changed("image-size") {
+				builder = builder.ImageSize(params.ImageSize)
+			}
+			result, err := builder.Run(cmd.Context())
+			if err != nil {
+				return err
+			}
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(result)
+		},
+	}
+	cmd.Flags().StringVar(&params.UserID, "id", "", "UserID selects the user by ID.")
+	cmd.MarkFlagRequired("id")
+	cmd.Flags().Int64Var(&params.PhotoID, "query-id", 0, "")
+	cmd.Flags().IntVar(&params.ImageSize, "image-size", 0, "")
+	return cmd
+}`)
+	assert.Contains(t, source, `func newDeletePhotosRequestBuilderCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete-photos",`)
+	assert.Contains(t, source, `			result, err := builder.Run()`)
+}
+
+func TestGetCLIName(t *testing.T) {
+	assert.Equal(t, "get-photos", getCLIName("GetPhotos"))
+	assert.Equal(t, "get-api-keys", getCLIName("GetAPIKeys"))
+	assert.Equal(t, "image-size", getCLIName("image_size"))
+	assert.Equal(t, "id", getCLIName("id"))
+}
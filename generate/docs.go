@@ -0,0 +1,213 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/jsaund/gorest/parse"
+)
+
+var docTemplates = template.Must(template.New("docs").Funcs(template.FuncMap{
+	"DocText":      getDocText,
+	"DocParams":    getDocParams,
+	"DocMethods":   getDocMethods,
+	"DocErrors":    getDocErrors,
+	"DocOperation": getDocOperation,
+	"Deprecated":   getDeprecatedMessage,
+	"Cell":         getCell,
+	"lower":        strings.ToLower,
+}).Parse(`# {{ .PackageName }} API Reference
+{{ range .Builders }}
+- [{{ .RequestType }}](#{{ .RequestType | lower }})
+{{- end }}
+{{ range .Builders }}
+## {{ .RequestType }}
+{{ with DocText .Doc }}
+{{ . }}
+{{ end }}
+{{- with Deprecated .Doc }}
+**Deprecated:** {{ . }}
+{{ end }}
+` + "```" + `
+{{ .HttpMethod }} {{ .ApiEndpoint }}
+` + "```" + `
+{{ with DocOperation .ParseResult }}
+{{ . }}
+{{ end }}
+{{- with DocParams .ParseResult }}
+### Parameters
+
+| Name | In | Type | Setter | Required | Description |
+| --- | --- | --- | --- | --- | --- |
+{{- range . }}
+| {{ if .Name }}` + "`{{ .Name }}`" + `{{ end }} | {{ .In }} | ` + "`{{ .Type }}`" + ` | ` + "`{{ .Setter }}`" + ` | {{ if .Required }}yes{{ else }}no{{ end }} | {{ Cell .Description }} |
+{{- end }}
+{{ end }}
+{{- with DocMethods .ParseResult }}
+### Response
+{{ range . }}
+- ` + "`{{ . }}`" + `
+{{- end }}
+{{ end }}
+{{- with DocErrors .ParseResult }}
+### Errors
+{{ range . }}
+- {{ . }}
+{{- end }}
+{{ end }}
+{{- end }}`))
+
+// docParam is a parameter of a request builder in the API reference
+type docParam struct {
+	Name        string
+	In          string
+	Type        string
+	Setter      string
+	Required    bool
+	Description string
+}
+
+// GenerateDocs generates a Markdown reference of the request builders, with a section per request
+// builder describing its request, parameters, response and errors.
+func GenerateDocs(results []*parse.ParseResult) ([]byte, error) {
+	if len(results) == 0 {
+		return nil, fmt.Errorf("No request builders to document")
+	}
+
+	var buf bytes.Buffer
+	if err := docTemplates.Execute(&buf, newFileData(results, Options{})); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// getDocText returns the text of a doc comment without its annotations
+func getDocText(doc *ast.CommentGroup) string {
+	return strings.TrimSpace(parse.StripAnnotations(doc.Text()))
+}
+
+// getDeprecatedMessage returns the message of the @DEPRECATED annotation of a doc comment, or the
+// empty string when the declaration is not deprecated
+func getDeprecatedMessage(doc *ast.CommentGroup) string {
+	annotation, valid := parse.ExtractAnnotation("DEPRECATED", doc.Text())
+	if !valid {
+		return ""
+	}
+	if annotation.Value == "" {
+		return "this should no longer be used."
+	}
+	return annotation.Value
+}
+
+// getCell returns text which can be used as a cell of a Markdown table
+func getCell(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// getDocOperation returns a sentence describing what a GraphQL or WebSocket request builder sends,
+// or the empty string for other request builders
+func getDocOperation(r *parse.ParseResult) string {
+	switch {
+	case r.GraphQL != nil && r.GraphQL.Persisted != "":
+		return "Sends the persisted GraphQL query `" + r.GraphQL.Persisted + "`."
+	case r.GraphQL != nil:
+		return "Sends the GraphQL operation held by `" + r.GraphQL.Query + "`."
+	case r.WebSocket != nil:
+		return "Opens a WebSocket connection which sends `" + r.WebSocket.Send + "` and receives `" + r.WebSocket.Receive + "` messages."
+	}
+	return ""
+}
+
+// getDocParams returns the parameters of the request builder in the order they appear in a
+// request: path, query, header and body parameters
+func getDocParams(r *parse.ParseResult) []docParam {
+	groups := []struct {
+		in     string
+		params map[string]*ast.Field
+	}{
+		{"path", r.PathSubstitutions},
+		{"query", r.QueryParams},
+		{"query", r.QueryStructParams},
+		{"header", r.HeaderParams},
+		{"field", r.PostFormParams},
+		{"part", r.PostMultiPartParams},
+		{"variable", r.Variables},
+	}
+
+	var params []docParam
+	for _, g := range groups {
+		var group []docParam
+		for _, f := range g.params {
+			group = append(group, docParam{
+				Name:        getAnnotationValue(f),
+				In:          g.in,
+				Type:        getParamType(f.Type.(*ast.FuncType).Params.List[0].Type),
+				Setter:      getFunctionName(f),
+				Required:    isRequired(f) && !isOptionalPath(r.ApiEndpoint, f),
+				Description: getDocText(f.Doc),
+			})
+		}
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].Name < group[j].Name || group[i].Name == group[j].Name && group[i].Setter < group[j].Setter
+		})
+		params = append(params, group...)
+	}
+	if r.BodyStream != nil {
+		params = append(params, docParam{
+			In:          "body",
+			Type:        getParamType(r.BodyStream.Type.(*ast.FuncType).Params.List[0].Type),
+			Setter:      getFunctionName(r.BodyStream),
+			Description: strings.TrimSpace("Content type " + getContentType(r.BodyStream) + ". " + getDocText(r.BodyStream.Doc)),
+		})
+	}
+	return params
+}
+
+// getDocMethods returns the signatures of the methods which send the request
+// Example: Run(ctx context.Context) (GetPhotosResponse, error)
+func getDocMethods(r *parse.ParseResult) []string {
+	var methods []string
+	for _, f := range []*ast.Field{r.SyncResponse, r.AsyncResponse, r.PaginatedResponse, r.Download, r.Connect, r.PollResponse} {
+		if f != nil {
+			methods = append(methods, getFunctionName(f)+strings.TrimPrefix(types.ExprString(f.Type), "func"))
+		}
+	}
+	return methods
+}
+
+// getDocErrors returns the errors returned by the request builder other than transport errors
+func getDocErrors(r *parse.ParseResult) []string {
+	var errors []string
+	var required []string
+	for _, p := range getDocParams(r) {
+		if p.Required {
+			required = append(required, p.In+" "+p.Name)
+		}
+	}
+	if len(required) > 0 {
+		errors = append(errors, "An error when a required parameter is not set: "+strings.Join(required, ", ")+".")
+	}
+
+	status := "2xx"
+	for _, s := range r.AllowedStatus {
+		status += ", " + strconv.Itoa(s)
+	}
+	errors = append(errors, "`*restclient.HTTPError` when the response status is not "+status+".")
+	if r.MaxBody > 0 {
+		errors = append(errors, fmt.Sprintf("`*restclient.BodyTooLargeError` when the response body is larger than %d bytes.", r.MaxBody))
+	}
+	if r.GraphQL != nil {
+		errors = append(errors, "`*restclient.GraphQLErrors` when the response reports GraphQL errors.")
+	}
+	if r.WebSocket != nil {
+		errors = append(errors, "`*restclient.WebSocketCloseError` when the server closes the connection with an error.")
+	}
+	return errors
+}
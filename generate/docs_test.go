@@ -0,0 +1,106 @@
+package generate
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/jsaund/gorest/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateDocs(t *testing.T) {
+	src := `package test
+		import "io"
+
+		// GetPhotosRequestBuilder lists the photos of a user.
+		//
+		// @GET("/users/{id}/photos")
+		// @ALLOW_STATUS("404")
+		type GetPhotosRequestBuilder interface {
+			// UserID selects the user by ID.
+			// @PATH("id")
+			UserID(id string) GetPhotosRequestBuilder
+
+			// Page is the page of photos, starting at 1 | default.
+			// @QUERY("page", required)
+			Page(page int) GetPhotosRequestBuilder
+
+			// @HEADER("X-Trace")
+			Trace(id string) GetPhotosRequestBuilder
+
+			// @SYNC("GetPhotosResponse")
+			Run() (GetPhotosResponse, error)
+		}
+
+		// @PUT("/files/{name}")
+		// @DEPRECATED("use UploadRequestBuilder")
+		// @MAX_BODY("1KB")
+		type UploadFileRequestBuilder interface {
+			// @PATH("name")
+			Name(name string) UploadFileRequestBuilder
+
+			// @BODY_STREAM("application/zip")
+			Content(r io.Reader) UploadFileRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	docs, err := GenerateDocs(parse.NewParser(f, "test").ParseAll())
+	assert.NoError(t, err)
+	assert.Equal(t, "# test API Reference\n"+`
+- [GetPhotosRequestBuilder](#getphotosrequestbuilder)
+- [UploadFileRequestBuilder](#uploadfilerequestbuilder)
+
+## GetPhotosRequestBuilder
+
+GetPhotosRequestBuilder lists the photos of a user.
+
+`+"```"+`
+GET /users/{id}/photos
+`+"```"+`
+
+### Parameters
+
+| Name | In | Type | Setter | Required | Description |
+| --- | --- | --- | --- | --- | --- |
+| `+"`id`"+` | path | `+"`string`"+` | `+"`UserID`"+` | yes | UserID selects the user by ID. |
+| `+"`page`"+` | query | `+"`int`"+` | `+"`Page`"+` | yes | Page is the page of photos, starting at 1 \| default. |
+| `+"`X-Trace`"+` | header | `+"`string`"+` | `+"`Trace`"+` | no |  |
+
+### Response
+
+- `+"`Run() (GetPhotosResponse, error)`"+`
+
+### Errors
+
+- An error when a required parameter is not set: path id, query page.
+- `+"`*restclient.HTTPError`"+` when the response status is not 2xx, 404.
+
+## UploadFileRequestBuilder
+
+**Deprecated:** use UploadRequestBuilder
+
+`+"```"+`
+PUT /files/{name}
+`+"```"+`
+
+### Parameters
+
+| Name | In | Type | Setter | Required | Description |
+| --- | --- | --- | --- | --- | --- |
+| `+"`name`"+` | path | `+"`string`"+` | `+"`Name`"+` | yes |  |
+|  | body | `+"`io.Reader`"+` | `+"`Content`"+` | no | Content type application/zip. |
+
+### Errors
+
+- An error when a required parameter is not set: path name.
+- `+"`*restclient.HTTPError`"+` when the response status is not 2xx.
+- `+"`*restclient.BodyTooLargeError`"+` when the response body is larger than 1024 bytes.
+`, string(docs))
+
+	_, err = GenerateDocs(nil)
+	assert.EqualError(t, err, "No request builders to document")
+}
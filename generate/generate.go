@@ -5,10 +5,21 @@ import (
 	"fmt"
 	"go/ast"
 	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
 	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"text/template"
+	"time"
+	"unicode"
 
 	"github.com/jsaund/gorest/parse"
+	"github.com/jsaund/gorest/restclient"
+	"golang.org/x/tools/go/ast/astutil"
 )
 
 var funcMap = template.FuncMap{
@@ -16,11 +27,92 @@ var funcMap = template.FuncMap{
 	"ParamName":       getParamName,
 	"AnnotationValue": getAnnotationValue,
 	"FunctionName":    getFunctionName,
+	"ExtraImports":    getExtraImports,
+	"BuilderImports":  func() []string { return builderImports },
+	"Constructor":     getConstructor,
+	"Decoder":         getDecoder,
+	"IsLocalType":     isLocalType,
+	"ResultType":      getResultType,
+	"Examples":        getExamples,
+	"IsRequired":      isRequired,
+	"IsEncoded":       isEncoded,
+	"ParamString":     getParamString,
+	"IsList":          isList,
+	"PartValue":       getPartValue,
+	"IsOptionalPath":  isOptionalPath,
+	"DocComment":      getDocComment,
+	"Deprecation":     getDeprecation,
+	"ContentType":     getContentType,
+	"ContextParam":    getContextParam,
+	"CallbackParam":   getCallbackParam,
+	"Duration":        getDuration,
+	"Callbacks":       getCallbacks,
+	"HasBody":         hasBody,
+	"RunType":         getRunType,
+	"IsDecoded":       isDecoded,
 }
 
-// Generate generates the implementation using the details contained in ParseResult.
-func Generate(r *parse.ParseResult) ([]byte, error) {
-	var builderTemplate = template.Must(template.New("builder").Funcs(funcMap).Parse(`/*
+// builderImports are the packages always imported by the generated implementation.
+// Imports which end up unused are removed once the implementation is generated.
+var builderImports = []string{
+	"bytes",
+	"context",
+	"encoding/json",
+	"fmt",
+	"io",
+	"io/ioutil",
+	"net/http",
+	"net/url",
+	"sort",
+	"strconv",
+	"strings",
+	"time",
+}
+
+// Layout describes how the generated implementation is split in to files.
+type Layout string
+
+const (
+	// LayoutSingle generates the complete implementation in to the output file.
+	LayoutSingle Layout = "single"
+	// LayoutPerEndpoint generates each request builder in to its own file. Declarations shared
+	// by the request builders, such as callbacks, are generated in to the output file.
+	LayoutPerEndpoint Layout = "endpoint"
+)
+
+// FileNaming describes how the files of the request builders are named in the per endpoint layout.
+type FileNaming string
+
+const (
+	// FileNamingBuilder names the file after the request builder, such as
+	// get_photos_request_builder_gorest.go for GetPhotosRequestBuilder.
+	FileNamingBuilder FileNaming = "builder"
+	// FileNamingResource names the file after the resource followed by the action of the request
+	// builder, such as photos_get_gorest.go for GetPhotosRequestBuilder and photos_upload_gorest.go
+	// for UploadPhotosRequestBuilder, which lists the files of a resource next to each other.
+	FileNamingResource FileNaming = "resource"
+)
+
+// DefaultFileSuffix is the suffix of the names of generated files
+const DefaultFileSuffix = "_gorest.go"
+
+// File is a generated Go source file.
+// An empty Name refers to the output file, otherwise Name is the base name of a file which
+// is created in the same directory as the output file.
+// Files with a Suffix, such as tests, are named after the file they belong to with the suffix
+// replacing the .go extension.
+type File struct {
+	Name   string
+	Source []byte
+	Suffix string
+}
+
+var templates = template.Must(template.New("gorest").Funcs(funcMap).Parse(`
+{{ define "single" }}{{ template "header" . }}{{ range Callbacks .Builders }}{{ template "callback" . }}{{ end }}{{ range .Builders }}{{ template "builder" . }}{{ end }}{{ end }}
+{{ define "shared" }}{{ template "header" . }}{{ range Callbacks .Builders }}{{ template "callback" . }}{{ end }}{{ end }}
+{{ define "endpoint" }}{{ template "header" . }}{{ range .Builders }}{{ template "builder" . }}{{ end }}{{ end }}
+
+{{ define "test" }}/*
 * CODE GENERATED AUTOMATICALLY WITH GOREST (github.com/jsaund/gorest)
 * THIS FILE SHOULD NOT BE EDITED BY HAND
 */
@@ -28,242 +120,1806 @@ func Generate(r *parse.ParseResult) ([]byte, error) {
 package {{.PackageName}}
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
-	"strings"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jsaund/gorest/restclient"
+{{ with ExtraImports .Builders }}
+{{ range . }}	{{ .Name }} "{{ .Path }}"
+{{ end }}{{ end }})
+{{ range .Builders }}{{ if .Examples }}{{ template "examples" . }}{{ end }}{{ end }}
+{{ end }}
+
+{{ define "examples" }}
+func Test{{ .RequestType }}Examples(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+	}))
+	defer server.Close()
+	restclient.RegisterClient(restclient.NewDefaultClient(server.URL, false, server.Client()))
+
+	testCases := []struct {
+		builder func() {{ .RequestType }}
+		method  string
+		path    string
+		query   string
+		headers map[string]string
+	}{
+{{- range Examples .ParseResult }}
+		{
+			func() {{ $.RequestType }} {
+				builder := New{{ $.RequestType }}()
+				{{ range .Calls }}{{ if $.Immutable }}builder = builder.{{ . }}.({{ $.RequestType }}){{ else }}builder.{{ . }}{{ end }}
+				{{ end }}return builder
+			},
+			{{ printf "%q" .Method }},
+			{{ with $.Version }}restclient.VersionPath(restclient.GetClient(), {{ printf "%q" . }}) + {{ end }}{{ .Path }},
+			{{ .Query }},
+			map[string]string{ {{ range $key, $value := .Headers }}{{ printf "%q" $key }}: {{ $value }},{{ end }} },
+		},
+{{- end }}
+	}
+
+	for i, tc := range testCases {
+		request, err := tc.builder().(*{{ .RequestType }}Impl).BuildRequest()
+		if err != nil {
+			t.Errorf("Example %d: failed to build request: %v", i, err)
+			continue
+		}
+		response, err := server.Client().Do(request)
+		if err != nil {
+			t.Errorf("Example %d: failed to send request: %v", i, err)
+			continue
+		}
+		response.Body.Close()
+
+		received := <-requests
+		if received.Method != tc.method {
+			t.Errorf("Example %d: expected method %s, got %s", i, tc.method, received.Method)
+		}
+		if received.URL.Path != tc.path {
+			t.Errorf("Example %d: expected path %s, got %s", i, tc.path, received.URL.Path)
+		}
+		if received.URL.RawQuery != tc.query {
+			t.Errorf("Example %d: expected query %s, got %s", i, tc.query, received.URL.RawQuery)
+		}
+		for key, value := range tc.headers {
+			if received.Header.Get(key) != value {
+				t.Errorf("Example %d: expected header %s to be %s, got %s", i, key, value, received.Header.Get(key))
+			}
+		}
+	}
+}
+{{ end }}
+
+{{ define "conformance" }}//go:build conformance
+
+/*
+* CODE GENERATED AUTOMATICALLY WITH GOREST (github.com/jsaund/gorest)
+* THIS FILE SHOULD NOT BE EDITED BY HAND
+*/
+
+package {{.PackageName}}
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
 
 	"github.com/jsaund/gorest/restclient"
 )
+{{ range .Builders }}{{ if .Examples }}{{ template "conformanceExamples" . }}{{ end }}{{ end }}
+{{ end }}
+
+{{ define "conformanceExamples" }}
+func TestConformance{{ .RequestType }}(t *testing.T) {
+	baseURL := os.Getenv(restclient.SandboxURLEnv)
+	if baseURL == "" {
+		t.Skipf("%s is not set", restclient.SandboxURLEnv)
+	}
+	restclient.RegisterClient(restclient.NewDefaultClient(baseURL, false, http.DefaultClient))
+
+	builders := []func() {{ .RequestType }}{
+{{- range Examples .ParseResult }}
+		func() {{ $.RequestType }} {
+			builder := New{{ $.RequestType }}()
+			{{ range .Calls }}{{ if $.Immutable }}builder = builder.{{ . }}.({{ $.RequestType }}){{ else }}builder.{{ . }}{{ end }}
+			{{ end }}return builder
+		},
+{{- end }}
+	}
+
+	for i, builder := range builders {
+		result := restclient.ConformanceResult{Endpoint: "{{ .RequestType }}", Example: i}
+		start := time.Now()
+		err := func() error {
+			b := builder().(*{{ .RequestType }}Impl)
+			restClient, err := restclient.EnvironmentClient(b.environment)
+			if err != nil {
+				return err
+			}
+			request, err := b.build(restClient)
+			if err != nil {
+				return err
+			}
+			result.Method = request.Method
+			result.URL = request.URL.String()
+
+			response, err := b.send(restClient, request)
+			if err != nil {
+				return err
+			}
+			defer response.Body.Close()
+
+			result.Status = response.StatusCode
+			if response.StatusCode < 200 || response.StatusCode > 299 {
+				return fmt.Errorf("unexpected status %s", response.Status)
+			}
+{{- if and (IsDecoded .ParseResult) .SyncResponse }}
+			if _, err := {{ Decoder .ParseResult }}(response.Body); err != nil {
+				return fmt.Errorf("response does not match {{ .ResponseType }}: %v", err)
+			}
+{{- end }}
+{{- if and .ResponseType .PaginatedResponse }}
+
+			// Request a few pages to verify the next page is requested correctly
+			err = builder().(*{{ .RequestType }}Impl).{{ .PaginatedResponse | FunctionName }}(context.Background(), func(page {{ .ResponseType }}) bool {
+				result.Pages++
+				return result.Pages < 3
+			})
+			if err != nil {
+				return fmt.Errorf("pagination failed after %d pages: %v", result.Pages, err)
+			}
+{{- end }}
+			return nil
+		}()
+
+		result.Duration = time.Since(start)
+		result.Passed = err == nil
+		if err != nil {
+			result.Error = err.Error()
+			t.Errorf("Example %d: %v", i, err)
+		}
+		if err := restclient.RecordConformance(result); err != nil {
+			t.Errorf("Failed to record result of example %d: %v", i, err)
+		}
+	}
+}
+{{ end }}
+
+{{ define "header" }}/*
+* CODE GENERATED AUTOMATICALLY WITH GOREST (github.com/jsaund/gorest)
+* THIS FILE SHOULD NOT BE EDITED BY HAND
+*/
 
-{{ if .CallbackType }}
+package {{.PackageName}}
+
+import (
+{{ range BuilderImports }}	"{{ . }}"
+{{ end }}
+	"github.com/jsaund/gorest/restclient"
+{{ with ExtraImports .Builders }}
+{{ range . }}	{{ .Name }} "{{ .Path }}"
+{{ end }}{{ end }})
+
+{{ end }}
+
+{{ define "callback" }}{{ if and .CallbackType (IsLocalType .CallbackType) }}
 type {{ $.CallbackType }} interface {
 	OnStart()
 	OnError(reason string)
-	OnSuccess(response {{ $.ResponseType }})
+	OnSuccess(response {{ RunType $.ParseResult }})
 }
 {{ end }}
+{{ end }}
 
+{{ define "builder" }}
 type {{ .RequestType }}Impl struct {
-	pathSubstitutions  map[string]string
+	pathSubstitutions  map[string]interface{}
 	queryParams        url.Values
+	encodedQueryParams url.Values
 	postFormParams     url.Values
-	postBody           interface{}
+	postBody           json.RawMessage
 	postMultiPartParam map[string][]byte
 	headerParams       map[string]string
+	err                error
+	onRequest          []func(*http.Request)
+	onResponse         []func(*http.Response)
+	environment        string
+{{- if .IdempotencyHeader }}
+	idempotencyKey     string
+{{- end }}
+{{- if and .SyncResponse .ResponseType }}
+	fallback           *{{ .ResponseType }}
+{{- end }}
+{{- if .Progress }}
+	progress           func(sent, total int64)
+{{- end }}
+{{- if .BodyStream }}
+	bodyStream         io.Reader
+	bodyStreamLength   int64
+{{- end }}
+{{- if .GraphQL }}
+	variables          map[string]interface{}
+{{- end }}
 }
 
-func New{{ .RequestType }}() {{ .RequestType }} {
+{{ DocComment .Doc }}func New{{ .RequestType }}() {{ .RequestType }} {
 	return &{{ .RequestType }}Impl{
-		pathSubstitutions:  make(map[string]string),
+		pathSubstitutions:  make(map[string]interface{}),
 		queryParams:        url.Values{},
+		encodedQueryParams: url.Values{},
 		postFormParams:     url.Values{},
 		postMultiPartParam: make(map[string][]byte),
 		headerParams:       make(map[string]string),
+{{- if .GraphQL }}
+		variables:          make(map[string]interface{}),
+{{- end }}
+	}
+}
+
+func (b *{{ .RequestType }}Impl) Clone() {{ .RequestType }} {
+	return b.clone()
+}
+
+func (b *{{ .RequestType }}Impl) clone() *{{ .RequestType }}Impl {
+	clone := &{{ .RequestType }}Impl{
+		pathSubstitutions:  make(map[string]interface{}, len(b.pathSubstitutions)),
+		queryParams:        make(url.Values, len(b.queryParams)),
+		encodedQueryParams: make(url.Values, len(b.encodedQueryParams)),
+		postFormParams:     make(url.Values, len(b.postFormParams)),
+		postBody:           b.postBody,
+		postMultiPartParam: make(map[string][]byte, len(b.postMultiPartParam)),
+		headerParams:       make(map[string]string, len(b.headerParams)),
+		err:                b.err,
+		onRequest:          append(b.onRequest[:0:0], b.onRequest...),
+		onResponse:         append(b.onResponse[:0:0], b.onResponse...),
+		environment:        b.environment,
+{{- if and .SyncResponse .ResponseType }}
+		fallback:           b.fallback,
+{{- end }}
+{{- if .Progress }}
+		progress:           b.progress,
+{{- end }}
+{{- if .BodyStream }}
+		bodyStream:         b.bodyStream,
+		bodyStreamLength:   b.bodyStreamLength,
+{{- end }}
+{{- if .GraphQL }}
+		variables:          make(map[string]interface{}, len(b.variables)),
+{{- end }}
+	}
+	for key, value := range b.pathSubstitutions {
+		clone.pathSubstitutions[key] = value
 	}
+	for key, values := range b.queryParams {
+		clone.queryParams[key] = append([]string(nil), values...)
+	}
+	for key, values := range b.encodedQueryParams {
+		clone.encodedQueryParams[key] = append([]string(nil), values...)
+	}
+	for key, values := range b.postFormParams {
+		clone.postFormParams[key] = append([]string(nil), values...)
+	}
+	for key, value := range b.postMultiPartParam {
+		clone.postMultiPartParam[key] = append([]byte(nil), value...)
+	}
+	for key, value := range b.headerParams {
+		clone.headerParams[key] = value
+	}
+{{- if .GraphQL }}
+	for key, value := range b.variables {
+		clone.variables[key] = value
+	}
+{{- end }}
+	return clone
 }
 
+// formatParam returns the string representation of the parameter named name
+// The first error is recorded and returned when the request is built
+func (b *{{ .RequestType }}Impl) formatParam(name string, value interface{}) string {
+	s, err := restclient.FormatParam(value)
+	if err != nil && b.err == nil {
+		b.err = fmt.Errorf("Failed to format parameter %s: %w", name, err)
+	}
+	return s
+}
+{{ if .PostMultiPartParams }}
+// readPart reads the body of the part named name
+// The first error is recorded and returned when the request is built
+func (b *{{ .RequestType }}Impl) readPart(name string, r io.Reader) []byte {
+	data, err := ioutil.ReadAll(r)
+	if err != nil && b.err == nil {
+		b.err = fmt.Errorf("Failed to read part %s: %w", name, err)
+	}
+	return data
+}
+{{ end }}
 {{ range $key, $value := .PathSubstitutions }}
-func (b *{{ $.RequestType }}Impl) {{ $key }}({{ ParamsList $value.Type }}) {{ $.RequestType }} {
-	b.pathSubstitutions["{{ AnnotationValue $value }}"] = {{ ParamName $value.Type true 0 }}
+{{ DocComment $value.Doc }}func (b *{{ $.RequestType }}Impl) {{ $key }}({{ ParamsList $value.Type }}) {{ ResultType $value.Type }} {
+	{{- with Deprecation (printf "%s.%s" $.RequestType $key) $value.Doc }}
+	{{ . }}
+	{{- end }}
+	{{- if $.Immutable }}
+	b = b.clone()
+	{{- end }}
+	{{- if IsEncoded $value }}
+	b.pathSubstitutions["{{ AnnotationValue $value }}"] = restclient.Encoded({{ ParamString $value }})
+	{{- else if IsList $value }}
+	b.pathSubstitutions["{{ AnnotationValue $value }}"] = {{ ParamName $value.Type false 0 }}
+	{{- else }}
+	b.pathSubstitutions["{{ AnnotationValue $value }}"] = {{ ParamString $value }}
+	{{- end }}
 	return b
 }
 {{ end }}
 
 {{ range $key, $value := .QueryParams }}
-func (b *{{ $.RequestType }}Impl) {{ $key }}({{ ParamsList $value.Type }}) {{ $.RequestType }} {
-	b.queryParams.Add("{{ AnnotationValue $value }}", {{ ParamName $value.Type true 0 }})
+{{ DocComment $value.Doc }}func (b *{{ $.RequestType }}Impl) {{ $key }}({{ ParamsList $value.Type }}) {{ ResultType $value.Type }} {
+	{{- with Deprecation (printf "%s.%s" $.RequestType $key) $value.Doc }}
+	{{ . }}
+	{{- end }}
+	{{- if $.Immutable }}
+	b = b.clone()
+	{{- end }}
+	{{- if IsEncoded $value }}
+	b.encodedQueryParams.Add("{{ AnnotationValue $value }}", {{ ParamString $value }})
+	{{- else }}
+	b.queryParams.Add("{{ AnnotationValue $value }}", {{ ParamString $value }})
+	{{- end }}
+	return b
+}
+{{ end }}
+
+{{ range $key, $value := .QueryStructParams }}
+{{ DocComment $value.Doc }}func (b *{{ $.RequestType }}Impl) {{ $key }}({{ ParamsList $value.Type }}) {{ ResultType $value.Type }} {
+	{{- with Deprecation (printf "%s.%s" $.RequestType $key) $value.Doc }}
+	{{ . }}
+	{{- end }}
+	{{- if $.Immutable }}
+	b = b.clone()
+	{{- end }}
+	values, err := restclient.QueryValues({{ ParamName $value.Type false 0 }})
+	if err != nil && b.err == nil {
+		b.err = fmt.Errorf("Failed to format parameter {{ ParamName $value.Type false 0 }}: %w", err)
+	}
+	for key, value := range values {
+		b.queryParams[key] = append(b.queryParams[key], value...)
+	}
+	return b
+}
+{{ end }}
+
+// AddHeader sets a header which is not declared by the request builder
+func (b *{{ .RequestType }}Impl) AddHeader(key string, value string) {{ .RequestType }} {
+	{{- if .Immutable }}
+	b = b.clone()
+	{{- end }}
+	b.headerParams[key] = value
+	return b
+}
+
+// AddQueryParam adds a query parameter which is not declared by the request builder
+func (b *{{ .RequestType }}Impl) AddQueryParam(key string, value string) {{ .RequestType }} {
+	{{- if .Immutable }}
+	b = b.clone()
+	{{- end }}
+	b.queryParams.Add(key, value)
+	return b
+}
+
+// OnRequest adds a hook which is called with the request right before it is sent
+func (b *{{ .RequestType }}Impl) OnRequest(hook func(*http.Request)) {{ .RequestType }} {
+	{{- if .Immutable }}
+	b = b.clone()
+	{{- end }}
+	b.onRequest = append(b.onRequest, hook)
+	return b
+}
+
+// OnResponse adds a hook which is called with the response as soon as it is received
+func (b *{{ .RequestType }}Impl) OnResponse(hook func(*http.Response)) {{ .RequestType }} {
+	{{- if .Immutable }}
+	b = b.clone()
+	{{- end }}
+	b.onResponse = append(b.onResponse, hook)
+	return b
+}
+
+// WithEnvironment sends the request to the environment registered as name with
+// restclient.RegisterEnvironment, rather than with the registered client
+func (b *{{ .RequestType }}Impl) WithEnvironment(name string) {{ .RequestType }} {
+	{{- if .Immutable }}
+	b = b.clone()
+	{{- end }}
+	b.environment = name
+	return b
+}
+{{ if and .SyncResponse .ResponseType }}
+// WithFallback sets the response returned when the request fails, along with a
+// *restclient.FallbackError wrapping the error of the request
+func (b *{{ .RequestType }}Impl) WithFallback(fallback {{ .ResponseType }}) {{ .RequestType }} {
+	{{- if .Immutable }}
+	b = b.clone()
+	{{- end }}
+	b.fallback = &fallback
+	return b
+}
+{{ end }}
+{{ with .Progress }}
+{{ DocComment .Doc }}func (b *{{ $.RequestType }}Impl) {{ FunctionName . }}({{ ParamsList .Type }}) {{ ResultType .Type }} {
+	{{- with Deprecation (printf "%s.%s" $.RequestType (FunctionName .)) .Doc }}
+	{{ . }}
+	{{- end }}
+	{{- if $.Immutable }}
+	b = b.clone()
+	{{- end }}
+	b.progress = {{ ParamName .Type false 0 }}
+	return b
+}
+{{ end }}
+
+{{ with .BodyStream }}
+{{ DocComment .Doc }}func (b *{{ $.RequestType }}Impl) {{ FunctionName . }}({{ ParamsList .Type }}) {{ ResultType .Type }} {
+	{{- with Deprecation (printf "%s.%s" $.RequestType (FunctionName .)) .Doc }}
+	{{ . }}
+	{{- end }}
+	{{- if $.Immutable }}
+	b = b.clone()
+	{{- end }}
+	b.bodyStream = {{ ParamName .Type false 0 }}
+	{{- if eq (len .Type.Params.List) 2 }}
+	b.bodyStreamLength = {{ ParamName .Type false 1 }}
+	{{- end }}
 	return b
 }
 {{ end }}
 
 {{ range $key, $value := .PostFormParams }}
-func (b *{{ $.RequestType }}Impl) {{ $key }}({{ ParamsList $value.Type }}) {{ $.RequestType }} {
-	b.postFormParams.Add("{{ AnnotationValue $value }}", {{ ParamName $value.Type true 0 }})
+{{ DocComment $value.Doc }}func (b *{{ $.RequestType }}Impl) {{ $key }}({{ ParamsList $value.Type }}) {{ ResultType $value.Type }} {
+	{{- with Deprecation (printf "%s.%s" $.RequestType $key) $value.Doc }}
+	{{ . }}
+	{{- end }}
+	{{- if $.Immutable }}
+	b = b.clone()
+	{{- end }}
+	b.postFormParams.Add("{{ AnnotationValue $value }}", {{ ParamString $value }})
 	return b
 }
 {{ end }}
 
 {{ range $key, $value := .PostParams }}
-func (b *{{ $.RequestType }}Impl) {{ $key }}({{ ParamsList $value.Type }}) {{ $.RequestType }} {
-	b.postBody = {{ ParamName $value.Type false 0 }}
+{{ DocComment $value.Doc }}func (b *{{ $.RequestType }}Impl) {{ $key }}({{ ParamsList $value.Type }}) {{ ResultType $value.Type }} {
+	{{- with Deprecation (printf "%s.%s" $.RequestType $key) $value.Doc }}
+	{{ . }}
+	{{- end }}
+	{{- if $.Immutable }}
+	b = b.clone()
+	{{- end }}
+	b.postBody = b.encodeBody({{ ParamName $value.Type false 0 }})
 	return b
 }
 {{ end }}
+{{ if .PostParams }}
+// encodeBody returns the JSON encoding of the request body, which is encoded once when it is set so
+// that copies of the request builder share the encoded body rather than encoding it again.
+// The first error is recorded and returned when the request is built.
+func (b *{{ .RequestType }}Impl) encodeBody(body interface{}) json.RawMessage {
+	if body == nil {
+		return nil
+	}
+	data, err := restclient.MarshalJSON(body)
+	if err != nil && b.err == nil {
+		b.err = fmt.Errorf("Failed to encode body: %w", err)
+	}
+	return data
+}
+{{ end }}
 
 {{ range $key, $value := .HeaderParams }}
-func (b *{{ $.RequestType }}Impl) {{ $key }}({{ ParamsList $value.Type }}) {{ $.RequestType }} {
-	b.headerParams["{{ AnnotationValue $value }}"] = {{ ParamName $value.Type true 0 }}
+{{ DocComment $value.Doc }}func (b *{{ $.RequestType }}Impl) {{ $key }}({{ ParamsList $value.Type }}) {{ ResultType $value.Type }} {
+	{{- with Deprecation (printf "%s.%s" $.RequestType $key) $value.Doc }}
+	{{ . }}
+	{{- end }}
+	{{- if $.Immutable }}
+	b = b.clone()
+	{{- end }}
+	b.headerParams["{{ AnnotationValue $value }}"] = {{ ParamString $value }}
+	return b
+}
+{{ end }}
+
+{{ range $key, $value := .Variables }}
+{{ DocComment $value.Doc }}func (b *{{ $.RequestType }}Impl) {{ $key }}({{ ParamsList $value.Type }}) {{ ResultType $value.Type }} {
+	{{- with Deprecation (printf "%s.%s" $.RequestType $key) $value.Doc }}
+	{{ . }}
+	{{- end }}
+	{{- if $.Immutable }}
+	b = b.clone()
+	{{- end }}
+	b.variables["{{ AnnotationValue $value }}"] = {{ ParamName $value.Type false 0 }}
 	return b
 }
 {{ end }}
 
 {{ range $key, $value := .PostMultiPartParams }}
-func (b *{{ $.RequestType }}Impl) {{ $key }}({{ ParamsList $value.Type }}) {{ $.RequestType }} {
-	b.postMultiPartParams["{{ AnnotationValue $value }}"] = {{ ParamName $value.Type true 0 }}
+{{ DocComment $value.Doc }}func (b *{{ $.RequestType }}Impl) {{ $key }}({{ ParamsList $value.Type }}) {{ ResultType $value.Type }} {
+	{{- with Deprecation (printf "%s.%s" $.RequestType $key) $value.Doc }}
+	{{ . }}
+	{{- end }}
+	{{- if $.Immutable }}
+	b = b.clone()
+	{{- end }}
+	b.postMultiPartParam["{{ AnnotationValue $value }}"] = {{ PartValue $value }}
 	return b
 }
 {{ end }}
 
-func (b *{{ .RequestType }}Impl) applyPathSubstituions(api string) string {
-	if len(b.pathSubstitutions) == 0 {
-		return api
+// rawQuery returns the encoded query of the request
+// The values of encoded query parameters are appended as is
+func (b *{{ .RequestType }}Impl) rawQuery() string {
+	query := b.queryParams.Encode()
+	if len(b.encodedQueryParams) == 0 {
+		return query
 	}
-
-	for key, value := range b.pathSubstitutions {
-		api = strings.Replace(api, "{" + key + "}", value, -1)
+	keys := make([]string, 0, len(b.encodedQueryParams))
+	for key := range b.encodedQueryParams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var builder strings.Builder
+	builder.WriteString(query)
+	for _, key := range keys {
+		for _, value := range b.encodedQueryParams[key] {
+			if builder.Len() > 0 {
+				builder.WriteByte('&')
+			}
+			builder.WriteString(url.QueryEscape(key))
+			builder.WriteByte('=')
+			builder.WriteString(value)
+		}
 	}
+	return builder.String()
+}
 
-	return api
+func (b *{{ .RequestType }}Impl) validate() error {
+	var missing []string
+{{- range $key, $value := .PathSubstitutions }}{{ if not (IsOptionalPath $.ApiEndpoint $value) }}
+	if _, ok := b.pathSubstitutions["{{ AnnotationValue $value }}"]; !ok {
+		missing = append(missing, "path parameter {{ AnnotationValue $value }}")
+	}
+{{- end }}{{ end }}
+{{- range $key, $value := .QueryParams }}{{ if IsRequired $value }}
+	if _, ok := b.{{ if IsEncoded $value }}encodedQueryParams{{ else }}queryParams{{ end }}["{{ AnnotationValue $value }}"]; !ok {
+		missing = append(missing, "query parameter {{ AnnotationValue $value }}")
+	}
+{{- end }}{{ end }}
+{{- range $key, $value := .PostFormParams }}{{ if IsRequired $value }}
+	if _, ok := b.postFormParams["{{ AnnotationValue $value }}"]; !ok {
+		missing = append(missing, "field {{ AnnotationValue $value }}")
+	}
+{{- end }}{{ end }}
+{{- range $key, $value := .PostMultiPartParams }}{{ if IsRequired $value }}
+	if _, ok := b.postMultiPartParam["{{ AnnotationValue $value }}"]; !ok {
+		missing = append(missing, "part {{ AnnotationValue $value }}")
+	}
+{{- end }}{{ end }}
+{{- range $key, $value := .HeaderParams }}{{ if IsRequired $value }}
+	if _, ok := b.headerParams["{{ AnnotationValue $value }}"]; !ok {
+		missing = append(missing, "header {{ AnnotationValue $value }}")
+	}
+{{- end }}{{ end }}
+	if len(missing) > 0 {
+		return &restclient.MissingParamsError{Request: "{{ .RequestType }}", Missing: missing}
+	}
+	return nil
 }
 
-func (b *{{ .RequestType }}Impl) build() (req *http.Request, err error) {
-	restClient := restclient.GetClient()
-	if restClient == nil {
-		return nil, fmt.Errorf("A rest client has not been registered yet. You must call client.RegisterClient first")
+{{ if and (not .GraphQL) (HasBody .ParseResult) -}}
+// body returns the body of the request along with its content type, or a nil reader when the
+// request has no body
+func (b *{{ .RequestType }}Impl) body() (io.Reader, string, error) {
+{{- if .BodyStream }}
+	if b.bodyStream != nil {
+		// The reader is sent as is, without buffering the body in memory
+		return b.bodyStream, "{{ ContentType .BodyStream }}", nil
 	}
-	url := restClient.BaseURL() + b.applyPathSubstituions("{{ .ApiEndpoint }}")
-	httpMethod := "{{ .HttpMethod }}"
-	switch httpMethod {
-	case "POST", "PUT":
-		if b.postBody != nil {
-			// Assume the body is to be marshalled to JSON
-			contentBody, err := json.Marshal(b.postBody)
-			if err != nil {
-				return nil, err
-			}
-			contentReader := bytes.NewReader(contentBody)
-			req, err = http.NewRequest(httpMethod, url, contentReader)
-			if err != nil {
-				return nil, err
-			}
-			req.Header.Set("Content-Type", "application/json")
-		} else if len(b.postFormParams) > 0 {
-			contentForm := b.postFormParams.Encode()
-			contentReader := strings.NewReader(contentForm)
-			if req, err = http.NewRequest(httpMethod, url, contentReader); err != nil {
-				return nil, err
-			}
-			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-		} else if len(b.postMultiPartParam) > 0 {
-			contentBody := &bytes.Buffer{}
-			writer := multipart.NewWriter(contentBody)
-			for key, value := range b.postMultiPartParam {
-				if err := writer.WriteField(key, string(value)); err != nil {
-					return nil, err
-				}
-			}
-			if err = writer.Close(); err != nil {
-				return nil, err
-			}
-			if req, err = http.NewRequest(httpMethod, url, contentBody); err != nil {
-				return nil, err
-			}
-			req.Header.Set("Content-Type", "multipart/form-data")
-		}
-	case "GET", "DELETE":
-		req, err = http.NewRequest(httpMethod, url, nil)
+{{- end }}
+	if b.postBody != nil {
+		// The body was encoded to JSON when it was set
+		return bytes.NewReader(b.postBody), "application/json", nil
+	}
+	if len(b.postFormParams) > 0 {
+		return strings.NewReader(b.postFormParams.Encode()), "application/x-www-form-urlencoded", nil
+	}
+	if len(b.postMultiPartParam) > 0 {
+		contentBody, contentType, err := restclient.MultipartBody(b.postMultiPartParam)
 		if err != nil {
-			return nil, err
-		}
-		if len(b.queryParams) > 0 {
-			req.URL.RawQuery = b.queryParams.Encode()
+			return nil, "", err
 		}
+		return bytes.NewReader(contentBody), contentType, nil
+	}
+	return nil, "", nil
+}
+{{ end }}
+// build builds the request to be sent with restClient, which is resolved once by the caller so
+// that the request is sent with the client it was built for when the client is replaced meanwhile
+func (b *{{ .RequestType }}Impl) build(restClient restclient.Client) (req *http.Request, err error) {
+{{- with Deprecation .RequestType .Doc }}
+	{{ . }}
+{{- end }}
+	if b.err != nil {
+		return nil, b.err
+	}
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+	path, err := restclient.ExpandURITemplate("{{ .ApiEndpoint }}", b.pathSubstitutions)
+	if err != nil {
+		return nil, err
+	}
+	url := restClient.BaseURL() + restclient.VersionPath(restClient, "{{ .Version }}") + path
+	httpMethod := "{{ .HttpMethod }}"
+{{- if .GraphQL }}
+	operation := restclient.GraphQLOperation{
+{{- if .GraphQL.Query }}
+		Query:     {{ .GraphQL.Query }},
+{{- else }}
+		ID:        {{ printf "%q" .GraphQL.Persisted }},
+{{- end }}
+		Variables: b.variables,
+	}
+	if req, err = restclient.NewGraphQLRequest(httpMethod, url, operation); err != nil {
+		return nil, err
 	}
-	req.Header.Set("Accept", "application/json")
+	restclient.AddGraphQLQuery(req, b.rawQuery())
+{{- else if HasBody .ParseResult }}
+	body, contentType, err := b.body()
+	if err != nil {
+		return nil, err
+	}
+	if req, err = http.NewRequest(httpMethod, url, body); err != nil {
+		return nil, err
+	}
+	restclient.AddRawQuery(req, b.rawQuery())
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+{{- if .BodyStream }}
+	if b.bodyStream != nil && b.bodyStreamLength > 0 {
+		req.ContentLength = b.bodyStreamLength
+	}
+{{- end }}
+{{- else }}
+	if req, err = http.NewRequest(httpMethod, url, nil); err != nil {
+		return nil, err
+	}
+	restclient.AddRawQuery(req, b.rawQuery())
+{{- end }}
+{{- with .Accept }}
+	req.Header.Set("Accept", {{ printf "%q" . }})
+{{- else }}
+	req.Header.Set("Accept", restclient.DefaultAccept())
+{{- end }}
+	req.Header.Set("User-Agent", restclient.UserAgent(restClient))
+	restclient.SetVersionHeader(req, restClient, "{{ .Version }}")
 	for key, value := range b.headerParams {
 		req.Header.Set(key, value)
 	}
+{{- with .IdempotencyHeader }}
+	if req.Header.Get("{{ . }}") == "" {
+		req.Header.Set("{{ . }}", b.getIdempotencyKey())
+	}
+{{- end }}
+{{- if .Dictionary }}
+	if err := restclient.CompressRequest(req, "{{ .Dictionary }}"); err != nil {
+		return nil, err
+	}
+{{- end }}
+{{- if .Progress }}
+	restclient.TrackUploadProgress(req, b.progress)
+{{- end }}
 	return req, nil
 }
 
-{{ if and .ResponseType .SyncResponse }}
-func (b *{{ $.RequestType }}Impl) {{ $.SyncResponse | FunctionName }}() ({{ $.ResponseType }}, error) {
-	request, err := b.build()
+{{ if .IdempotencyHeader }}
+// getIdempotencyKey returns the idempotency key of the request builder, which is generated when the
+// request is first built so that running the request builder again sends the same key. Copies of
+// the request builder, such as the ones returned by Clone or by its setters, generate their own key.
+func (b *{{ .RequestType }}Impl) getIdempotencyKey() string {
+	if b.idempotencyKey == "" {
+		b.idempotencyKey = restclient.NewIdempotencyKey()
+	}
+	return b.idempotencyKey
+}
+{{ end }}
+// BuildRequest returns the request which is sent by the request builder, without sending it
+func (b *{{ .RequestType }}Impl) BuildRequest() (*http.Request, error) {
+	restClient, err := restclient.EnvironmentClient(b.environment)
 	if err != nil {
 		return nil, err
 	}
-	request.URL.RawQuery = request.URL.Query().Encode()
+	return b.build(restClient)
+}
 
-	restClient := restclient.GetClient()
-	if restClient == nil {
-		return nil, fmt.Errorf("A rest client has not been registered yet. You must call client.RegisterClient first")
+// send sends the request with restClient and the headers of its context and returns the response
+func (b *{{ .RequestType }}Impl) send(restClient restclient.Client, request *http.Request) (*http.Response, error) {
+	restclient.ApplyContextHeaders(request)
+	for _, hook := range b.onRequest {
+		hook(request)
 	}
-
 	if restClient.Debug() {
 		restclient.DebugRequest(request)
 	}
-
+	request, traced := restclient.TraceTimings("{{ .RequestType }}", request)
+{{ with .Hedge }}
+	response, err := restclient.DoHedged(restClient.HttpClient(), request, restclient.HedgePolicy{After: {{ Duration .After }}, Max: {{ .Max }}})
+{{- else }}
 	response, err := restClient.HttpClient().Do(request)
+{{- end }}
+	traced(response, err)
 	if err != nil {
 		return nil, err
 	}
+{{- if .Dictionary }}
+	if err := restclient.DecompressResponse(response); err != nil {
+		response.Body.Close()
+		return nil, err
+	}
+{{- end }}
+	for _, hook := range b.onResponse {
+		hook(response)
+	}
 
-	defer response.Body.Close()
 	if restClient.Debug() {
 		restclient.DebugResponse(response)
 	}
-
-	return New{{ $.ResponseType }}(response.Body)
+	return response, nil
 }
-{{ end }}
 
-{{ if and .CallbackType .AsyncResponse }}
-func (b *{{ $.RequestType }}Impl) {{ $.AsyncResponse | FunctionName }}({{ ParamsList $.AsyncResponse.Type }}) {
-	if {{ ParamName $.AsyncResponse.Type false 0 }} != nil {
-		{{ ParamName $.AsyncResponse.Type false 0 }}.OnStart()
-	}
+{{ if .SyncResponse }}
+{{- if .ResponseType }}
+{{ DocComment $.SyncResponse.Doc }}func (b *{{ $.RequestType }}Impl) {{ $.SyncResponse | FunctionName }}({{ ParamsList $.SyncResponse.Type }}) ({{ $.ResponseType }}, error) {
+	return b.run({{ with ContextParam $.SyncResponse.Type }}{{ . }}{{ else }}context.Background(){{ end }})
+}
+{{- else }}
+{{ DocComment $.SyncResponse.Doc }}func (b *{{ $.RequestType }}Impl) {{ $.SyncResponse | FunctionName }}({{ ParamsList $.SyncResponse.Type }}) error {
+	_, err := b.run({{ with ContextParam $.SyncResponse.Type }}{{ . }}{{ else }}context.Background(){{ end }})
+	return err
+}
+{{- end }}
 
-	go func(b *{{ $.RequestType }}Impl) {
-		response, err := b.{{ $.SyncResponse | FunctionName }}()
+// RunRequest sends the request with the context ctx and returns the response.
+// It implements restclient.Runner, which lets restclient.Batch run the request builder.
+func (b *{{ $.RequestType }}Impl) RunRequest(ctx context.Context) (interface{}, error) {
+	return b.run(ctx)
+}
 
-		if {{ ParamName $.AsyncResponse.Type false 0 }} != nil {
-			if err != nil {
-				{{ ParamName $.AsyncResponse.Type false 0 }}.OnError(err.Error())
-			} else {
-				{{ ParamName $.AsyncResponse.Type false 0 }}.OnSuccess(response)
-			}
+func (b *{{ $.RequestType }}Impl) run(ctx context.Context) (result {{ RunType $.ParseResult }}, err error) {
+	defer restclient.ProfileAllocations("{{ $.RequestType }}")()
+{{- if $.ResponseType }}
+	defer func() {
+		if err != nil && b.fallback != nil {
+			result, err = *b.fallback, &restclient.FallbackError{Err: err}
 		}
-	}(b)
-}
-{{ end }}
-`))
-	var buf bytes.Buffer
-	err := builderTemplate.Execute(&buf, r)
+	}()
+{{- end }}
+
+	restClient, err := restclient.EnvironmentClient(b.environment)
 	if err != nil {
-		log.Fatalf("Failed to generate template: %v", err)
-		return nil, err
+		return result, err
+	}
+	request, err := b.build(restClient)
+	if err != nil {
+		return result, err
 	}
 
-	formatted, err := format.Source(buf.Bytes())
+	response, err := b.send(restClient, request.WithContext(ctx))
 	if err != nil {
-		log.Fatalf("Failed to generate template: %v", err)
-		return nil, err
+		return result, err
+	}
+	defer response.Body.Close()
+	if err := restclient.CheckStatus(response{{ range $.AllowedStatus }}, {{ . }}{{ end }}); err != nil {
+		return result, err
+	}
+	if err := restclient.LimitBody(response, {{ with $.MaxBody }}{{ . }}{{ else }}restclient.MaxBodySize(restClient){{ end }}); err != nil {
+		return result, err
 	}
+{{- if not $.ResponseType }}
 
-	return formatted, nil
-}
+	// The response has no content, its body is drained so the connection can be reused
+	_, err = io.Copy(io.Discard, response.Body)
+	return result, err
+{{- else if eq $.ResponseType "http.Header" }}
 
-// getFunctionName returns the name of the function
-func getFunctionName(f *ast.Field) string {
-	return f.Names[0].Name
-}
+	return response.Header, nil
+{{- else if $.GraphQL }}
 
-// getAnnotationValue returns the value represented by the annotation in the field's comment
-func getAnnotationValue(f *ast.Field) string {
-	comment := f.Doc.Text()
-	if annotation, valid := parse.ExtractRequestAnnotation(comment); valid {
-		return annotation.Value
+	data, err := restclient.DecodeGraphQL(response.Body)
+	if err != nil {
+		return result, err
 	}
-	log.Fatalf("Must have query or path or field parameter defined.")
-	return ""
+	return restclient.Decode({{ Decoder $.ParseResult }}, data)
+{{- else }}
+	if !restclient.HasContent(response) {
+		return result, nil
+	}
+
+	return restclient.Decode({{ Decoder $.ParseResult }}, response.Body)
+{{- end }}
 }
+{{ end }}
 
-// getParamName returns the name of the parameter in the field's argument list
-func getParamName(function *ast.FuncType, forceString bool, index int) string {
+{{ if and .Poll .PollResponse }}
+{{ $ctx := ParamName .PollResponse.Type false 0 }}
+{{ DocComment .PollResponse.Doc }}func (b *{{ $.RequestType }}Impl) {{ FunctionName .PollResponse }}({{ ParamsList .PollResponse.Type }}) (result {{ .Poll.ResponseType }}, err error) {
+	defer restclient.ProfileAllocations("{{ $.RequestType }}")()
+
+	restClient, err := restclient.EnvironmentClient(b.environment)
+	if err != nil {
+		return result, err
+	}
+	request, err := b.build(restClient)
+	if err != nil {
+		return result, err
+	}
+
+	response, err := b.send(restClient, request.WithContext({{ $ctx }}))
+	if err != nil {
+		return result, err
+	}
+	defer response.Body.Close()
+	if err := restclient.CheckStatus(response{{ range $.AllowedStatus }}, {{ . }}{{ end }}); err != nil {
+		return result, err
+	}
+	if err := restclient.LimitBody(response, {{ with $.MaxBody }}{{ . }}{{ else }}restclient.MaxBodySize(restClient){{ end }}); err != nil {
+		return result, err
+	}
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return result, err
+	}
+
+	operationURL, err := restclient.OperationURL(response, data, "{{ .Poll.URL }}")
+	if err != nil {
+		return result, err
+	}
+	poll := func(ctx context.Context) ([]byte, error) {
+		pollRequest, err := restclient.NewPollRequest(request, operationURL)
+		if err != nil {
+			return nil, err
+		}
+		response, err := b.send(restClient, pollRequest.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+		if err := restclient.CheckStatus(response{{ range $.AllowedStatus }}, {{ . }}{{ end }}); err != nil {
+			return nil, err
+		}
+		if err := restclient.LimitBody(response, {{ with $.MaxBody }}{{ . }}{{ else }}restclient.MaxBodySize(restClient){{ end }}); err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(response.Body)
+	}
+	data, err = restclient.Poll({{ $ctx }}, data, poll, restclient.PollPolicy{
+		StatusField: "{{ .Poll.StatusField }}",
+		Until:       []string{ {{- range $i, $state := .Poll.Until }}{{ if $i }}, {{ end }}{{ printf "%q" $state }}{{ end -}} },
+		Interval:    {{ Duration .Poll.Interval }},
+{{- with .Poll.Timeout }}
+		Timeout:     {{ Duration . }},
+{{- end }}
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return restclient.Decode({{ Constructor .Poll.ResponseType }}, bytes.NewReader(data))
+}
+{{ end }}
+
+{{ with .Download }}
+{{ DocComment .Doc }}func (b *{{ $.RequestType }}Impl) {{ FunctionName . }}({{ ParamsList .Type }}) (int64, error) {
+	defer restclient.ProfileAllocations("{{ $.RequestType }}")()
+
+	restClient, err := restclient.EnvironmentClient(b.environment)
+	if err != nil {
+		return 0, err
+	}
+	request, err := b.build(restClient)
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := b.send(restClient, request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	return restclient.DownloadResponse(response, {{ ParamName .Type false 0 }}, {{ if eq (len .Type.Params.List) 2 }}{{ ParamName .Type false 1 }}{{ else }}nil{{ end }})
+}
+{{ end }}
+
+{{ if and .WebSocket .Connect }}
+{{ DocComment .Connect.Doc }}func (b *{{ $.RequestType }}Impl) {{ FunctionName .Connect }}({{ ParamsList .Connect.Type }}) (*restclient.WebSocket[{{ .WebSocket.Send }}, {{ .WebSocket.Receive }}], error) {
+	restClient, err := restclient.EnvironmentClient(b.environment)
+	if err != nil {
+		return nil, err
+	}
+	request, err := b.build(restClient)
+	if err != nil {
+		return nil, err
+	}
+	restclient.UpgradeWebSocket(request)
+
+	response, err := b.send(restClient, request{{ with ContextParam .Connect.Type }}.WithContext({{ . }}){{ end }})
+	if err != nil {
+		return nil, err
+	}
+	return restclient.NewWebSocket[{{ .WebSocket.Send }}, {{ .WebSocket.Receive }}](response)
+}
+{{ end }}
+
+{{ if and .CallbackType .AsyncResponse }}
+{{ $ctx := ContextParam $.AsyncResponse.Type }}{{ $callback := CallbackParam $.AsyncResponse.Type }}
+{{ DocComment $.AsyncResponse.Doc }}func (b *{{ $.RequestType }}Impl) {{ $.AsyncResponse | FunctionName }}({{ ParamsList $.AsyncResponse.Type }}) {
+	if {{ $callback }} != nil {
+		{{ $callback }}.OnStart()
+	}
+
+	// The request may wait for the executor of asynchronous requests, so it is sent with a copy
+{{- if $.IdempotencyHeader }}
+	// which sends the idempotency key of the request builder
+	key := b.getIdempotencyKey()
+	b = b.clone()
+	b.idempotencyKey = key
+{{- else }}
+	b = b.clone()
+{{- end }}
+	err := restclient.RunAsync(func() {
+		response, err := b.run({{ with $ctx }}{{ . }}{{ else }}context.Background(){{ end }})
+{{- with $ctx }}
+		if ctxErr := {{ . }}.Err(); err != nil && ctxErr != nil {
+			// The request was cancelled, or never sent when the context was done while it waited
+			err = ctxErr
+		}
+{{- end }}
+
+		if {{ $callback }} != nil {
+			if err != nil {
+				{{ $callback }}.OnError(err.Error())
+			} else {
+				{{ $callback }}.OnSuccess(response)
+			}
+		}
+	})
+	if err != nil && {{ $callback }} != nil {
+		// The request was rejected by the executor of asynchronous requests
+		{{ $callback }}.OnError(err.Error())
+	}
+}
+{{ end }}
+
+{{ if and .ResponseType .PaginatedResponse }}
+{{ $ctx := ParamName .PaginatedResponse.Type false 0 }}{{ $fn := ParamName .PaginatedResponse.Type false 1 }}
+{{ DocComment $.PaginatedResponse.Doc }}func (b *{{ $.RequestType }}Impl) {{ $.PaginatedResponse | FunctionName }}({{ ParamsList $.PaginatedResponse.Type }}) error {
+	defer restclient.ProfileAllocations("{{ $.RequestType }}")()
+
+	// Request the next pages with a copy to leave the requested page unchanged
+	b = b.clone()
+	restClient, err := restclient.EnvironmentClient(b.environment)
+	if err != nil {
+		return err
+	}
+
+	for {
+		request, err := b.build(restClient)
+		if err != nil {
+			return err
+		}
+
+		response, err := b.send(restClient, request.WithContext({{ $ctx }}))
+		if err != nil {
+			return err
+		}
+		if err := restclient.CheckStatus(response{{ range $.AllowedStatus }}, {{ . }}{{ end }}); err != nil {
+			response.Body.Close()
+			return err
+		}
+		if err := restclient.LimitBody(response, {{ with $.MaxBody }}{{ . }}{{ else }}restclient.MaxBodySize(restClient){{ end }}); err != nil {
+			response.Body.Close()
+			return err
+		}
+
+		data, err := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		result, err := restclient.Decode({{ Decoder $.ParseResult }}, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		if !{{ $fn }}(result) {
+			return nil
+		}
+{{ if $.Pagination.Cursor }}
+		cursor, err := restclient.PageValue(data, "{{ $.Pagination.Cursor }}")
+		if err != nil {
+			return err
+		}
+		if cursor == "" {
+			return nil
+		}
+		b.queryParams.Set("{{ $.Pagination.Param }}", cursor)
+{{ else }}
+		pages, err := restclient.PageValue(data, "{{ $.Pagination.Pages }}")
+		if err != nil {
+			return err
+		}
+		total, err := strconv.Atoi(pages)
+		if err != nil {
+			return err
+		}
+		current, err := strconv.Atoi(b.queryParams.Get("{{ $.Pagination.Param }}"))
+		if err != nil {
+			current = 1
+		}
+		if current >= total {
+			return nil
+		}
+		b.queryParams.Set("{{ $.Pagination.Param }}", strconv.Itoa(current+1))
+{{ end }}
+	}
+}
+{{ end }}
+{{ end }}
+`))
+
+// Options configures the generated implementation.
+type Options struct {
+	// Layout determines how the implementation is split in to files
+	Layout Layout
+	// FileNaming determines the names of the files of the request builders in the per endpoint
+	// layout, FileNamingBuilder is used when empty
+	FileNaming FileNaming
+	// FileSuffix is the suffix of the names of the files of the request builders in the per
+	// endpoint layout, DefaultFileSuffix is used when empty
+	FileSuffix string
+	// Immutable generates setters which return a modified copy of the request builder rather than
+	// modifying it, which makes request builders safe to share between goroutines
+	Immutable bool
+}
+
+// templateData is the data the request builder templates are executed with
+type templateData struct {
+	*parse.ParseResult
+	Options
+}
+
+// fileData is the data the file templates are executed with
+type fileData struct {
+	PackageName string
+	Builders    []templateData
+}
+
+// newFileData returns the data of a file containing the request builders
+func newFileData(results []*parse.ParseResult, options Options) fileData {
+	data := fileData{PackageName: results[0].PackageName}
+	for _, r := range results {
+		data.Builders = append(data.Builders, templateData{r, options})
+	}
+	return data
+}
+
+// getCallbacks returns the request builders declaring a callback interface, keeping the first
+// request builder of each callback so that a callback shared by request builders is declared once.
+// Returns an error when request builders share a callback with different response types.
+func getCallbacks(builders []templateData) ([]templateData, error) {
+	declared := make(map[string]templateData)
+	var callbacks []templateData
+	for _, b := range builders {
+		if b.CallbackType == "" || !isLocalType(b.CallbackType) {
+			continue
+		}
+		if other, ok := declared[b.CallbackType]; ok {
+			if other.ResponseType != b.ResponseType {
+				return nil, fmt.Errorf("Callback %s of %s and %s has the different response types %s and %s",
+					b.CallbackType, other.RequestType, b.RequestType, other.ResponseType, b.ResponseType)
+			}
+			continue
+		}
+		declared[b.CallbackType] = b
+		callbacks = append(callbacks, b)
+	}
+	return callbacks, nil
+}
+
+// Generate generates the implementation using the details contained in ParseResult.
+func Generate(r *parse.ParseResult) ([]byte, error) {
+	return render("single", newFileData([]*parse.ParseResult{r}, Options{Layout: LayoutSingle}))
+}
+
+// GenerateFiles generates the implementation using the details contained in ParseResult
+// and splits it in to files according to the layout of the options.
+func GenerateFiles(r *parse.ParseResult, options Options) ([]File, error) {
+	return GenerateAll([]*parse.ParseResult{r}, options)
+}
+
+// GenerateAll generates the implementation of several request builders of the same package
+// and splits it in to files according to the layout of the options. The single layout generates
+// all request builders in to the output file, importing each package once.
+func GenerateAll(results []*parse.ParseResult, options Options) ([]File, error) {
+	if len(results) == 0 {
+		return nil, fmt.Errorf("No request builders to generate")
+	}
+
+	var files []File
+	switch options.Layout {
+	case LayoutSingle:
+		src, err := render("single", newFileData(results, options))
+		if err != nil {
+			return nil, err
+		}
+		files = []File{{Source: src}}
+		files, err = appendTestFiles(files, "", results, options)
+		if err != nil {
+			return nil, err
+		}
+	case LayoutPerEndpoint:
+		names, err := getFileNames(results, options)
+		if err != nil {
+			return nil, err
+		}
+		shared, err := render("shared", newFileData(results, options))
+		if err != nil {
+			return nil, err
+		}
+		files = []File{{Source: shared}}
+		for i, r := range results {
+			name := names[i]
+			builder, err := render("endpoint", newFileData([]*parse.ParseResult{r}, options))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, File{Name: name, Source: builder})
+			files, err = appendTestFiles(files, name, []*parse.ParseResult{r}, options)
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported layout %q", options.Layout)
+	}
+
+	return files, nil
+}
+
+// appendTestFiles appends the example and conformance tests of the request builders with examples
+// to files. The tests are named after the file named name, which contains the request builders.
+func appendTestFiles(files []File, name string, results []*parse.ParseResult, options Options) ([]File, error) {
+	var examples []*parse.ParseResult
+	for _, r := range results {
+		if len(r.Examples) > 0 {
+			examples = append(examples, r)
+		}
+	}
+	if len(examples) == 0 {
+		return files, nil
+	}
+
+	data := newFileData(examples, options)
+	test, err := render("test", data)
+	if err != nil {
+		return nil, err
+	}
+	conformance, err := render("conformance", data)
+	if err != nil {
+		return nil, err
+	}
+	return append(files,
+		File{Name: name, Source: test, Suffix: "_test.go"},
+		File{Name: name, Source: conformance, Suffix: "_conformance_test.go"},
+	), nil
+}
+
+// render executes the named template and returns the formatted source with unused imports removed.
+func render(name string, data fileData) ([]byte, error) {
+	var buf bytes.Buffer
+	err := templates.ExecuteTemplate(&buf, name, data)
+	if err != nil {
+		log.Fatalf("Failed to generate template: %v", err)
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", buf.Bytes(), parser.ParseComments)
+	if err != nil {
+		log.Fatalf("Failed to generate template: %v", err)
+		return nil, err
+	}
+	imports := append([]*ast.ImportSpec(nil), file.Imports...)
+	for _, spec := range imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if !astutil.UsesImport(file, path) {
+			name := ""
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			astutil.DeleteNamedImport(fset, file, name, path)
+		}
+	}
+
+	var formatted bytes.Buffer
+	if err := format.Node(&formatted, fset, file); err != nil {
+		log.Fatalf("Failed to generate template: %v", err)
+		return nil, err
+	}
+
+	return format.Source(formatted.Bytes())
+}
+
+// getFileNames returns the names of the files containing the implementation of each request
+// builder in the per endpoint layout. Returns an error when the naming or the suffix of the options
+// is invalid, or when two request builders would be generated in to the same file.
+func getFileNames(results []*parse.ParseResult, options Options) ([]string, error) {
+	suffix := options.FileSuffix
+	if suffix == "" {
+		suffix = DefaultFileSuffix
+	}
+	if !strings.HasSuffix(suffix, ".go") || strings.HasSuffix(suffix, "_test.go") || strings.ContainsAny(suffix, `/\`) {
+		return nil, fmt.Errorf("Unsupported file suffix %q, expected a suffix such as %s", suffix, DefaultFileSuffix)
+	}
+
+	names := make([]string, len(results))
+	builders := make(map[string]string)
+	for i, r := range results {
+		switch options.FileNaming {
+		case "", FileNamingBuilder:
+			names[i] = getSnakeCase(r.RequestType) + suffix
+		case FileNamingResource:
+			names[i] = getResourceName(r.RequestType) + suffix
+		default:
+			return nil, fmt.Errorf("Unsupported file naming %q", options.FileNaming)
+		}
+		if other, ok := builders[names[i]]; ok {
+			return nil, fmt.Errorf("Request builders %s and %s are both generated in to file %s", other, r.RequestType, names[i])
+		}
+		builders[names[i]] = r.RequestType
+	}
+	return names, nil
+}
+
+// getSnakeCase returns the name in snake case
+// Example: GetPhotoDetailsRequestBuilder -> get_photo_details_request_builder
+func getSnakeCase(s string) string {
+	runes := []rune(s)
+	var name []rune
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word at a lower to upper case transition and at the end of an acronym
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				name = append(name, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		name = append(name, r)
+	}
+	return string(name)
+}
+
+// getResourceName returns the name of the resource of a request builder followed by its action,
+// which is the first word of the name of the request builder
+// Example: GetPhotoDetailsRequestBuilder -> photo_details_get, SearchRequestBuilder -> search
+func getResourceName(requestType string) string {
+	words := strings.Split(getSnakeCase(strings.TrimSuffix(requestType, "RequestBuilder")), "_")
+	return strings.Join(append(words[1:], words[0]), "_")
+}
+
+// importSpec is an import declaration of a generated file
+type importSpec struct {
+	Name string
+	Path string
+}
+
+// getExtraImports returns the imports required by the parameter, response and callback
+// types of the request builders which are not already imported by the generated implementation.
+// A package imported by several request builders is imported once, unless they import it with
+// different names.
+func getExtraImports(builders []templateData) []importSpec {
+	imported := make(map[importSpec]bool)
+	for _, path := range append(builderImports, "github.com/jsaund/gorest/restclient") {
+		imported[importSpec{Path: path}] = true
+	}
+
+	var extra []importSpec
+	for _, b := range builders {
+		for path, name := range b.Imports {
+			spec := importSpec{Name: name, Path: path}
+			if !imported[spec] {
+				imported[spec] = true
+				extra = append(extra, spec)
+			}
+		}
+	}
+	sort.Slice(extra, func(i, j int) bool {
+		if extra[i].Path != extra[j].Path {
+			return extra[i].Path < extra[j].Path
+		}
+		return extra[i].Name < extra[j].Name
+	})
+	return extra
+}
+
+// getRunType returns the type of the response returned by the @SYNC method of the request builder,
+// which is an empty struct when the method returns only an error
+func getRunType(r *parse.ParseResult) string {
+	if r.ResponseType == "" {
+		return "struct{}"
+	}
+	return r.ResponseType
+}
+
+// isDecoded returns true if the body of the response is decoded in to the response type, rather
+// than the response having no content or only its headers being returned
+func isDecoded(r *parse.ParseResult) bool {
+	return r.ResponseType != "" && r.ResponseType != "http.Header"
+}
+
+// getConstructor returns the name of the function used to create a response of the given type
+// The constructor of a generic type is instantiated with the type arguments of the type.
+// Example: PhotoResponse -> NewPhotoResponse, models.Photo -> models.NewPhoto, Page[Photo] -> NewPage[Photo]
+func getConstructor(typeName string) string {
+	typeName, typeArgs := splitTypeArguments(strings.TrimPrefix(typeName, "*"))
+	if i := strings.LastIndex(typeName, "."); i >= 0 {
+		return typeName[:i+1] + "New" + typeName[i+1:] + typeArgs
+	}
+	return "New" + typeName + typeArgs
+}
+
+// getDecoder returns the name of the function decoding the body of the response of the request
+// builder, which is the function named by @DECODER or the constructor of the response type
+func getDecoder(r *parse.ParseResult) string {
+	if r.Decoder != "" {
+		return r.Decoder
+	}
+	return getConstructor(r.ResponseType)
+}
+
+// isLocalType returns true if the type is declared in the package being generated
+func isLocalType(typeName string) bool {
+	typeName, _ = splitTypeArguments(typeName)
+	return !strings.Contains(typeName, ".")
+}
+
+// splitTypeArguments splits the name of a generic type from its type arguments
+// Example: models.Page[models.Photo] -> models.Page, [models.Photo]
+func splitTypeArguments(typeName string) (string, string) {
+	if i := strings.Index(typeName, "["); i >= 0 {
+		return typeName[:i], typeName[i:]
+	}
+	return typeName, ""
+}
+
+// example is a request builder configured with the arguments of an @EXAMPLE annotation
+// along with the request it is expected to build. The path, query and header values are Go
+// expressions, as the expected values of parameters which are formatted are computed by the test.
+type example struct {
+	Calls   []string
+	Method  string
+	Path    string
+	Query   string
+	Headers map[string]string
+}
+
+// getExamples returns the examples of the request builder.
+// Example arguments are matched to setters by name, ignoring case, by the parser which records
+// the type underlying the parameter of each setter in ExampleTypes.
+func getExamples(r *parse.ParseResult) []example {
+	setters := make(map[string]*ast.Field)
+	for _, params := range []map[string]*ast.Field{
+		r.PathSubstitutions,
+		r.QueryParams,
+		r.PostFormParams,
+		r.PostMultiPartParams,
+		r.HeaderParams,
+	} {
+		for name, f := range params {
+			setters[strings.ToLower(name)] = f
+		}
+	}
+
+	var examples []example
+	for _, args := range r.Examples {
+		e := example{
+			Method:  r.HttpMethod,
+			Headers: make(map[string]string),
+		}
+		substitutions := make(map[string]string)
+		query := make(map[string][]string)
+		encodedQuery := make(map[string][]string)
+		constant := true
+
+		keys := make([]string, 0, len(args))
+		for key := range args {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			f, ok := setters[strings.ToLower(key)]
+			if !ok {
+				// Reported by the parser
+				continue
+			}
+			literal, value := getExampleValue(f, r.ExampleTypes[getFunctionName(f)], args[key])
+			e.Calls = append(e.Calls, fmt.Sprintf("%s(%s)", getFunctionName(f), literal))
+
+			name := getAnnotationValue(f)
+			switch f {
+			case r.PathSubstitutions[getFunctionName(f)]:
+				substitutions[name] = value
+			case r.QueryParams[getFunctionName(f)]:
+				if isEncoded(f) {
+					encodedQuery[name] = append(encodedQuery[name], value)
+				} else {
+					query[name] = append(query[name], value)
+				}
+				_, err := strconv.Unquote(value)
+				constant = constant && err == nil
+			case r.HeaderParams[getFunctionName(f)]:
+				e.Headers[name] = value
+			}
+		}
+
+		e.Path = getExamplePath(r.ApiEndpoint, substitutions)
+		if constant {
+			e.Query = strconv.Quote(restclient.EncodeExampleQuery(unquoteValues(query), unquoteValues(encodedQuery)))
+		} else {
+			e.Query = "restclient.EncodeExampleQuery(" + getValuesLiteral(query) + ", " + getValuesLiteral(encodedQuery) + ")"
+		}
+		examples = append(examples, e)
+	}
+	return examples
+}
+
+// getExampleValue returns the literal of the example value of the parameter of the setter f,
+// whose underlying type is given, along with the expression of the string the parameter is
+// expected to be sent as. The expression is a constant unless the parameter is formatted by the
+// request builder at run time.
+// Example: PhotoID(id PhotoID) with value abc -> PhotoID("abc"), restclient.FormatExample(PhotoID("abc"))
+func getExampleValue(f *ast.Field, underlying string, value string) (string, string) {
+	paramType := getParamType(f.Type.(*ast.FuncType).Params.List[0].Type)
+	layout := ""
+	if annotation, valid := parse.ExtractAnnotation("FORMAT", f.Doc.Text()); valid {
+		layout = annotation.Value
+	}
+
+	var literal string
+	switch underlying {
+	case "string":
+		literal = strconv.Quote(value)
+	case "time.Duration":
+		literal = getDuration(value)
+	case "time.Time":
+		switch layout {
+		case "unix":
+			literal = "time.Unix(" + value + ", 0)"
+		case "unixmilli":
+			literal = "time.UnixMilli(" + value + ")"
+		case "unixnano":
+			literal = "time.Unix(0, " + value + ")"
+		default:
+			format := "time.RFC3339"
+			if layout != "" && !strings.HasPrefix(layout, "%") {
+				format = strconv.Quote(layout)
+			}
+			literal = "restclient.ParseExampleTime(" + format + ", " + strconv.Quote(value) + ")"
+		}
+	default:
+		literal = value
+	}
+	if paramType != underlying {
+		literal = paramType + "(" + literal + ")"
+	}
+
+	// Parameters of a basic type are sent as is unless formatted
+	if paramType == underlying && layout == "" {
+		switch underlying {
+		case "string":
+			return literal, literal
+		case "bool":
+			v, _ := strconv.ParseBool(value)
+			return literal, strconv.Quote(strconv.FormatBool(v))
+		case "int", "int8", "int16", "int32", "int64":
+			v, _ := strconv.ParseInt(value, 0, 64)
+			return literal, strconv.Quote(strconv.FormatInt(v, 10))
+		case "uint", "uint8", "uint16", "uint32", "uint64", "uintptr":
+			v, _ := strconv.ParseUint(value, 0, 64)
+			return literal, strconv.Quote(strconv.FormatUint(v, 10))
+		}
+	}
+	return literal, formatParam(f, literal, "restclient.FormatExample("+literal+")")
+}
+
+// getExamplePath returns the expression of the path of the example, which substitutes the path
+// parameters of the endpoint with the expressions of their values
+// Example: /photos/{id} -> "/photos/" + restclient.FormatExample(PhotoID("abc"))
+func getExamplePath(endpoint string, substitutions map[string]string) string {
+	var parts []string
+	constant := ""
+	for endpoint != "" {
+		start := strings.Index(endpoint, "{")
+		end := strings.Index(endpoint, "}")
+		if start < 0 || end < start {
+			constant += endpoint
+			break
+		}
+		constant += endpoint[:start]
+		value, ok := substitutions[endpoint[start+1:end]]
+		if s, err := strconv.Unquote(value); !ok || err == nil {
+			if !ok {
+				s = endpoint[start : end+1]
+			}
+			constant += s
+		} else {
+			if constant != "" {
+				parts = append(parts, strconv.Quote(constant))
+				constant = ""
+			}
+			parts = append(parts, value)
+		}
+		endpoint = endpoint[end+1:]
+	}
+	if constant != "" || len(parts) == 0 {
+		parts = append(parts, strconv.Quote(constant))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// getValuesLiteral returns the url.Values literal of the expressions of the parameter values
+func getValuesLiteral(values map[string][]string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var s string
+	for _, key := range keys {
+		s += strconv.Quote(key) + ": {" + strings.Join(values[key], ", ") + "}, "
+	}
+	return "url.Values{" + strings.TrimSuffix(s, ", ") + "}"
+}
+
+// unquoteValues returns the parameter values given as constant expressions
+func unquoteValues(values map[string][]string) url.Values {
+	unquoted := url.Values{}
+	for key, expressions := range values {
+		for _, expression := range expressions {
+			value, _ := strconv.Unquote(expression)
+			unquoted.Add(key, value)
+		}
+	}
+	return unquoted
+}
+
+// hasBody returns true if the request of the request builder has a body, which is the case for POST
+// and PUT requests and for requests of other methods annotated with @ALLOW_BODY
+func hasBody(r *parse.ParseResult) bool {
+	return r.HttpMethod == "POST" || r.HttpMethod == "PUT" || r.AllowBody
+}
+
+// getFunctionName returns the name of the function
+func getFunctionName(f *ast.Field) string {
+	return f.Names[0].Name
+}
+
+// getContentType returns the content type of the body streamed by the @BODY_STREAM method f,
+// which defaults to application/octet-stream
+func getContentType(f *ast.Field) string {
+	if contentType := getAnnotationValue(f); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// getAnnotationValue returns the value represented by the annotation in the field's comment
+func getAnnotationValue(f *ast.Field) string {
+	comment := f.Doc.Text()
+	if annotation, valid := parse.ExtractRequestAnnotation(comment); valid {
+		return annotation.Value
+	}
+	log.Fatalf("Must have query or path or field parameter defined.")
+	return ""
+}
+
+// isRequired returns true if the parameter must be set before the request is built
+// Path parameters are always required, other parameters are required when annotated
+// with the required flag, for example @QUERY("q", required)
+func isRequired(f *ast.Field) bool {
+	annotation, valid := parse.ExtractRequestAnnotation(f.Doc.Text())
+	if !valid {
+		return false
+	}
+	return annotation.Key == "PATH" || annotation.Args["required"] == "true"
+}
+
+// isEncoded returns true if the value of the parameter is already encoded by the caller
+// and must be sent as is, for example @PATH("id", encoded=true)
+func isEncoded(f *ast.Field) bool {
+	annotation, valid := parse.ExtractRequestAnnotation(f.Doc.Text())
+	return valid && annotation.Args["encoded"] == "true"
+}
+
+// isList returns true if the parameter of the setter is a slice, an array or a map which is
+// expanded as a list by the URI template of the endpoint, such as {/segments*}
+func isList(f *ast.Field) bool {
+	if _, formatted := parse.ExtractAnnotation("FORMAT", f.Doc.Text()); formatted {
+		return false
+	}
+	switch f.Type.(*ast.FuncType).Params.List[0].Type.(type) {
+	case *ast.ArrayType, *ast.MapType, *ast.Ellipsis:
+		return true
+	}
+	return false
+}
+
+// getPartValue returns the expression of the body of the part set by the @PART method f
+// A []byte is sent as is and an io.Reader is read in to memory, other parameters are converted to
+// strings like the parameters of the other setters, see getParamString.
+func getPartValue(f *ast.Field) string {
+	function := f.Type.(*ast.FuncType)
+	if _, formatted := parse.ExtractAnnotation("FORMAT", f.Doc.Text()); !formatted {
+		paramName := getParamName(function, false, 0)
+		switch getParamType(function.Params.List[0].Type) {
+		case "[]byte":
+			return paramName
+		case "io.Reader":
+			return "b.readPart(" + strconv.Quote(getAnnotationValue(f)) + ", " + paramName + ")"
+		}
+	}
+	return "[]byte(" + getParamString(f) + ")"
+}
+
+// isOptionalPath returns true if the variable set by the @PATH method f is in an expression of the
+// URI template of the endpoint which is omitted when not set, such as {/id} or {?fields}
+func isOptionalPath(endpoint string, f *ast.Field) bool {
+	if annotation, valid := parse.ExtractRequestAnnotation(f.Doc.Text()); !valid || annotation.Key != "PATH" {
+		return false
+	}
+	template, err := restclient.ParseURITemplate(endpoint)
+	if err != nil {
+		return false
+	}
+	for _, v := range template.Variables() {
+		if v.Name == getAnnotationValue(f) {
+			return v.Optional()
+		}
+	}
+	return false
+}
+
+// getDocComment returns the doc comment of a generated declaration, which is the doc comment of the
+// declaration it implements without the annotations. A declaration annotated with @DEPRECATED gets
+// a Deprecated paragraph.
+func getDocComment(doc *ast.CommentGroup) string {
+	text := parse.StripAnnotations(doc.Text())
+	if annotation, valid := parse.ExtractAnnotation("DEPRECATED", doc.Text()); valid {
+		message := annotation.Value
+		if message == "" {
+			message = "this should no longer be used."
+		}
+		if text != "" {
+			text += "\n\n"
+		}
+		text += "Deprecated: " + message
+	}
+	if text == "" {
+		return ""
+	}
+	var comment string
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" {
+			comment += "//\n"
+		} else {
+			comment += "// " + line + "\n"
+		}
+	}
+	return comment
+}
+
+// getDeprecation returns the statement reporting the use of the request builder or setter name
+// when its declaration is annotated with @DEPRECATED, otherwise the empty string.
+func getDeprecation(name string, doc *ast.CommentGroup) string {
+	annotation, valid := parse.ExtractAnnotation("DEPRECATED", doc.Text())
+	if !valid {
+		return ""
+	}
+	return "restclient.WarnDeprecated(" + strconv.Quote(name) + ", " + strconv.Quote(annotation.Value) + ")"
+}
+
+// getParamString returns the expression converting the first parameter of the setter to a string
+// The conversion is controlled by the @FORMAT annotation of the setter, which is either:
+// - unix, unixmilli or unixnano to format a time.Time as a Unix timestamp
+// - a fmt verb such as %.2f to control the precision of floating point numbers
+// - a layout passed to the Format method of the parameter, such as a time.Time layout
+// Parameters of the predeclared string, boolean and numeric types without a @FORMAT annotation are
+// converted with strconv, other parameters are converted by restclient.FormatParam, which uses the
+// encoding.TextMarshaler or fmt.Stringer implementation of the parameter when available.
+func getParamString(f *ast.Field) string {
+	function := f.Type.(*ast.FuncType)
+	paramName := getParamName(function, false, 0)
+	if conversion, ok := getBasicParamString(function.Params.List[0].Type, paramName); ok {
+		return formatParam(f, paramName, conversion)
+	}
+	return formatParam(f, paramName, "b.formatParam("+strconv.Quote(getAnnotationValue(f))+", "+paramName+")")
+}
+
+// getBasicParamString returns the strconv expression converting the value of a predeclared type to
+// the same string as restclient.FormatParam, without boxing the value in an interface{}
+func getBasicParamString(t ast.Expr, value string) (string, bool) {
+	ident, ok := t.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	switch ident.Name {
+	case "string":
+		return value, true
+	case "bool":
+		return "strconv.FormatBool(" + value + ")", true
+	case "int64":
+		return "strconv.FormatInt(" + value + ", 10)", true
+	case "int", "int8", "int16", "int32", "rune":
+		return "strconv.FormatInt(int64(" + value + "), 10)", true
+	case "uint64":
+		return "strconv.FormatUint(" + value + ", 10)", true
+	case "uint", "uint8", "uint16", "uint32", "byte":
+		return "strconv.FormatUint(uint64(" + value + "), 10)", true
+	case "float64":
+		return "strconv.FormatFloat(" + value + ", 'g', -1, 64)", true
+	case "float32":
+		return "strconv.FormatFloat(float64(" + value + "), 'g', -1, 32)", true
+	}
+	return "", false
+}
+
+// formatParam returns the expression converting the value of the parameter of the setter f to a
+// string as controlled by its @FORMAT annotation, or the expression unformatted when the setter
+// is not annotated.
+func formatParam(f *ast.Field, value string, unformatted string) string {
+	annotation, valid := parse.ExtractAnnotation("FORMAT", f.Doc.Text())
+	if !valid {
+		return unformatted
+	}
+
+	switch format := annotation.Value; {
+	case format == "unix":
+		return "strconv.FormatInt(" + value + ".Unix(), 10)"
+	case format == "unixmilli":
+		return "strconv.FormatInt(" + value + ".UnixMilli(), 10)"
+	case format == "unixnano":
+		return "strconv.FormatInt(" + value + ".UnixNano(), 10)"
+	case strings.HasPrefix(format, "%"):
+		return "fmt.Sprintf(" + strconv.Quote(format) + ", " + value + ")"
+	default:
+		return value + ".Format(" + strconv.Quote(format) + ")"
+	}
+}
+
+// getParamName returns the name of the parameter in the field's argument list
+func getParamName(function *ast.FuncType, forceString bool, index int) string {
 	p := function.Params
 	if len(p.List) == 0 {
 		log.Fatalf("Function does not have any parameters")
@@ -283,6 +1939,48 @@ func getParamName(function *ast.FuncType, forceString bool, index int) string {
 	return paramName
 }
 
+// getDuration returns the Go expression of a duration such as 150ms
+// Example: 150ms -> 150 * time.Millisecond, 2s -> 2 * time.Second, 1m30s -> 90 * time.Second
+func getDuration(s string) string {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		log.Fatalf("Invalid duration %s: %v", s, err)
+		return ""
+	}
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d * %s", d/u.unit, u.name)
+		}
+	}
+	return fmt.Sprintf("%d * time.Nanosecond", d)
+}
+
+// getContextParam returns the name of the context.Context parameter of the function, if any
+func getContextParam(function *ast.FuncType) string {
+	for _, f := range function.Params.List {
+		if len(f.Names) > 0 && getParamType(f.Type) == "context.Context" {
+			return f.Names[0].Name
+		}
+	}
+	return ""
+}
+
+// getCallbackParam returns the name of the callback parameter of an @ASYNC method, which is its
+// last parameter
+func getCallbackParam(function *ast.FuncType) string {
+	return getParamName(function, false, len(function.Params.List)-1)
+}
+
 // getParamsList returns a comma separated list of parameter name, parameter type pairs
 // Example: size int8, name string, lat float64
 func getParamsList(function *ast.FuncType) string {
@@ -298,6 +1996,17 @@ func getParamsList(function *ast.FuncType) string {
 	return s
 }
 
+// getResultType returns the type of the single result of the function
+// Setters of an embedded interface return the embedded interface rather than the request builder
+func getResultType(function *ast.FuncType) string {
+	r := function.Results
+	if r == nil || len(r.List) != 1 {
+		log.Fatalf("Function must have exactly one result")
+		return ""
+	}
+	return getParamType(r.List[0].Type)
+}
+
 // getParamType will return the parameter type
 func getParamType(e ast.Expr) string {
 	switch v := e.(type) {
@@ -307,6 +2016,9 @@ func getParamType(e ast.Expr) string {
 		return "*" + getParamType(v.X)
 	case *ast.SelectorExpr:
 		return getParamType(v.X) + "." + getParamType(v.Sel)
+	case *ast.ArrayType, *ast.MapType, *ast.FuncType, *ast.InterfaceType, *ast.ChanType, *ast.Ellipsis,
+		*ast.IndexExpr, *ast.IndexListExpr:
+		return types.ExprString(v)
 	default:
 		log.Fatalf("Unrecognized expression type: %v", e)
 		return ""
@@ -1,9 +1,13 @@
 package generate
 
 import (
+	"fmt"
 	"go/ast"
+	"go/importer"
 	"go/parser"
 	"go/token"
+	"go/types"
+	"strings"
 	"testing"
 
 	"github.com/jsaund/gorest/parse"
@@ -35,12 +39,13 @@ func TestGenerateValid(t *testing.T) {
 package test
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"mime/multipart"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/jsaund/gorest/restclient"
@@ -53,133 +58,273 @@ type GetPhotoDetailsCallback interface {
 }
 
 type GetPhotoDetailsRequestBuilderImpl struct {
-	pathSubstitutions  map[string]string
+	pathSubstitutions  map[string]interface{}
 	queryParams        url.Values
+	encodedQueryParams url.Values
 	postFormParams     url.Values
-	postBody           interface{}
+	postBody           json.RawMessage
 	postMultiPartParam map[string][]byte
 	headerParams       map[string]string
+	err                error
+	onRequest          []func(*http.Request)
+	onResponse         []func(*http.Response)
+	environment        string
+	fallback           *GetPhotoDetailsResponse
 }
 
 func NewGetPhotoDetailsRequestBuilder() GetPhotoDetailsRequestBuilder {
 	return &GetPhotoDetailsRequestBuilderImpl{
-		pathSubstitutions:  make(map[string]string),
+		pathSubstitutions:  make(map[string]interface{}),
 		queryParams:        url.Values{},
+		encodedQueryParams: url.Values{},
 		postFormParams:     url.Values{},
 		postMultiPartParam: make(map[string][]byte),
 		headerParams:       make(map[string]string),
 	}
 }
 
+func (b *GetPhotoDetailsRequestBuilderImpl) Clone() GetPhotoDetailsRequestBuilder {
+	return b.clone()
+}
+
+func (b *GetPhotoDetailsRequestBuilderImpl) clone() *GetPhotoDetailsRequestBuilderImpl {
+	clone := &GetPhotoDetailsRequestBuilderImpl{
+		pathSubstitutions:  make(map[string]interface{}, len(b.pathSubstitutions)),
+		queryParams:        make(url.Values, len(b.queryParams)),
+		encodedQueryParams: make(url.Values, len(b.encodedQueryParams)),
+		postFormParams:     make(url.Values, len(b.postFormParams)),
+		postBody:           b.postBody,
+		postMultiPartParam: make(map[string][]byte, len(b.postMultiPartParam)),
+		headerParams:       make(map[string]string, len(b.headerParams)),
+		err:                b.err,
+		onRequest:          append(b.onRequest[:0:0], b.onRequest...),
+		onResponse:         append(b.onResponse[:0:0], b.onResponse...),
+		environment:        b.environment,
+		fallback:           b.fallback,
+	}
+	for key, value := range b.pathSubstitutions {
+		clone.pathSubstitutions[key] = value
+	}
+	for key, values := range b.queryParams {
+		clone.queryParams[key] = append([]string(nil), values...)
+	}
+	for key, values := range b.encodedQueryParams {
+		clone.encodedQueryParams[key] = append([]string(nil), values...)
+	}
+	for key, values := range b.postFormParams {
+		clone.postFormParams[key] = append([]string(nil), values...)
+	}
+	for key, value := range b.postMultiPartParam {
+		clone.postMultiPartParam[key] = append([]byte(nil), value...)
+	}
+	for key, value := range b.headerParams {
+		clone.headerParams[key] = value
+	}
+	return clone
+}
+
+// formatParam returns the string representation of the parameter named name
+// The first error is recorded and returned when the request is built
+func (b *GetPhotoDetailsRequestBuilderImpl) formatParam(name string, value interface{}) string {
+	s, err := restclient.FormatParam(value)
+	if err != nil && b.err == nil {
+		b.err = fmt.Errorf("Failed to format parameter %s: %w", name, err)
+	}
+	return s
+}
+
 func (b *GetPhotoDetailsRequestBuilderImpl) PhotoID(id string) GetPhotoDetailsRequestBuilder {
-	b.pathSubstitutions["id"] = fmt.Sprintf("%v", id)
+	b.pathSubstitutions["id"] = id
 	return b
 }
 
 func (b *GetPhotoDetailsRequestBuilderImpl) ImageSize(size int) GetPhotoDetailsRequestBuilder {
-	b.queryParams.Add("image_size", fmt.Sprintf("%v", size))
+	b.queryParams.Add("image_size", strconv.FormatInt(int64(size), 10))
 	return b
 }
 
-func (b *GetPhotoDetailsRequestBuilderImpl) applyPathSubstituions(api string) string {
-	if len(b.pathSubstitutions) == 0 {
-		return api
-	}
+// AddHeader sets a header which is not declared by the request builder
+func (b *GetPhotoDetailsRequestBuilderImpl) AddHeader(key string, value string) GetPhotoDetailsRequestBuilder {
+	b.headerParams[key] = value
+	return b
+}
 
-	for key, value := range b.pathSubstitutions {
-		api = strings.Replace(api, "{"+key+"}", value, -1)
-	}
+// AddQueryParam adds a query parameter which is not declared by the request builder
+func (b *GetPhotoDetailsRequestBuilderImpl) AddQueryParam(key string, value string) GetPhotoDetailsRequestBuilder {
+	b.queryParams.Add(key, value)
+	return b
+}
+
+// OnRequest adds a hook which is called with the request right before it is sent
+func (b *GetPhotoDetailsRequestBuilderImpl) OnRequest(hook func(*http.Request)) GetPhotoDetailsRequestBuilder {
+	b.onRequest = append(b.onRequest, hook)
+	return b
+}
+
+// OnResponse adds a hook which is called with the response as soon as it is received
+func (b *GetPhotoDetailsRequestBuilderImpl) OnResponse(hook func(*http.Response)) GetPhotoDetailsRequestBuilder {
+	b.onResponse = append(b.onResponse, hook)
+	return b
+}
+
+// WithEnvironment sends the request to the environment registered as name with
+// restclient.RegisterEnvironment, rather than with the registered client
+func (b *GetPhotoDetailsRequestBuilderImpl) WithEnvironment(name string) GetPhotoDetailsRequestBuilder {
+	b.environment = name
+	return b
+}
 
-	return api
+// WithFallback sets the response returned when the request fails, along with a
+// *restclient.FallbackError wrapping the error of the request
+func (b *GetPhotoDetailsRequestBuilderImpl) WithFallback(fallback GetPhotoDetailsResponse) GetPhotoDetailsRequestBuilder {
+	b.fallback = &fallback
+	return b
 }
 
-func (b *GetPhotoDetailsRequestBuilderImpl) build() (req *http.Request, err error) {
-	restClient := restclient.GetClient()
-	if restClient == nil {
-		return nil, fmt.Errorf("A rest client has not been registered yet. You must call client.RegisterClient first")
+// rawQuery returns the encoded query of the request
+// The values of encoded query parameters are appended as is
+func (b *GetPhotoDetailsRequestBuilderImpl) rawQuery() string {
+	query := b.queryParams.Encode()
+	if len(b.encodedQueryParams) == 0 {
+		return query
 	}
-	url := restClient.BaseURL() + b.applyPathSubstituions("/photos/{id}")
-	httpMethod := "GET"
-	switch httpMethod {
-	case "POST", "PUT":
-		if b.postBody != nil {
-			// Assume the body is to be marshalled to JSON
-			contentBody, err := json.Marshal(b.postBody)
-			if err != nil {
-				return nil, err
-			}
-			contentReader := bytes.NewReader(contentBody)
-			req, err = http.NewRequest(httpMethod, url, contentReader)
-			if err != nil {
-				return nil, err
-			}
-			req.Header.Set("Content-Type", "application/json")
-		} else if len(b.postFormParams) > 0 {
-			contentForm := b.postFormParams.Encode()
-			contentReader := strings.NewReader(contentForm)
-			if req, err = http.NewRequest(httpMethod, url, contentReader); err != nil {
-				return nil, err
-			}
-			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-		} else if len(b.postMultiPartParam) > 0 {
-			contentBody := &bytes.Buffer{}
-			writer := multipart.NewWriter(contentBody)
-			for key, value := range b.postMultiPartParam {
-				if err := writer.WriteField(key, string(value)); err != nil {
-					return nil, err
-				}
-			}
-			if err = writer.Close(); err != nil {
-				return nil, err
-			}
-			if req, err = http.NewRequest(httpMethod, url, contentBody); err != nil {
-				return nil, err
+	keys := make([]string, 0, len(b.encodedQueryParams))
+	for key := range b.encodedQueryParams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var builder strings.Builder
+	builder.WriteString(query)
+	for _, key := range keys {
+		for _, value := range b.encodedQueryParams[key] {
+			if builder.Len() > 0 {
+				builder.WriteByte('&')
 			}
-			req.Header.Set("Content-Type", "multipart/form-data")
-		}
-	case "GET", "DELETE":
-		req, err = http.NewRequest(httpMethod, url, nil)
-		if err != nil {
-			return nil, err
-		}
-		if len(b.queryParams) > 0 {
-			req.URL.RawQuery = b.queryParams.Encode()
+			builder.WriteString(url.QueryEscape(key))
+			builder.WriteByte('=')
+			builder.WriteString(value)
 		}
 	}
-	req.Header.Set("Accept", "application/json")
+	return builder.String()
+}
+
+func (b *GetPhotoDetailsRequestBuilderImpl) validate() error {
+	var missing []string
+	if _, ok := b.pathSubstitutions["id"]; !ok {
+		missing = append(missing, "path parameter id")
+	}
+	if len(missing) > 0 {
+		return &restclient.MissingParamsError{Request: "GetPhotoDetailsRequestBuilder", Missing: missing}
+	}
+	return nil
+}
+
+// build builds the request to be sent with restClient, which is resolved once by the caller so
+// that the request is sent with the client it was built for when the client is replaced meanwhile
+func (b *GetPhotoDetailsRequestBuilderImpl) build(restClient restclient.Client) (req *http.Request, err error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+	path, err := restclient.ExpandURITemplate("/photos/{id}", b.pathSubstitutions)
+	if err != nil {
+		return nil, err
+	}
+	url := restClient.BaseURL() + restclient.VersionPath(restClient, "") + path
+	httpMethod := "GET"
+	if req, err = http.NewRequest(httpMethod, url, nil); err != nil {
+		return nil, err
+	}
+	restclient.AddRawQuery(req, b.rawQuery())
+	req.Header.Set("Accept", restclient.DefaultAccept())
+	req.Header.Set("User-Agent", restclient.UserAgent(restClient))
+	restclient.SetVersionHeader(req, restClient, "")
 	for key, value := range b.headerParams {
 		req.Header.Set(key, value)
 	}
 	return req, nil
 }
 
-func (b *GetPhotoDetailsRequestBuilderImpl) Run() (GetPhotoDetailsResponse, error) {
-	request, err := b.build()
+// BuildRequest returns the request which is sent by the request builder, without sending it
+func (b *GetPhotoDetailsRequestBuilderImpl) BuildRequest() (*http.Request, error) {
+	restClient, err := restclient.EnvironmentClient(b.environment)
 	if err != nil {
 		return nil, err
 	}
-	request.URL.RawQuery = request.URL.Query().Encode()
+	return b.build(restClient)
+}
 
-	restClient := restclient.GetClient()
-	if restClient == nil {
-		return nil, fmt.Errorf("A rest client has not been registered yet. You must call client.RegisterClient first")
+// send sends the request with restClient and the headers of its context and returns the response
+func (b *GetPhotoDetailsRequestBuilderImpl) send(restClient restclient.Client, request *http.Request) (*http.Response, error) {
+	restclient.ApplyContextHeaders(request)
+	for _, hook := range b.onRequest {
+		hook(request)
 	}
-
 	if restClient.Debug() {
 		restclient.DebugRequest(request)
 	}
+	request, traced := restclient.TraceTimings("GetPhotoDetailsRequestBuilder", request)
 
 	response, err := restClient.HttpClient().Do(request)
+	traced(response, err)
 	if err != nil {
 		return nil, err
 	}
+	for _, hook := range b.onResponse {
+		hook(response)
+	}
 
-	defer response.Body.Close()
 	if restClient.Debug() {
 		restclient.DebugResponse(response)
 	}
+	return response, nil
+}
+
+func (b *GetPhotoDetailsRequestBuilderImpl) Run() (GetPhotoDetailsResponse, error) {
+	return b.run(context.Background())
+}
+
+// RunRequest sends the request with the context ctx and returns the response.
+// It implements restclient.Runner, which lets restclient.Batch run the request builder.
+func (b *GetPhotoDetailsRequestBuilderImpl) RunRequest(ctx context.Context) (interface{}, error) {
+	return b.run(ctx)
+}
+
+func (b *GetPhotoDetailsRequestBuilderImpl) run(ctx context.Context) (result GetPhotoDetailsResponse, err error) {
+	defer restclient.ProfileAllocations("GetPhotoDetailsRequestBuilder")()
+	defer func() {
+		if err != nil && b.fallback != nil {
+			result, err = *b.fallback, &restclient.FallbackError{Err: err}
+		}
+	}()
+
+	restClient, err := restclient.EnvironmentClient(b.environment)
+	if err != nil {
+		return result, err
+	}
+	request, err := b.build(restClient)
+	if err != nil {
+		return result, err
+	}
+
+	response, err := b.send(restClient, request.WithContext(ctx))
+	if err != nil {
+		return result, err
+	}
+	defer response.Body.Close()
+	if err := restclient.CheckStatus(response); err != nil {
+		return result, err
+	}
+	if err := restclient.LimitBody(response, restclient.MaxBodySize(restClient)); err != nil {
+		return result, err
+	}
+	if !restclient.HasContent(response) {
+		return result, nil
+	}
 
-	return NewGetPhotoDetailsResponse(response.Body)
+	return restclient.Decode(NewGetPhotoDetailsResponse, response.Body)
 }
 
 func (b *GetPhotoDetailsRequestBuilderImpl) RunAsync(callback GetPhotoDetailsCallback) {
@@ -187,8 +332,10 @@ func (b *GetPhotoDetailsRequestBuilderImpl) RunAsync(callback GetPhotoDetailsCal
 		callback.OnStart()
 	}
 
-	go func(b *GetPhotoDetailsRequestBuilderImpl) {
-		response, err := b.Run()
+	// The request may wait for the executor of asynchronous requests, so it is sent with a copy
+	b = b.clone()
+	err := restclient.RunAsync(func() {
+		response, err := b.run(context.Background())
 
 		if callback != nil {
 			if err != nil {
@@ -197,7 +344,11 @@ func (b *GetPhotoDetailsRequestBuilderImpl) RunAsync(callback GetPhotoDetailsCal
 				callback.OnSuccess(response)
 			}
 		}
-	}(b)
+	})
+	if err != nil && callback != nil {
+		// The request was rejected by the executor of asynchronous requests
+		callback.OnError(err.Error())
+	}
 }
 `
 	fset := token.NewFileSet()
@@ -283,6 +434,20 @@ func TestParamType(t *testing.T) {
 			`,
 			"*some.Pointer",
 		},
+		{
+			`package main
+			func four(b Page[Photo]) {
+			}
+			`,
+			"Page[Photo]",
+		},
+		{
+			`package main
+			func five(b *models.Result[models.Photo, error]) {
+			}
+			`,
+			"*models.Result[models.Photo, error]",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -294,3 +459,1258 @@ func TestParamType(t *testing.T) {
 		assert.Equal(t, tc.output, paramType)
 	}
 }
+
+func TestGenerateFiles(t *testing.T) {
+	src := `package test
+		// @GET("/photos/{id}")
+		type GetPhotoDetailsRequestBuilder interface {
+			// @PATH("id")
+			PhotoID(id string) GetPhotoDetailsRequestBuilder
+
+			// @SYNC("GetPhotoDetailsResponse")
+			Run() (GetPhotoDetailsResponse, error)
+
+			// @ASYNC("GetPhotoDetailsCallback")
+			RunAsync(callback GetPhotoDetailsCallback)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	result := parse.NewParser(f, "test").Parse()
+
+	files, err := GenerateFiles(result, Options{Layout: LayoutSingle})
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	single, err := Generate(result)
+	assert.NoError(t, err)
+	assert.Equal(t, "", files[0].Name)
+	assert.Equal(t, string(single), string(files[0].Source))
+
+	files, err = GenerateFiles(result, Options{Layout: LayoutPerEndpoint})
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	shared := string(files[0].Source)
+	assert.Equal(t, "", files[0].Name)
+	assert.Contains(t, shared, "type GetPhotoDetailsCallback interface")
+	assert.NotContains(t, shared, "GetPhotoDetailsRequestBuilderImpl")
+	assert.NotContains(t, shared, "import")
+
+	builder := string(files[1].Source)
+	assert.Equal(t, "get_photo_details_request_builder_gorest.go", files[1].Name)
+	assert.Contains(t, builder, "type GetPhotoDetailsRequestBuilderImpl struct")
+	assert.NotContains(t, builder, "type GetPhotoDetailsCallback interface")
+
+	_, err = GenerateFiles(result, Options{Layout: Layout("invalid")})
+	assert.Error(t, err)
+}
+
+func TestGetFileNames(t *testing.T) {
+	var testCases = []struct {
+		input    string
+		builder  string
+		resource string
+	}{
+		{"GetPhotosRequestBuilder", "get_photos_request_builder_gorest.go", "photos_get_gorest.go"},
+		{"UploadPhotoRequestBuilder", "upload_photo_request_builder_gorest.go", "photo_upload_gorest.go"},
+		{"GetHTTPStatus", "get_http_status_gorest.go", "http_status_get_gorest.go"},
+		{"SearchRequestBuilder", "search_request_builder_gorest.go", "search_gorest.go"},
+	}
+
+	for _, tc := range testCases {
+		results := []*parse.ParseResult{{RequestType: tc.input}}
+		names, err := getFileNames(results, Options{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{tc.builder}, names)
+		names, err = getFileNames(results, Options{FileNaming: FileNamingResource})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{tc.resource}, names)
+	}
+
+	names, err := getFileNames([]*parse.ParseResult{{RequestType: "GetPhotosRequestBuilder"}}, Options{FileNaming: FileNamingResource, FileSuffix: ".gen.go"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"photos_get.gen.go"}, names)
+
+	_, err = getFileNames([]*parse.ParseResult{{RequestType: "GetPhotosRequestBuilder"}, {RequestType: "GetPhotos"}}, Options{FileNaming: FileNamingResource})
+	assert.EqualError(t, err, "Request builders GetPhotosRequestBuilder and GetPhotos are both generated in to file photos_get_gorest.go")
+	_, err = getFileNames([]*parse.ParseResult{{RequestType: "GetPhotosRequestBuilder"}}, Options{FileSuffix: "_test.go"})
+	assert.Error(t, err)
+	_, err = getFileNames([]*parse.ParseResult{{RequestType: "GetPhotosRequestBuilder"}}, Options{FileNaming: FileNaming("invalid")})
+	assert.Error(t, err)
+}
+
+func TestGenerateEmbeddedInterface(t *testing.T) {
+	src := `package test
+		type Paginated interface {
+			// @QUERY("page")
+			Page(page int) Paginated
+		}
+
+		// @GET("/photos")
+		type GetPhotosRequestBuilder interface {
+			Paginated
+
+			// @QUERY("feature")
+			Feature(feature string) GetPhotosRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "func (b *GetPhotosRequestBuilderImpl) Page(page int) Paginated {")
+	assert.Contains(t, string(data), "func (b *GetPhotosRequestBuilderImpl) Feature(feature string) GetPhotosRequestBuilder {")
+}
+
+func TestGeneratePagination(t *testing.T) {
+	src := `package test
+		// @GET("/photos")
+		type GetPhotosRequestBuilder interface {
+			// @SYNC("GetPhotosResponse")
+			Run() (GetPhotosResponse, error)
+
+			// @PAGINATED(cursor="next_cursor", param="cursor")
+			Iterate(ctx context.Context, fn func(page GetPhotosResponse) bool) error
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "func (b *GetPhotosRequestBuilderImpl) Iterate(ctx context.Context, fn func(page GetPhotosResponse) bool) error {")
+	assert.Contains(t, string(data), `cursor, err := restclient.PageValue(data, "next_cursor")`)
+	assert.Contains(t, string(data), `b.queryParams.Set("cursor", cursor)`)
+	assert.Contains(t, string(data), "\t\"context\"\n")
+}
+
+func TestGenerateExamples(t *testing.T) {
+	src := `package test
+		// @GET("/photos/{id}")
+		// @EXAMPLE(photoID="123", imageSize=3, type="thumb")
+		type GetPhotoDetailsRequestBuilder interface {
+			// @PATH("id")
+			PhotoID(id string) GetPhotoDetailsRequestBuilder
+
+			// @QUERY("image_size")
+			ImageSize(size int) GetPhotoDetailsRequestBuilder
+
+			// @HEADER("x-type")
+			Type(t string) GetPhotoDetailsRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	result := parse.NewParser(f, "test").Parse()
+	assert.Equal(t, []example{
+		{
+			Calls:   []string{`ImageSize(3)`, `PhotoID("123")`, `Type("thumb")`},
+			Method:  "GET",
+			Path:    `"/photos/123"`,
+			Query:   `"image_size=3"`,
+			Headers: map[string]string{"x-type": `"thumb"`},
+		},
+	}, getExamples(result))
+
+	files, err := GenerateFiles(result, Options{Layout: LayoutPerEndpoint})
+	assert.NoError(t, err)
+	if assert.Len(t, files, 4) {
+		typeCheck(t, map[string]string{"input.go": src, "gorest.go": string(files[1].Source), "gorest_test.go": string(files[2].Source)})
+	}
+	assert.Equal(t, "_test.go", files[2].Suffix)
+	assert.Equal(t, files[1].Name, files[2].Name)
+	assert.Contains(t, string(files[2].Source), "func TestGetPhotoDetailsRequestBuilderExamples(t *testing.T) {")
+	assert.Equal(t, "_conformance_test.go", files[3].Suffix)
+	assert.Equal(t, files[1].Name, files[3].Name)
+	assert.True(t, strings.HasPrefix(string(files[3].Source), "//go:build conformance\n"))
+	assert.Contains(t, string(files[3].Source), "func TestConformanceGetPhotoDetailsRequestBuilder(t *testing.T) {")
+	assert.Contains(t, string(files[3].Source), "response, err := b.send(restClient, request)")
+}
+
+func TestGenerateExampleTypes(t *testing.T) {
+	src := `package test
+
+		import "time"
+
+		type PhotoID string
+
+		type Size int
+
+		// @GET("/photos/{id}")
+		// @EXAMPLE(id="abc", size=3, since="1700000000", taken="2024-01-01", timeout="1m30s", raw=true, ratio=1.5)
+		type GetPhotoRequestBuilder interface {
+			// @PATH("id")
+			ID(id PhotoID) GetPhotoRequestBuilder
+
+			// @QUERY("size")
+			Size(size Size) GetPhotoRequestBuilder
+
+			// @QUERY("since") @FORMAT("unix")
+			Since(since time.Time) GetPhotoRequestBuilder
+
+			// @QUERY("taken") @FORMAT("2006-01-02")
+			Taken(taken time.Time) GetPhotoRequestBuilder
+
+			// @HEADER("x-timeout")
+			Timeout(timeout time.Duration) GetPhotoRequestBuilder
+
+			// @QUERY("raw")
+			Raw(raw bool) GetPhotoRequestBuilder
+
+			// @QUERY("ratio") @FORMAT("%.2f")
+			Ratio(ratio float64) GetPhotoRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	p := parse.NewParser(f, "test")
+	result := p.Parse()
+	assert.NoError(t, p.Err())
+	assert.Equal(t, []example{
+		{
+			Calls: []string{
+				`ID(PhotoID("abc"))`,
+				`Ratio(1.5)`,
+				`Raw(true)`,
+				`Since(time.Unix(1700000000, 0))`,
+				`Size(Size(3))`,
+				`Taken(restclient.ParseExampleTime("2006-01-02", "2024-01-01"))`,
+				`Timeout(90 * time.Second)`,
+			},
+			Method: "GET",
+			Path:   `"/photos/" + restclient.FormatExample(PhotoID("abc"))`,
+			Query: `restclient.EncodeExampleQuery(url.Values{` +
+				`"ratio": {fmt.Sprintf("%.2f", 1.5)}, ` +
+				`"raw": {"true"}, ` +
+				`"since": {strconv.FormatInt(time.Unix(1700000000, 0).Unix(), 10)}, ` +
+				`"size": {restclient.FormatExample(Size(3))}, ` +
+				`"taken": {restclient.ParseExampleTime("2006-01-02", "2024-01-01").Format("2006-01-02")}}, url.Values{})`,
+			Headers: map[string]string{"x-timeout": `restclient.FormatExample(90 * time.Second)`},
+		},
+	}, getExamples(result))
+
+	files, err := GenerateFiles(result, Options{Layout: LayoutSingle})
+	assert.NoError(t, err)
+	if assert.Len(t, files, 3) {
+		typeCheck(t, map[string]string{"input.go": src, "gorest.go": string(files[0].Source), "gorest_test.go": string(files[1].Source)})
+	}
+}
+
+func TestGenerateRequired(t *testing.T) {
+	src := `package test
+		// @GET("/photos/{id}")
+		type GetPhotoDetailsRequestBuilder interface {
+			// @PATH("id")
+			PhotoID(id string) GetPhotoDetailsRequestBuilder
+
+			// @QUERY("q", required)
+			Query(q string) GetPhotoDetailsRequestBuilder
+
+			// @QUERY("image_size")
+			ImageSize(size int) GetPhotoDetailsRequestBuilder
+
+			// @HEADER("x-token", required)
+			Token(token string) GetPhotoDetailsRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `missing = append(missing, "path parameter id")`)
+	assert.Contains(t, string(data), `missing = append(missing, "query parameter q")`)
+	assert.Contains(t, string(data), `missing = append(missing, "header x-token")`)
+	assert.NotContains(t, string(data), `missing = append(missing, "query parameter image_size")`)
+}
+
+func TestGenerateDictionary(t *testing.T) {
+	src := `package test
+		// @POST("/photos")
+		// @DICTIONARY("photos")
+		type CreatePhotoRequestBuilder interface {
+			// @FIELD("title")
+			Title(title string) CreatePhotoRequestBuilder
+
+			// @SYNC("CreatePhotoResponse")
+			Run() (CreatePhotoResponse, error)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	result := parse.NewParser(f, "test").Parse()
+	assert.Equal(t, "photos", result.Dictionary)
+
+	data, err := Generate(result)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `if err := restclient.CompressRequest(req, "photos"); err != nil {`)
+	assert.Contains(t, string(data), `if err := restclient.DecompressResponse(response); err != nil {`)
+}
+
+func TestGenerateImmutable(t *testing.T) {
+	src := `package test
+		// @GET("/photos/{id}")
+		// @EXAMPLE(photoID="123")
+		type GetPhotoDetailsRequestBuilder interface {
+			// @PATH("id")
+			PhotoID(id string) GetPhotoDetailsRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	result := parse.NewParser(f, "test").Parse()
+
+	files, err := GenerateFiles(result, Options{Layout: LayoutSingle})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(files[0].Source), "b = b.clone()")
+	assert.Contains(t, string(files[1].Source), `builder.PhotoID("123")`)
+
+	files, err = GenerateFiles(result, Options{Layout: LayoutSingle, Immutable: true})
+	assert.NoError(t, err)
+	assert.Contains(t, string(files[0].Source), `func (b *GetPhotoDetailsRequestBuilderImpl) PhotoID(id string) GetPhotoDetailsRequestBuilder {
+	b = b.clone()
+	b.pathSubstitutions["id"] = id
+	return b
+}`)
+	assert.Contains(t, string(files[1].Source), `builder = builder.PhotoID("123").(GetPhotoDetailsRequestBuilder)`)
+}
+
+func TestGetParamString(t *testing.T) {
+	var testCases = []struct {
+		annotations string
+		output      string
+	}{
+		{`// @QUERY("t")`, `b.formatParam("t", t)`},
+		{`// @QUERY("t") @FORMAT("unix")`, `strconv.FormatInt(t.Unix(), 10)`},
+		{`// @FORMAT("unixmilli") @QUERY("t")`, `strconv.FormatInt(t.UnixMilli(), 10)`},
+		{`// @QUERY("t") @FORMAT("unixnano")`, `strconv.FormatInt(t.UnixNano(), 10)`},
+		{`// @QUERY("t") @FORMAT("%.2f")`, `fmt.Sprintf("%.2f", t)`},
+		{`// @QUERY("t") @FORMAT("2006-01-02")`, `t.Format("2006-01-02")`},
+	}
+
+	for _, tc := range testCases {
+		src := `package test
+			type GetPhotosRequestBuilder interface {
+				` + tc.annotations + `
+				Since(t time.Time) GetPhotosRequestBuilder
+			}
+			`
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+		assert.NoError(t, err)
+
+		ifc := f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.InterfaceType)
+		assert.Equal(t, tc.output, getParamString(ifc.Methods.List[0]))
+	}
+}
+
+func TestGetBasicParamString(t *testing.T) {
+	var testCases = []struct {
+		paramType string
+		output    string
+	}{
+		{"string", `v`},
+		{"bool", `strconv.FormatBool(v)`},
+		{"int", `strconv.FormatInt(int64(v), 10)`},
+		{"int64", `strconv.FormatInt(v, 10)`},
+		{"uint8", `strconv.FormatUint(uint64(v), 10)`},
+		{"uint64", `strconv.FormatUint(v, 10)`},
+		{"float32", `strconv.FormatFloat(float64(v), 'g', -1, 32)`},
+		{"float64", `strconv.FormatFloat(v, 'g', -1, 64)`},
+		{"Sort", `b.formatParam("v", v)`},
+		{"*int", `b.formatParam("v", v)`},
+	}
+
+	for _, tc := range testCases {
+		src := `package test
+			type GetPhotosRequestBuilder interface {
+				// @QUERY("v")
+				Value(v ` + tc.paramType + `) GetPhotosRequestBuilder
+			}
+			`
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+		assert.NoError(t, err)
+
+		ifc := f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.InterfaceType)
+		assert.Equal(t, tc.output, getParamString(ifc.Methods.List[0]), tc.paramType)
+	}
+}
+
+func TestGenerateMultipart(t *testing.T) {
+	src := `package test
+
+		import "io"
+
+		type Photo struct{}
+
+		func NewPhoto(r io.Reader) (*Photo, error) {
+			return &Photo{}, nil
+		}
+
+		// @POST("/photos")
+		type UploadPhotoRequestBuilder interface {
+			// @PART("id")
+			ID(id int) UploadPhotoRequestBuilder
+
+			// @PART("thumbnail")
+			Thumbnail(thumbnail []byte) UploadPhotoRequestBuilder
+
+			// @PART("file")
+			File(file io.Reader) UploadPhotoRequestBuilder
+
+			// @SYNC("*Photo")
+			Run() (*Photo, error)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	// The bytes of the part are sent as is rather than formatted
+	assert.Contains(t, string(data), `b.postMultiPartParam["thumbnail"] = thumbnail`)
+	assert.Contains(t, string(data), `b.postMultiPartParam["file"] = b.readPart("file", file)`)
+	assert.Contains(t, string(data), `b.postMultiPartParam["id"] = []byte(strconv.FormatInt(int64(id), 10))`)
+	typeCheck(t, map[string]string{"input.go": src, "generated.go": string(data)})
+}
+
+func TestGenerateQueryStruct(t *testing.T) {
+	src := `package test
+		// @GET("/photos")
+		type GetPhotosRequestBuilder interface {
+			// @QUERYSTRUCT()
+			Filter(filter *PhotoFilter) GetPhotosRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `func (b *GetPhotosRequestBuilderImpl) Filter(filter *PhotoFilter) GetPhotosRequestBuilder {
+	values, err := restclient.QueryValues(filter)
+	if err != nil && b.err == nil {
+		b.err = fmt.Errorf("Failed to format parameter filter: %w", err)
+	}
+	for key, value := range values {
+		b.queryParams[key] = append(b.queryParams[key], value...)
+	}
+	return b
+}`)
+}
+
+func TestGenerateEncodedPath(t *testing.T) {
+	src := `package test
+		// @GET("/users/{user}/files/{path}")
+		type GetFileRequestBuilder interface {
+			// @PATH("user")
+			User(user string) GetFileRequestBuilder
+
+			// @PATH("path", encoded=true)
+			Path(path string) GetFileRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `b.pathSubstitutions["user"] = user`)
+	assert.Contains(t, string(data), `b.pathSubstitutions["path"] = restclient.Encoded(path)`)
+}
+
+func TestGenerateURITemplate(t *testing.T) {
+	src := `package test
+		// @GET("/repos/{owner}/contents{/path*}{?ref}")
+		type GetContentsRequestBuilder interface {
+			// @PATH("owner")
+			Owner(owner string) GetContentsRequestBuilder
+
+			// @PATH("path")
+			Path(path ...string) GetContentsRequestBuilder
+
+			// @PATH("ref")
+			Ref(ref string) GetContentsRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `b.pathSubstitutions["path"] = path`)
+	assert.Contains(t, string(data), `b.pathSubstitutions["ref"] = ref`)
+	assert.Contains(t, string(data), `path, err := restclient.ExpandURITemplate("/repos/{owner}/contents{/path*}{?ref}", b.pathSubstitutions)`)
+	// The variables of path segment and query expressions are optional
+	assert.Contains(t, string(data), `missing = append(missing, "path parameter owner")`)
+	assert.NotContains(t, string(data), `missing = append(missing, "path parameter path")`)
+	assert.NotContains(t, string(data), `missing = append(missing, "path parameter ref")`)
+}
+
+func TestGenerateEncodedQuery(t *testing.T) {
+	src := `package test
+		// @GET("/photos")
+		// @EXAMPLE(since="2024-01-01T00:00:00+01:00", feature="a b")
+		type GetPhotosRequestBuilder interface {
+			// @QUERY("since", encoded=true)
+			Since(since string) GetPhotosRequestBuilder
+
+			// @QUERY("feature")
+			Feature(feature string) GetPhotosRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	files, err := GenerateFiles(parse.NewParser(f, "test").Parse(), Options{Layout: LayoutSingle})
+	assert.NoError(t, err)
+	assert.Contains(t, string(files[0].Source), `b.encodedQueryParams.Add("since", since)`)
+	assert.Contains(t, string(files[0].Source), `b.queryParams.Add("feature", feature)`)
+	assert.NotContains(t, string(files[0].Source), `request.URL.RawQuery = request.URL.Query().Encode()`)
+	assert.Contains(t, string(files[1].Source), `"feature=a+b&since=2024-01-01T00:00:00+01:00"`)
+}
+
+func TestGenerateAll(t *testing.T) {
+	src := `package test
+		import "time"
+
+		// @GET("/photos")
+		// @EXAMPLE(since="2024-01-01")
+		type GetPhotosRequestBuilder interface {
+			// @QUERY("since") @FORMAT("2006-01-02")
+			Since(t time.Time) GetPhotosRequestBuilder
+		}
+
+		// @GET("/albums")
+		type GetAlbumsRequestBuilder interface {
+			// @QUERY("since") @FORMAT("2006-01-02")
+			Since(t time.Time) GetAlbumsRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	results := parse.NewParser(f, "test").ParseAll()
+	assert.Len(t, results, 2)
+
+	files, err := GenerateAll(results, Options{Layout: LayoutSingle})
+	assert.NoError(t, err)
+	if assert.Len(t, files, 3) {
+		typeCheck(t, map[string]string{"input.go": src, "gorest.go": string(files[0].Source), "gorest_test.go": string(files[1].Source)})
+		assert.Contains(t, string(files[1].Source), `builder.Since(restclient.ParseExampleTime("2006-01-02", "2024-01-01"))`)
+		assert.Contains(t, string(files[1].Source), `restclient.EncodeExampleQuery(url.Values{"since": {restclient.ParseExampleTime("2006-01-02", "2024-01-01").Format("2006-01-02")}}, url.Values{})`)
+		src := string(files[0].Source)
+		assert.Equal(t, 1, strings.Count(src, `"time"`))
+		assert.Contains(t, src, "type GetPhotosRequestBuilderImpl struct")
+		assert.Contains(t, src, "type GetAlbumsRequestBuilderImpl struct")
+		assert.Contains(t, string(files[1].Source), "func TestGetPhotosRequestBuilderExamples(t *testing.T)")
+		assert.NotContains(t, string(files[1].Source), "TestGetAlbumsRequestBuilderExamples")
+		assert.Equal(t, "_test.go", files[1].Suffix)
+	}
+
+	files, err = GenerateAll(results, Options{Layout: LayoutPerEndpoint})
+	assert.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name+f.Suffix)
+	}
+	assert.Equal(t, []string{
+		"",
+		"get_photos_request_builder_gorest.go",
+		"get_photos_request_builder_gorest.go_test.go",
+		"get_photos_request_builder_gorest.go_conformance_test.go",
+		"get_albums_request_builder_gorest.go",
+	}, names)
+}
+
+func TestGenerateDocComments(t *testing.T) {
+	src := `package test
+		// GetPhotosRequestBuilder lists the photos of a user.
+		//
+		// @GET("/users/{id}/photos")
+		type GetPhotosRequestBuilder interface {
+			// UserID selects the user by ID.
+			// @PATH("id")
+			UserID(id string) GetPhotosRequestBuilder
+
+			// @QUERY("page")
+			Page(page int) GetPhotosRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `// GetPhotosRequestBuilder lists the photos of a user.
+func NewGetPhotosRequestBuilder() GetPhotosRequestBuilder {`)
+	assert.Contains(t, string(data), `// UserID selects the user by ID.
+func (b *GetPhotosRequestBuilderImpl) UserID(id string) GetPhotosRequestBuilder {`)
+	assert.Contains(t, string(data), `}
+
+func (b *GetPhotosRequestBuilderImpl) Page(page int) GetPhotosRequestBuilder {`)
+}
+
+func TestGenerateDeprecated(t *testing.T) {
+	src := `package test
+		// GetPhotosRequestBuilder lists photos.
+		// @GET("/photos")
+		// @DEPRECATED("use ListPhotosV2")
+		type GetPhotosRequestBuilder interface {
+			// @QUERY("page")
+			// @DEPRECATED()
+			Page(page int) GetPhotosRequestBuilder
+
+			// @QUERY("feature")
+			Feature(feature string) GetPhotosRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `// GetPhotosRequestBuilder lists photos.
+//
+// Deprecated: use ListPhotosV2
+func NewGetPhotosRequestBuilder() GetPhotosRequestBuilder {`)
+	assert.Contains(t, string(data), `// Deprecated: this should no longer be used.
+func (b *GetPhotosRequestBuilderImpl) Page(page int) GetPhotosRequestBuilder {
+	restclient.WarnDeprecated("GetPhotosRequestBuilder.Page", "")
+	b.queryParams.Add(`)
+	assert.Contains(t, string(data), `func (b *GetPhotosRequestBuilderImpl) build(restClient restclient.Client) (req *http.Request, err error) {
+	restclient.WarnDeprecated("GetPhotosRequestBuilder", "use ListPhotosV2")
+	if b.err != nil {`)
+	assert.Contains(t, string(data), `func (b *GetPhotosRequestBuilderImpl) Feature(feature string) GetPhotosRequestBuilder {
+	b.queryParams.Add(`)
+}
+
+func TestGetConstructor(t *testing.T) {
+	var testCases = []struct {
+		input  string
+		output string
+	}{
+		{"PhotoResponse", "NewPhotoResponse"},
+		{"*PhotoResponse", "NewPhotoResponse"},
+		{"models.Photo", "models.NewPhoto"},
+		{"Page[Photo]", "NewPage[Photo]"},
+		{"*models.Page[models.Photo]", "models.NewPage[models.Photo]"},
+		{"Result[Photo, models.Error]", "NewResult[Photo, models.Error]"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.output, getConstructor(tc.input))
+	}
+	assert.True(t, isLocalType("Page[models.Photo]"))
+	assert.False(t, isLocalType("models.Page[Photo]"))
+}
+
+func TestGenerateProgress(t *testing.T) {
+	src := `package test
+		// @POST("/photos")
+		type CreatePhotoRequestBuilder interface {
+			// @FIELD("title")
+			Title(title string) CreatePhotoRequestBuilder
+
+			// @PROGRESS()
+			OnProgress(fn func(sent, total int64)) CreatePhotoRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `func (b *CreatePhotoRequestBuilderImpl) OnProgress(fn func(sent, total int64)) CreatePhotoRequestBuilder {
+	b.progress = fn
+	return b
+}`)
+	assert.Contains(t, string(data), `	restclient.TrackUploadProgress(req, b.progress)
+	return req, nil
+}`)
+}
+
+func TestGenerateDownload(t *testing.T) {
+	src := `package test
+		// @GET("/photos/{id}/original")
+		type DownloadPhotoRequestBuilder interface {
+			// @PATH("id")
+			ID(id string) DownloadPhotoRequestBuilder
+
+			// @DOWNLOAD()
+			RunTo(w io.Writer, progress func(received, total int64)) (int64, error)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `func (b *DownloadPhotoRequestBuilderImpl) RunTo(w io.Writer, progress func(received, total int64)) (int64, error) {`)
+	assert.Contains(t, string(data), `	response, err := b.send(restClient, request)`)
+	assert.Contains(t, string(data), `	return restclient.DownloadResponse(response, w, progress)
+}`)
+}
+
+func TestGenerateBodyStream(t *testing.T) {
+	src := `package test
+		// @PUT("/files/{name}")
+		type UploadFileRequestBuilder interface {
+			// @PATH("name")
+			Name(name string) UploadFileRequestBuilder
+
+			// @BODY_STREAM("application/zip")
+			Content(r io.Reader, size int64) UploadFileRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `func (b *UploadFileRequestBuilderImpl) Content(r io.Reader, size int64) UploadFileRequestBuilder {
+	b.bodyStream = r
+	b.bodyStreamLength = size
+	return b
+}`)
+	assert.Contains(t, string(data), `	if b.bodyStream != nil {
+		// The reader is sent as is, without buffering the body in memory
+		return b.bodyStream, "application/zip", nil
+	}
+	if b.postBody != nil {`)
+	assert.Contains(t, string(data), `	if b.bodyStream != nil && b.bodyStreamLength > 0 {
+		req.ContentLength = b.bodyStreamLength
+	}`)
+}
+
+func TestGenerateQueryOnPost(t *testing.T) {
+	src := `package test
+		// @POST("/photos")
+		type CreatePhotoRequestBuilder interface {
+			// @QUERY("notify")
+			Notify(notify bool) CreatePhotoRequestBuilder
+
+			// @FIELD("title")
+			Title(title string) CreatePhotoRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	// The query is encoded for every method, independently of the body
+	assert.Contains(t, string(data), `	body, contentType, err := b.body()
+	if err != nil {
+		return nil, err
+	}
+	if req, err = http.NewRequest(httpMethod, url, body); err != nil {
+		return nil, err
+	}
+	restclient.AddRawQuery(req, b.rawQuery())
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}`)
+}
+
+func TestGenerateNoContent(t *testing.T) {
+	src := `package test
+		// @HEAD("/photos/{id}")
+		type PhotoExistsRequestBuilder interface {
+			// @PATH("id")
+			ID(id string) PhotoExistsRequestBuilder
+
+			// @SYNC()
+			Run(ctx context.Context) error
+		}
+
+		// @HEAD("/photos/{id}")
+		type PhotoHeadersRequestBuilder interface {
+			// @PATH("id")
+			ID(id string) PhotoHeadersRequestBuilder
+
+			// @SYNC("http.Header")
+			Run() (http.Header, error)
+		}
+
+		// @GET("/photos/{id}")
+		type GetPhotoRequestBuilder interface {
+			// @PATH("id")
+			ID(id string) GetPhotoRequestBuilder
+
+			// @SYNC("GetPhotoResponse")
+			Run() (GetPhotoResponse, error)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	parser := parse.NewParser(f, "test")
+	results := parser.ParseAll()
+	assert.NoError(t, parser.Err())
+	files, err := GenerateAll(results, Options{Layout: LayoutSingle})
+	if !assert.NoError(t, err) {
+		return
+	}
+	src = string(files[0].Source)
+	assert.Contains(t, src, `func (b *PhotoExistsRequestBuilderImpl) Run(ctx context.Context) error {
+	_, err := b.run(ctx)
+	return err
+}`)
+	assert.Contains(t, src, `func (b *PhotoExistsRequestBuilderImpl) run(ctx context.Context) (result struct{}, err error) {`)
+	assert.Contains(t, src, `	// The response has no content, its body is drained so the connection can be reused
+	_, err = io.Copy(io.Discard, response.Body)
+	return result, err
+}`)
+	assert.Contains(t, src, `func (b *PhotoHeadersRequestBuilderImpl) Run() (http.Header, error) {`)
+	assert.Contains(t, src, `	return response.Header, nil
+}`)
+	assert.Contains(t, src, `	if !restclient.HasContent(response) {
+		return result, nil
+	}
+
+	return restclient.Decode(NewGetPhotoResponse, response.Body)`)
+}
+
+func TestGenerateAllowBody(t *testing.T) {
+	src := `package test
+		// @DELETE("/{index}/_query")
+		// @ALLOW_BODY()
+		type DeleteByQueryRequestBuilder interface {
+			// @PATH("index")
+			Index(index string) DeleteByQueryRequestBuilder
+
+			// @BODY("query")
+			Query(query map[string]interface{}) DeleteByQueryRequestBuilder
+		}
+
+		// @DELETE("/photos/{id}")
+		type DeletePhotoRequestBuilder interface {
+			// @PATH("id")
+			ID(id string) DeletePhotoRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	parser := parse.NewParser(f, "test")
+	results := parser.ParseAll()
+	assert.NoError(t, parser.Err())
+	files, err := GenerateAll(results, Options{Layout: LayoutSingle})
+	if !assert.NoError(t, err) {
+		return
+	}
+	src = string(files[0].Source)
+	assert.Contains(t, src, `func (b *DeleteByQueryRequestBuilderImpl) Query(query map[string]interface{}) DeleteByQueryRequestBuilder {
+	b.postBody = b.encodeBody(query)
+	return b
+}`)
+	// The body is encoded once when it is set rather than by every copy of the request builder
+	assert.NotContains(t, src, `clone.postBody`)
+	assert.Contains(t, src, `		return bytes.NewReader(b.postBody), "application/json", nil`)
+	assert.Contains(t, src, `func (b *DeleteByQueryRequestBuilderImpl) body() (io.Reader, string, error) {`)
+	assert.Contains(t, src, `	httpMethod := "DELETE"
+	body, contentType, err := b.body()`)
+	assert.NotContains(t, src, `func (b *DeletePhotoRequestBuilderImpl) body()`)
+	assert.Contains(t, src, `	httpMethod := "DELETE"
+	if req, err = http.NewRequest(httpMethod, url, nil); err != nil {
+		return nil, err
+	}
+	restclient.AddRawQuery(req, b.rawQuery())`)
+}
+
+func TestGenerateIdempotent(t *testing.T) {
+	var testCases = []struct {
+		annotation string
+		header     string
+	}{
+		{`@IDEMPOTENT()`, "Idempotency-Key"},
+		{`@IDEMPOTENT("X-Request-Id")`, "X-Request-Id"},
+	}
+
+	for _, tc := range testCases {
+		src := `package test
+		// @POST("/charges")
+		// ` + tc.annotation + `
+		type CreateChargeRequestBuilder interface {
+			// @FIELD("amount")
+			Amount(amount int) CreateChargeRequestBuilder
+		}
+		`
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+		assert.NoError(t, err)
+
+		data, err := Generate(parse.NewParser(f, "test").Parse())
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `	if req.Header.Get("`+tc.header+`") == "" {
+		req.Header.Set("`+tc.header+`", b.getIdempotencyKey())
+	}`)
+		assert.Contains(t, string(data), `	if b.idempotencyKey == "" {
+		b.idempotencyKey = restclient.NewIdempotencyKey()
+	}`)
+	}
+}
+
+func TestGenerateSyncContext(t *testing.T) {
+	src := `package test
+		// @GET("/photos")
+		type GetPhotosRequestBuilder interface {
+			// @SYNC("GetPhotosResponse")
+			Run(ctx context.Context) (GetPhotosResponse, error)
+
+			// @ASYNC("GetPhotosCallback")
+			RunAsync(callback GetPhotosCallback)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `func (b *GetPhotosRequestBuilderImpl) Run(ctx context.Context) (GetPhotosResponse, error) {
+	return b.run(ctx)
+}`)
+	assert.Contains(t, string(data), `response, err := b.send(restClient, request.WithContext(ctx))`)
+	assert.Contains(t, string(data), `	restclient.ApplyContextHeaders(request)
+`)
+	assert.Contains(t, string(data), `response, err := b.run(context.Background())`)
+}
+
+func TestGenerateAsyncContext(t *testing.T) {
+	src := `package test
+		// @GET("/photos")
+		type GetPhotosRequestBuilder interface {
+			// @ASYNC("GetPhotosCallback")
+			RunAsync(ctx context.Context, callback GetPhotosCallback)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	p := parse.NewParser(f, "test")
+	result := p.Parse()
+	assert.NoError(t, p.Err())
+	data, err := Generate(result)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `func (b *GetPhotosRequestBuilderImpl) RunAsync(ctx context.Context, callback GetPhotosCallback) {
+	if callback != nil {
+		callback.OnStart()
+	}`)
+	assert.Contains(t, string(data), `		response, err := b.run(ctx)
+		if ctxErr := ctx.Err(); err != nil && ctxErr != nil {`)
+}
+
+func TestGenerateAccept(t *testing.T) {
+	src := `package test
+		// @GET("/repos/{owner}")
+		// @ACCEPT("application/vnd.github.v3+json")
+		type GetRepoRequestBuilder interface {
+			// @PATH("owner")
+			Owner(owner string) GetRepoRequestBuilder
+
+			// @HEADER("Accept")
+			Accept(accept string) GetRepoRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	result := parse.NewParser(f, "test").Parse()
+	assert.Equal(t, "application/vnd.github.v3+json", result.Accept)
+
+	data, err := Generate(result)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("User-Agent", restclient.UserAgent(restClient))
+	restclient.SetVersionHeader(req, restClient, "")
+	for key, value := range b.headerParams {`)
+}
+
+func TestGenerateDecoder(t *testing.T) {
+	src := `package test
+		// @GET("/photos/export")
+		// @DECODER("ParsePhotosCSV")
+		type ExportPhotosRequestBuilder interface {
+			// @SYNC("PhotoList")
+			Run() (PhotoList, error)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `return restclient.Decode(ParsePhotosCSV, response.Body)`)
+	assert.NotContains(t, string(data), `NewPhotoList`)
+}
+
+func TestGenerateAllowStatus(t *testing.T) {
+	src := `package test
+		// @GET("/photos/{id}")
+		// @ALLOW_STATUS("404, 410")
+		type GetPhotoRequestBuilder interface {
+			// @PATH("id")
+			ID(id string) GetPhotoRequestBuilder
+
+			// @SYNC("GetPhotoResponse")
+			Run() (GetPhotoResponse, error)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	result := parse.NewParser(f, "test").Parse()
+	assert.Equal(t, []int{404, 410}, result.AllowedStatus)
+
+	data, err := Generate(result)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `	defer response.Body.Close()
+	if err := restclient.CheckStatus(response, 404, 410); err != nil {
+		return result, err
+	}`)
+}
+
+func TestGenerateGraphQL(t *testing.T) {
+	src := `package test
+		// @GRAPHQL("POST /graphql", query="GetUserQuery")
+		type GetUserRequestBuilder interface {
+			// @VAR("login")
+			Login(login string) GetUserRequestBuilder
+
+			// @SYNC("GetUserResponse")
+			Run() (GetUserResponse, error)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `	b.variables["login"] = login
+	return b`)
+	assert.Contains(t, string(data), `	httpMethod := "POST"
+	operation := restclient.GraphQLOperation{
+		Query:     GetUserQuery,
+		Variables: b.variables,
+	}
+	if req, err = restclient.NewGraphQLRequest(httpMethod, url, operation); err != nil {`)
+	assert.Contains(t, string(data), `	data, err := restclient.DecodeGraphQL(response.Body)
+	if err != nil {
+		return result, err
+	}
+	return restclient.Decode(NewGetUserResponse, data)`)
+}
+
+func TestGeneratePoll(t *testing.T) {
+	src := `package test
+		// @POST("/instances")
+		type CreateInstanceRequestBuilder interface {
+			// @FIELD("name")
+			Name(name string) CreateInstanceRequestBuilder
+
+			// @POLL(statusField="metadata.state", until="DONE", url="name", timeout="5m")
+			RunAndWait(ctx context.Context) (Operation, error)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `func (b *CreateInstanceRequestBuilderImpl) RunAndWait(ctx context.Context) (result Operation, err error) {`)
+	assert.Contains(t, string(data), `	operationURL, err := restclient.OperationURL(response, data, "name")`)
+	assert.Contains(t, string(data), `	data, err = restclient.Poll(ctx, data, poll, restclient.PollPolicy{
+		StatusField: "metadata.state",
+		Until:       []string{"DONE"},
+		Interval:    1 * time.Second,
+		Timeout:     5 * time.Minute,
+	})`)
+	assert.Contains(t, string(data), `	return restclient.Decode(NewOperation, bytes.NewReader(data))`)
+}
+
+func TestGenerateWebSocket(t *testing.T) {
+	src := `package test
+		// @WS("/v1/stream")
+		type StreamRequestBuilder interface {
+			// @QUERY("channel")
+			Channel(channel string) StreamRequestBuilder
+
+			// @CONNECT(send="Subscription", receive="Event")
+			Connect(ctx context.Context) (*restclient.WebSocket[Subscription, Event], error)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `func (b *StreamRequestBuilderImpl) Connect(ctx context.Context) (*restclient.WebSocket[Subscription, Event], error) {
+	restClient, err := restclient.EnvironmentClient(b.environment)
+	if err != nil {
+		return nil, err
+	}
+	request, err := b.build(restClient)
+	if err != nil {
+		return nil, err
+	}
+	restclient.UpgradeWebSocket(request)
+
+	response, err := b.send(restClient, request.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return restclient.NewWebSocket[Subscription, Event](response)
+}`)
+}
+
+func TestGenerateMaxBody(t *testing.T) {
+	src := `package test
+		// @GET("/photos")
+		// @MAX_BODY("10MB")
+		type GetPhotosRequestBuilder interface {
+			// @SYNC("GetPhotosResponse")
+			Run() (GetPhotosResponse, error)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	result := parse.NewParser(f, "test").Parse()
+	assert.Equal(t, int64(10<<20), result.MaxBody)
+
+	data, err := Generate(result)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `	if err := restclient.LimitBody(response, 10485760); err != nil {
+		return result, err
+	}`)
+}
+
+func TestGenerateHedge(t *testing.T) {
+	src := `package test
+		// @GET("/photos")
+		// @HEDGE(after="150ms", max=3)
+		type GetPhotosRequestBuilder interface {
+			// @SYNC("GetPhotosResponse")
+			Run() (GetPhotosResponse, error)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	data, err := Generate(parse.NewParser(f, "test").Parse())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `response, err := restclient.DoHedged(restClient.HttpClient(), request, restclient.HedgePolicy{After: 150 * time.Millisecond, Max: 3})`)
+}
+
+func TestGetDuration(t *testing.T) {
+	assert.Equal(t, "150 * time.Millisecond", getDuration("150ms"))
+	assert.Equal(t, "2 * time.Second", getDuration("2s"))
+	assert.Equal(t, "90 * time.Second", getDuration("1m30s"))
+	assert.Equal(t, "1500 * time.Microsecond", getDuration("1.5ms"))
+}
+
+// typeCheck type checks the source files of a package, which are keyed by file name, with the
+// packages they import loaded from source.
+func typeCheck(t *testing.T, files map[string]string) {
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	for name, src := range files {
+		f, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+		if !assert.NoError(t, err) {
+			return
+		}
+		parsed = append(parsed, f)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err := conf.Check("test", fset, parsed, nil)
+	assert.NoError(t, err)
+}
+
+func TestGenerateSharedCallback(t *testing.T) {
+	src := `package test
+
+		import "io"
+
+		type Photo struct{}
+
+		func NewPhoto(r io.Reader) (*Photo, error) {
+			return &Photo{}, nil
+		}
+
+		// @GET("/photos/{id}")
+		type GetPhotoRequestBuilder interface {
+			// @PATH("id")
+			ID(id string) GetPhotoRequestBuilder
+
+			// @SYNC("*Photo")
+			Run() (*Photo, error)
+
+			// @ASYNC("PhotoCallback")
+			RunAsync(callback PhotoCallback)
+		}
+
+		// @GET("/photos/latest")
+		type GetLatestPhotoRequestBuilder interface {
+			// @SYNC("*Photo")
+			Run() (*Photo, error)
+
+			// @ASYNC("PhotoCallback")
+			RunAsync(callback PhotoCallback)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+	results := parse.NewParser(f, "test").ParseAll()
+
+	for _, layout := range []Layout{LayoutSingle, LayoutPerEndpoint} {
+		generated, err := GenerateAll(results, Options{Layout: layout})
+		assert.NoError(t, err)
+		files := map[string]string{"input.go": src}
+		for i, g := range generated {
+			files[fmt.Sprintf("generated%d.go", i)] = string(g.Source)
+		}
+		assert.Equal(t, 1, strings.Count(string(generated[0].Source), "type PhotoCallback interface"))
+		typeCheck(t, files)
+	}
+}
@@ -5,22 +5,42 @@ import (
 	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/scanner"
 	"go/token"
+	"go/types"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/jsaund/gorest/generate"
 	"github.com/jsaund/gorest/parse"
+	"golang.org/x/tools/go/packages"
 )
 
 var (
-	input  = flag.String("input", "", "name of input file containing REST API to generate (if absent then Stdin is used)")
-	output = flag.String("output", "", "name of output file containing generated API request and response implementation")
-	pkg    = flag.String("pkg", "", "name of output file package (should be the same as input package)")
+	input     = flag.String("input", "", "name of input file or package directory containing REST API to generate (if absent then Stdin is used)")
+	output    = flag.String("output", "", "name of output file containing generated API request and response implementation")
+	pkg       = flag.String("pkg", "", "name of output file package (should be the same as input package)")
+	layout    = flag.String("layout", string(generate.LayoutSingle), "layout of generated files: 'single' generates one output file, 'endpoint' generates a file per request builder next to the output file")
+	naming    = flag.String("naming", string(generate.FileNamingBuilder), "naming of the files of the 'endpoint' layout: 'builder' names a file after its request builder, 'resource' after its resource followed by its action, such as photos_get_gorest.go")
+	suffix    = flag.String("suffix", generate.DefaultFileSuffix, "suffix of the names of the files of the 'endpoint' layout")
+	strict    = flag.Bool("strict", true, "fail when an annotation is not known to gorest, which is usually a misspelled annotation")
+	immutable = flag.Bool("immutable", false, "generate setters which return a modified copy of the request builder, making request builders safe to share between goroutines")
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "conformance" {
+		os.Exit(runConformance(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "docs" {
+		os.Exit(runDocs(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "cli" {
+		os.Exit(runCLI(os.Args[2:]))
+	}
+
 	flag.Parse()
 
 	if *output == "" {
@@ -35,49 +55,186 @@ func main() {
 		os.Exit(1)
 	}
 
-	var file *ast.File
+	parseResults := parseInput(*input, *pkg)
+
+	generated, err := generateBuilder(parseResults, generate.Options{
+		Layout:     generate.Layout(*layout),
+		FileNaming: generate.FileNaming(*naming),
+		FileSuffix: *suffix,
+		Immutable:  *immutable,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to generate REST API implementation. %s\n", err)
+		os.Exit(1)
+	}
+
+	for _, f := range generated {
+		filename := *output
+		if f.Name != "" {
+			filename = filepath.Join(filepath.Dir(*output), f.Name)
+		}
+		if f.Suffix != "" {
+			filename = strings.TrimSuffix(filename, ".go") + f.Suffix
+		}
+		if err := writeFile(filename, f.Source); err != nil {
+			log.Fatalf("Failed to write generated source to file %s. Reason: %s", filename, err)
+		}
+		fmt.Println("Generated source written to file " + filename)
+	}
+}
+
+// parseInput parses the request builders of the input file or package directory, or of Stdin when
+// input is empty. Exits after printing the errors found in the annotations of the request builders.
+func parseInput(input string, pkg string) []*parse.ParseResult {
+	var files []*ast.File
+	var info *types.Info
 	fileset := token.NewFileSet()
 
-	if *input != "" {
-		f, err := parser.ParseFile(fileset, *input, nil, parser.ParseComments)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to parse input filename. Is input filename %s valid?\n", *input)
-			os.Exit(1)
+	if stat, err := os.Stat(input); input != "" && err == nil && stat.IsDir() {
+		if f, i, fs, err := loadPackageDir(input); err == nil {
+			files, info, fileset = f, i, fs
+		} else {
+			log.Printf("Failed to load package of input directory %s, type resolution is limited. Reason: %s", input, err)
+			names, _ := filepath.Glob(filepath.Join(input, "*.go"))
+			for _, name := range names {
+				if strings.HasSuffix(name, "_test.go") {
+					continue
+				}
+				f, err := parser.ParseFile(fileset, name, nil, parser.ParseComments)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to parse input filename. Is input filename %s valid?\n", name)
+					os.Exit(1)
+				}
+				files = append(files, f)
+			}
+		}
+		files = withoutGeneratedFiles(files)
+	} else if input != "" {
+		if f, i, fs, err := loadPackageFile(input); err == nil {
+			files, info, fileset = []*ast.File{f}, i, fs
+		} else {
+			// Fall back to parsing the file on its own. Types declared in other packages are
+			// resolved using the import declarations of the input file.
+			log.Printf("Failed to load package of input filename %s, type resolution is limited. Reason: %s", input, err)
+			f, err := parser.ParseFile(fileset, input, nil, parser.ParseComments)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to parse input filename. Is input filename %s valid?\n", input)
+				os.Exit(1)
+			}
+			files = []*ast.File{f}
 		}
-		file = f
 	} else {
 		f, err := parser.ParseFile(fileset, "", os.Stdin, parser.ParseComments)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "Failed to parse input file. Is source input valid?")
 			os.Exit(1)
 		}
-		file = f
+		files = []*ast.File{f}
 	}
 
-	parseResult := parseAST(file, *pkg)
-	buf, err := generateBuilder(parseResult)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to generate REST API implementation. %s\n", err)
+	var parseResults []*parse.ParseResult
+	var parseErrors scanner.ErrorList
+	for _, file := range files {
+		results, err := parseAST(fileset, file, files, pkg, info)
+		if list, ok := err.(scanner.ErrorList); ok {
+			parseErrors = append(parseErrors, list...)
+		}
+		parseResults = append(parseResults, results...)
+	}
+	if len(parseErrors) > 0 {
+		parseErrors.Sort()
+		scanner.PrintError(os.Stderr, parseErrors)
+		os.Exit(1)
+	}
+	if len(parseResults) == 0 {
+		fmt.Fprintln(os.Stderr, "No request builder found. Annotate an interface with an HTTP method such as @GET")
 		os.Exit(1)
 	}
 
-	if err := writeFile(*output, buf); err != nil {
-		log.Fatalf("Failed to write generated source to file %s. Reason: %s", *output, err)
+	return parseResults
+}
+
+// loadPackageFile loads and type checks the package containing filename.
+// Returns the syntax tree of filename along with the type information and file set of its package.
+func loadPackageFile(filename string) (*ast.File, *types.Info, *token.FileSet, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedImports |
+			packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+	}
+	pkgs, err := packages.Load(cfg, "file="+abs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	for _, p := range pkgs {
+		for i, f := range p.CompiledGoFiles {
+			if f == abs && i < len(p.Syntax) {
+				return p.Syntax[i], p.TypesInfo, p.Fset, nil
+			}
+		}
+	}
+	return nil, nil, nil, fmt.Errorf("no package contains %s", abs)
+}
+
+// loadPackageDir loads and type checks the package in the directory dir.
+// Returns the syntax trees of the package along with its type information and file set.
+func loadPackageDir(dir string) ([]*ast.File, *types.Info, *token.FileSet, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedImports |
+			packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir: abs,
+	}
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(pkgs) != 1 || len(pkgs[0].Errors) > 0 && len(pkgs[0].Syntax) == 0 {
+		return nil, nil, nil, fmt.Errorf("no package in %s", abs)
 	}
+	return pkgs[0].Syntax, pkgs[0].TypesInfo, pkgs[0].Fset, nil
+}
 
-	fmt.Println("Generated source written to file " + *output)
+// withoutGeneratedFiles removes the files generated by gorest, which must not be generated again
+func withoutGeneratedFiles(files []*ast.File) []*ast.File {
+	var sources []*ast.File
+	for _, f := range files {
+		generated := false
+		for _, c := range f.Comments {
+			if c.Pos() < f.Package && strings.Contains(c.Text(), "CODE GENERATED AUTOMATICALLY WITH GOREST") {
+				generated = true
+			}
+		}
+		if !generated {
+			sources = append(sources, f)
+		}
+	}
+	return sources
 }
 
-// parseAST walks the AST represented by the interface we wish to generate an implementation for.
-// Returns ParseResult which contains request and response implementation details.
-func parseAST(file *ast.File, pkg string) *parse.ParseResult {
-	parser := parse.NewParser(file, pkg)
-	return parser.Parse()
+// parseAST walks the AST represented by the interfaces we wish to generate an implementation for.
+// Returns a ParseResult for each request builder which contains request and response implementation
+// details, or the list of errors found in the annotations of the interfaces.
+func parseAST(fileset *token.FileSet, file *ast.File, packageFiles []*ast.File, pkg string, info *types.Info) ([]*parse.ParseResult, error) {
+	parser := parse.NewTypedParser(fileset, file, pkg, info)
+	parser.Strict = *strict
+	parser.PackageFiles = packageFiles
+	results := parser.ParseAll()
+	return results, parser.Err()
 }
 
-// generateBuilder transforms the parsed information in to a request builder and response golang file.
-func generateBuilder(r *parse.ParseResult) ([]byte, error) {
-	return generate.Generate(r)
+// generateBuilder transforms the parsed information in to request builder and response golang files.
+func generateBuilder(results []*parse.ParseResult, options generate.Options) ([]generate.File, error) {
+	return generate.GenerateAll(results, options)
 }
 
 // writeFile persists the data to the specified file
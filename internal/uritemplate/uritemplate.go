@@ -0,0 +1,144 @@
+// Package uritemplate parses URI templates (RFC 6570). It is shared by the parser, which validates
+// the endpoints of request builders, and by restclient, which expands them, so that the parser
+// does not depend on the runtime of the generated code.
+package uritemplate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Template is a URI template, such as /users/{id}/photos{?fields*}, made of the expressions
+// between braces and of the literals around them. It has one more literal than expressions.
+type Template struct {
+	Literals    []string
+	Expressions []Expression
+}
+
+// Expression is an expression of a URI template, such as {?fields*,page}
+type Expression struct {
+	// Operator is the operator of the expression, such as + in {+path} or ? in {?fields}, or 0 for
+	// a simple expression such as {id}
+	Operator  byte
+	Variables []Variable
+}
+
+// Variable is a variable of an expression of a URI template
+type Variable struct {
+	Name string
+	// Operator is the operator of the expression of the variable
+	Operator byte
+	// Explode expands each element of a list or a map separately, such as {?fields*}
+	Explode bool
+	// Prefix is the maximum number of characters of the value which are expanded, such as 3 in {id:3}
+	Prefix int
+}
+
+// Optional returns true if the expression of the variable expands to a valid URI without a value,
+// which is the case of the expressions with a prefix such as {/id} or {?fields}. The variables of
+// simple and reserved expressions such as {id} or {+path} would leave an empty segment.
+func (v Variable) Optional() bool {
+	return v.Operator != 0 && v.Operator != '+'
+}
+
+// operators are the operators of expressions, per section 2.2 of RFC 6570
+const operators = "+./;?&#"
+
+// Parse parses a URI template such as /users/{id}/photos{?fields*}
+func Parse(template string) (*Template, error) {
+	t := &Template{}
+	literal := ""
+	for template != "" {
+		start := strings.IndexAny(template, "{}")
+		if start < 0 {
+			literal += template
+			break
+		}
+		if template[start] == '}' {
+			return nil, fmt.Errorf("Unexpected } before %s", template[start+1:])
+		}
+		end := strings.IndexAny(template[start+1:], "{}")
+		if end < 0 || template[start+1+end] != '}' {
+			return nil, fmt.Errorf("Unterminated expression %s", template[start:])
+		}
+		expression, err := parseExpression(template[start+1 : start+1+end])
+		if err != nil {
+			return nil, err
+		}
+		t.Literals = append(t.Literals, literal+template[:start])
+		t.Expressions = append(t.Expressions, expression)
+		literal = ""
+		template = template[start+1+end+1:]
+	}
+	t.Literals = append(t.Literals, literal)
+	return t, nil
+}
+
+// parseExpression parses the expression between braces, such as ?fields*,page
+func parseExpression(s string) (Expression, error) {
+	var e Expression
+	if s == "" {
+		return e, fmt.Errorf("Empty expression {}")
+	}
+	if strings.IndexByte(operators, s[0]) >= 0 {
+		e.Operator, s = s[0], s[1:]
+	} else if strings.IndexByte("=,!@|", s[0]) >= 0 {
+		return e, fmt.Errorf("Operator %c of expression {%s} is reserved for future extensions", s[0], s)
+	}
+	for _, spec := range strings.Split(s, ",") {
+		v := Variable{Operator: e.Operator}
+		if strings.HasSuffix(spec, "*") {
+			v.Explode, spec = true, strings.TrimSuffix(spec, "*")
+		} else if i := strings.IndexByte(spec, ':'); i >= 0 {
+			prefix, err := strconv.Atoi(spec[i+1:])
+			if err != nil || prefix < 1 || prefix > 9999 {
+				return e, fmt.Errorf("Invalid prefix %s of variable %s, expected a length between 1 and 9999", spec[i+1:], spec[:i])
+			}
+			v.Prefix, spec = prefix, spec[:i]
+		}
+		if !isVariableName(spec) {
+			return e, fmt.Errorf("Invalid variable name %q in expression {%s}", spec, s)
+		}
+		v.Name = spec
+		e.Variables = append(e.Variables, v)
+	}
+	return e, nil
+}
+
+// isVariableName returns true if name is made of letters, digits, underscores, percent-encoded
+// triplets and dots between them
+func isVariableName(name string) bool {
+	if name == "" || name[0] == '.' || name[len(name)-1] == '.' || strings.Contains(name, "..") {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case c == '%':
+			if i+2 >= len(name) || !isHex(name[i+1]) || !isHex(name[i+2]) {
+				return false
+			}
+			i += 2
+		case c != '_' && c != '.' && !isAlphaNumeric(c):
+			return false
+		}
+	}
+	return true
+}
+
+// Variables returns the variables of the expressions of the template
+func (t *Template) Variables() []Variable {
+	var variables []Variable
+	for _, e := range t.Expressions {
+		variables = append(variables, e.Variables...)
+	}
+	return variables
+}
+
+func isAlphaNumeric(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
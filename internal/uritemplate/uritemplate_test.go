@@ -0,0 +1,28 @@
+package uritemplate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	template, err := Parse("/users/{id}/photos{/path*}{?fields*,size:3}")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"/users/", "/photos", "", ""}, template.Literals)
+	assert.Equal(t, []Variable{
+		{Name: "id"},
+		{Name: "path", Operator: '/', Explode: true},
+		{Name: "fields", Operator: '?', Explode: true},
+		{Name: "size", Operator: '?', Prefix: 3},
+	}, template.Variables())
+	assert.False(t, template.Variables()[0].Optional())
+	assert.True(t, template.Variables()[1].Optional())
+
+	for _, invalid := range []string{"/users/{id", "/users/id}", "/users/{}", "/users/{!id}", "/users/{id:0}", "/users/{user-id}", "/users/{a..b}"} {
+		_, err := Parse(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,515 @@
+package parse
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jsaund/gorest/internal/uritemplate"
+)
+
+// webSocketUnsupported are the annotations which do not apply to the connection opened by a @WS request builder
+var webSocketUnsupported = map[string]empty{
+	example:     empty{},
+	dictionary:  empty{},
+	idempotent:  empty{},
+	hedge:       empty{},
+	allowStatus: empty{},
+	maxBody:     empty{},
+	allowBody:   empty{},
+	body:        empty{},
+	field:       empty{},
+	part:        empty{},
+	bodyStream:  empty{},
+	variable:    empty{},
+	sync:        empty{},
+	async:       empty{},
+	paginated:   empty{},
+	progress:    empty{},
+	download:    empty{},
+	poll:        empty{},
+}
+
+// positionedAnnotation is an annotation along with the position of its @ sign in the source.
+// Valid is false when the arguments of the annotation are malformed.
+type positionedAnnotation struct {
+	Annotation
+	pos   token.Pos
+	valid bool
+}
+
+// Err returns the errors found while parsing as a go/scanner.ErrorList sorted by position,
+// or nil if no errors were found. The errors are reported with the file, line and column of the
+// offending annotation when the parser was created with a token.FileSet, see NewTypedParser.
+func (p *Parser) Err() error {
+	p.errors.Sort()
+	return p.errors.Err()
+}
+
+// errorf records an error at the position pos
+func (p *Parser) errorf(pos token.Pos, format string, args ...interface{}) {
+	var position token.Position
+	if p.fset != nil && pos.IsValid() {
+		position = p.fset.Position(pos)
+	}
+	p.errors.Add(position, fmt.Sprintf(format, args...))
+}
+
+// scanAnnotations returns every annotation of the comments, including unknown annotations
+func scanAnnotations(doc *ast.CommentGroup) []positionedAnnotation {
+	if doc == nil {
+		return nil
+	}
+	var annotations []positionedAnnotation
+	for _, comment := range doc.List {
+		for _, match := range re.FindAllStringSubmatchIndex(comment.Text, -1) {
+			value, args, valid := parseArguments(comment.Text[match[1]:])
+			annotations = append(annotations, positionedAnnotation{
+				Annotation: Annotation{Key: comment.Text[match[2]:match[3]], Value: value, Args: args},
+				pos:        comment.Slash + token.Pos(match[0]),
+				valid:      valid,
+			})
+		}
+	}
+	return annotations
+}
+
+// checkInterfaceAnnotations reports annotations of the request builder declaration which are
+// malformed or which belong to a method of the request builder.
+func (p *Parser) checkInterfaceAnnotations(doc *ast.CommentGroup) {
+	method := ""
+	for _, a := range scanAnnotations(doc) {
+		if _, ok := webSocketUnsupported[a.Key]; ok && p.result.WebSocket != nil && interfaceAnnotationFilter(a.Key) {
+			p.errorf(a.pos, "@%s is not supported by @%s request builders", a.Key, websocket)
+		}
+		switch {
+		case !a.valid:
+			p.errorf(a.pos, "@%s has malformed arguments", a.Key)
+		case httpAnnotationFilter(a.Key):
+			if a.Value == "" {
+				p.errorf(a.pos, "@%s requires an endpoint argument", a.Key)
+			}
+			if method != "" {
+				p.errorf(a.pos, "@%s conflicts with @%s, a request builder has a single HTTP method", a.Key, method)
+			}
+			method = a.Key
+			if a.Key == graphql {
+				p.checkGraphQL(a)
+			}
+		case a.Key == dictionary:
+			if a.Value == "" {
+				p.errorf(a.pos, "@%s requires a dictionary name argument", a.Key)
+			}
+		case a.Key == example:
+			if a.Value != "" || len(a.Args) == 0 {
+				p.errorf(a.pos, "@%s requires named arguments, for example @%s(id=\"123\")", a.Key, a.Key)
+			}
+			if p.result.GraphQL != nil {
+				p.errorf(a.pos, "@%s is not supported by @%s request builders", a.Key, graphql)
+			}
+		case a.Key == deprecated:
+			// Deprecates the request builder
+		case a.Key == hedge:
+			if _, err := time.ParseDuration(a.Args["after"]); err != nil {
+				p.errorf(a.pos, "@%s requires an after argument with a duration, for example @%s(after=\"150ms\")", a.Key, a.Key)
+			}
+			if max, ok := a.Args["max"]; ok {
+				if n, err := strconv.Atoi(max); err != nil || n < 2 {
+					p.errorf(a.pos, "@%s max must be a number of requests of at least 2", a.Key)
+				}
+			}
+			if p.result.HttpMethod != httpMethodGet {
+				p.errorf(a.pos, "@%s requires a GET request, which is safe to send more than once", a.Key)
+			}
+		case a.Key == accept:
+			if a.Value == "" {
+				p.errorf(a.pos, "@%s requires a media type, for example @%s(\"application/json\")", a.Key, a.Key)
+			}
+		case a.Key == maxBody:
+			if size, err := parseSize(a.Value); err != nil || size <= 0 {
+				p.errorf(a.pos, "@%s requires a size in bytes with an optional unit of B, KB, MB or GB, for example @%s(\"10MB\")", a.Key, a.Key)
+			}
+		case a.Key == allowStatus:
+			for _, code := range strings.Split(a.Value, ",") {
+				if status, err := strconv.Atoi(strings.TrimSpace(code)); err != nil || status < 100 || status > 599 {
+					p.errorf(a.pos, "@%s requires a comma separated list of status codes, for example @%s(\"404\")", a.Key, a.Key)
+					break
+				}
+			}
+		case a.Key == idempotent:
+			// Optionally names the header of the idempotency key
+		case a.Key == allowBody:
+			// Sends the body of a request whose method normally has no body
+		case a.Key == version:
+			if a.Value == "" {
+				p.errorf(a.pos, "@%s requires the version of the API, for example @%s(\"2023-10-01\")", a.Key, a.Key)
+			}
+		case a.Key == decoder:
+			if !isFunctionName(a.Value) {
+				p.errorf(a.pos, "@%s requires the name of a function decoding the response, for example @%s(\"ParsePhotoFeed\")", a.Key, a.Key)
+			}
+		case requestAnnotationFilter(a.Key) || modifierAnnotationFilter(a.Key):
+			p.errorf(a.pos, "@%s must annotate a method of the request builder", a.Key)
+		}
+	}
+}
+
+// checkMethodAnnotations reports annotations of a request builder method which are malformed,
+// which belong to the request builder declaration, which do not fit the method or which conflict
+// with another annotation of the request builder.
+func (p *Parser) checkMethodAnnotations(f *ast.Field) {
+	function, _ := f.Type.(*ast.FuncType)
+	name := f.Names[0].Name
+	request := ""
+	for _, a := range scanAnnotations(f.Doc) {
+		if !a.valid {
+			p.errorf(a.pos, "@%s has malformed arguments", a.Key)
+			continue
+		}
+
+		if requestAnnotationFilter(a.Key) {
+			if request != "" {
+				p.errorf(a.pos, "@%s conflicts with @%s, method %s must have a single request annotation", a.Key, request, name)
+			}
+			request = a.Key
+		}
+
+		// The request builder has a single response of each kind and a single setter per path segment
+		var declaration string
+		switch a.Key {
+		case sync, async, paginated, progress, download, body, bodyStream, connect, poll:
+			declaration = "@" + a.Key
+		case path, variable:
+			declaration = fmt.Sprintf("@%s(%q)", a.Key, a.Value)
+		}
+		if declaration != "" {
+			if other, ok := p.declared[declaration]; ok && other != name {
+				p.errorf(a.pos, "%s is already declared by method %s", declaration, other)
+			}
+			p.declared[declaration] = name
+		}
+
+		if p.result.GraphQL != nil {
+			switch a.Key {
+			case body, field, part, bodyStream, paginated:
+				p.errorf(a.pos, "@%s method %s is not supported by @%s request builders, the body of the request is the GraphQL operation", a.Key, name, graphql)
+			}
+		}
+
+		if _, ok := webSocketUnsupported[a.Key]; ok && p.result.WebSocket != nil {
+			p.errorf(a.pos, "@%s method %s is not supported by @%s request builders", a.Key, name, websocket)
+		}
+
+		if p.result.GraphQL == nil && p.result.WebSocket == nil && !p.result.AllowBody &&
+			p.result.HttpMethod != httpMethodPost && p.result.HttpMethod != httpMethodPut {
+			switch a.Key {
+			case body, field, part, bodyStream:
+				p.errorf(a.pos, "@%s method %s sets the body of a %s request, which is only sent when the request builder is annotated with @%s()", a.Key, name, p.result.HttpMethod, allowBody)
+			}
+		}
+
+		switch a.Key {
+		case body:
+			if !isSetter(function) {
+				p.errorf(a.pos, "@%s method %s must have a parameter and return the request builder", a.Key, name)
+			}
+		case field, header, part, path, query, variable:
+			if a.Value == "" {
+				p.errorf(a.pos, "@%s requires a name argument", a.Key)
+			}
+			if !isSetter(function) {
+				p.errorf(a.pos, "@%s method %s must have a parameter and return the request builder", a.Key, name)
+			}
+			if _, ok := p.endpointVariables()[a.Value]; a.Key == path && a.Value != "" && !ok {
+				p.errorf(a.pos, "@%s(%q) does not match a {%s} segment of endpoint %s", a.Key, a.Value, a.Value, p.result.ApiEndpoint)
+			}
+			if a.Key == variable && p.result.GraphQL == nil {
+				p.errorf(a.pos, "@%s method %s requires a @%s request builder", a.Key, name, graphql)
+			}
+		case queryStruct:
+			if a.Value != "" {
+				p.errorf(a.pos, "@%s does not take a name argument, the names are taken from the url tags of the struct", a.Key)
+			}
+			if !isSetter(function) {
+				p.errorf(a.pos, "@%s method %s must have a parameter and return the request builder", a.Key, name)
+			}
+		case progress:
+			if !isSetter(function) || !isFuncParam(function.Params.List[0]) {
+				p.errorf(a.pos, "@%s method %s must have a func(sent, total int64) parameter and return the request builder", a.Key, name)
+			}
+		case bodyStream:
+			if !isSetter(function) || len(function.Params.List) > 2 {
+				p.errorf(a.pos, "@%s method %s must have an io.Reader and an optional int64 content length as parameters and return the request builder", a.Key, name)
+			}
+			if p.result.Dictionary != "" {
+				p.errorf(a.pos, "@%s method %s cannot stream the body of a request compressed with @DICTIONARY", a.Key, name)
+			}
+		case download:
+			if function == nil || len(function.Params.List) == 0 || len(function.Params.List) > 2 ||
+				len(function.Params.List) == 2 && !isFuncParam(function.Params.List[1]) {
+				p.errorf(a.pos, "@%s method %s must have an io.Writer and an optional func(received, total int64) as parameters", a.Key, name)
+			}
+			if function == nil || function.Results == nil || len(function.Results.List) != 2 {
+				p.errorf(a.pos, "@%s method %s must return the number of bytes written and an error", a.Key, name)
+			}
+		case sync:
+			// A request without content, such as a HEAD request, can return only an error
+			noContent := a.Value == "" && isErrorOnly(function)
+			if a.Value == "" && !noContent {
+				p.errorf(a.pos, "@%s requires a response type argument", a.Key)
+			}
+			if !noContent && (function == nil || function.Results == nil || len(function.Results.List) != 2) {
+				p.errorf(a.pos, "@%s method %s must return the response and an error", a.Key, name)
+			}
+			if function != nil && (len(function.Params.List) > 1 || len(function.Params.List) == 1 && !isContextParam(function.Params.List[0])) {
+				p.errorf(a.pos, "@%s method %s must have no parameters or a context.Context parameter", a.Key, name)
+			}
+		case async:
+			if a.Value == "" {
+				p.errorf(a.pos, "@%s requires a callback type argument", a.Key)
+			}
+			if function == nil || len(function.Params.List) == 0 || len(function.Params.List) > 2 ||
+				len(function.Params.List) == 2 && !isContextParam(function.Params.List[0]) {
+				p.errorf(a.pos, "@%s method %s must have the callback as its only parameter, optionally preceded by a context.Context", a.Key, name)
+			}
+		case poll:
+			if a.Args["statusField"] == "" || a.Args["until"] == "" {
+				p.errorf(a.pos, "@%s requires statusField and until arguments, for example @%s(statusField=\"status\", until=\"done\")", a.Key, a.Key)
+			}
+			for _, arg := range []string{"interval", "timeout"} {
+				if value, ok := a.Args[arg]; ok {
+					if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+						p.errorf(a.pos, "@%s %s must be a positive duration, for example %s=\"2s\"", a.Key, arg, arg)
+					}
+				}
+			}
+			if function == nil || function.Results == nil || len(function.Results.List) != 2 {
+				p.errorf(a.pos, "@%s method %s must return the final state of the operation and an error", a.Key, name)
+			}
+			if function == nil || len(function.Params.List) != 1 || !isContextParam(function.Params.List[0]) {
+				p.errorf(a.pos, "@%s method %s must have a context.Context parameter", a.Key, name)
+			}
+		case connect:
+			if a.Args["send"] == "" || a.Args["receive"] == "" {
+				p.errorf(a.pos, "@%s requires the types of the messages sent and received, for example @%s(send=\"Subscription\", receive=\"Event\")", a.Key, a.Key)
+			}
+			if function == nil || function.Results == nil || len(function.Results.List) != 2 {
+				p.errorf(a.pos, "@%s method %s must return the connection and an error", a.Key, name)
+			}
+			if function != nil && (len(function.Params.List) > 1 || len(function.Params.List) == 1 && !isContextParam(function.Params.List[0])) {
+				p.errorf(a.pos, "@%s method %s must have no parameters or a context.Context parameter", a.Key, name)
+			}
+			if p.result.WebSocket == nil {
+				p.errorf(a.pos, "@%s method %s requires a @%s request builder", a.Key, name, websocket)
+			}
+		case paginated:
+			_, hasParam := a.Args["param"]
+			_, hasPage := a.Args["page"]
+			_, hasCursor := a.Args["cursor"]
+			_, hasPages := a.Args["pages"]
+			if !(hasCursor && hasParam && !hasPages || hasPages && (hasPage || hasParam) && !hasCursor) {
+				p.errorf(a.pos, "@%s requires either cursor and param arguments or page and pages arguments", a.Key)
+			}
+			if function == nil || len(function.Params.List) != 2 {
+				p.errorf(a.pos, "@%s method %s must have a context and an iteration function as parameters", a.Key, name)
+			}
+		case format:
+			if a.Value == "" {
+				p.errorf(a.pos, "@%s requires a format argument", a.Key)
+			}
+		case deprecated:
+			// Deprecates the method
+		default:
+			if httpAnnotationFilter(a.Key) || interfaceAnnotationFilter(a.Key) {
+				p.errorf(a.pos, "@%s must annotate the request builder interface", a.Key)
+			}
+		}
+	}
+}
+
+// checkPathSubstitutions reports an endpoint which is not a valid URI template, and the variables
+// of the endpoint which are not substituted by a @PATH method. The variables of expressions with a
+// prefix, such as {/id} or {?fields}, are optional.
+func (p *Parser) checkPathSubstitutions() {
+	template, err := uritemplate.Parse(p.result.ApiEndpoint)
+	if err != nil {
+		p.errorf(p.httpPos, "Endpoint %s is not a valid URI template: %v", p.result.ApiEndpoint, err)
+		return
+	}
+	substituted := make(map[string]bool)
+	for _, f := range p.result.PathSubstitutions {
+		if annotation, valid := ExtractRequestAnnotation(f.Doc.Text()); valid {
+			substituted[annotation.Value] = true
+		}
+	}
+	for _, v := range template.Variables() {
+		if !substituted[v.Name] && !v.Optional() {
+			p.errorf(p.httpPos, "Endpoint %s has no @PATH method for {%s}", p.result.ApiEndpoint, v.Name)
+		}
+	}
+}
+
+// checkDecoder reports a @DECODER annotation of a request builder without a response decoded from
+// the body of the response
+func (p *Parser) checkDecoder() {
+	for _, a := range scanAnnotations(p.result.Doc) {
+		if a.Key != decoder || !a.valid {
+			continue
+		}
+		if r := p.result; r.ResponseType == "" || r.ResponseType == "http.Header" {
+			p.errorf(a.pos, "@%s requires a response type decoded from the body of the response, such as @%s(\"PhotoFeed\")", a.Key, sync)
+		}
+	}
+}
+
+// isFunctionName returns true if name refers to a function, such as ParseFeed, csv.ParseFeed or
+// the instantiation of a generic function such as ParseFeed[Photo]
+func isFunctionName(name string) bool {
+	expr, err := parser.ParseExpr(name)
+	if err != nil {
+		return false
+	}
+	switch e := expr.(type) {
+	case *ast.IndexExpr:
+		expr = e.X
+	case *ast.IndexListExpr:
+		expr = e.X
+	}
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		_, ok := e.X.(*ast.Ident)
+		return ok
+	}
+	return false
+}
+
+// endpointVariables returns the variables of the URI template of the endpoint by name
+func (p *Parser) endpointVariables() map[string]uritemplate.Variable {
+	variables := make(map[string]uritemplate.Variable)
+	if template, err := uritemplate.Parse(p.result.ApiEndpoint); err == nil {
+		for _, v := range template.Variables() {
+			variables[v.Name] = v
+		}
+	}
+	return variables
+}
+
+// checkGraphQL reports a @GRAPHQL annotation whose endpoint or operation is malformed
+func (p *Parser) checkGraphQL(a positionedAnnotation) {
+	method, endpoint := splitGraphQLEndpoint(a.Value)
+	if method != httpMethodGet && method != httpMethodPost || endpoint == "" {
+		p.errorf(a.pos, "@%s requires an endpoint preceded by an optional GET or POST method, for example @%s(\"POST /graphql\")", a.Key, a.Key)
+	}
+	query, hasQuery := a.Args["query"]
+	_, hasPersisted := a.Args["persisted"]
+	if hasQuery == hasPersisted {
+		p.errorf(a.pos, "@%s requires either a query argument naming the constant of the query document or a persisted argument naming a persisted query", a.Key)
+	} else if hasQuery && !token.IsIdentifier(query) {
+		p.errorf(a.pos, "@%s query must name the constant containing the query document, %q is not an identifier", a.Key, query)
+	}
+}
+
+// isSetter returns true if the function has a parameter and returns a single result
+func isSetter(function *ast.FuncType) bool {
+	return function != nil && len(function.Params.List) > 0 && function.Results != nil && len(function.Results.List) == 1
+}
+
+// isFuncParam returns true if the parameter is a single function
+func isFuncParam(param *ast.Field) bool {
+	_, ok := param.Type.(*ast.FuncType)
+	return ok && len(param.Names) <= 1
+}
+
+// isErrorOnly returns true if the function returns only an error
+func isErrorOnly(function *ast.FuncType) bool {
+	if function == nil || function.Results == nil || len(function.Results.List) != 1 {
+		return false
+	}
+	ident, ok := function.Results.List[0].Type.(*ast.Ident)
+	return ok && ident.Name == "error" && len(function.Results.List[0].Names) <= 1
+}
+
+// isContextParam returns true if the parameter is a single context.Context
+func isContextParam(param *ast.Field) bool {
+	sel, ok := param.Type.(*ast.SelectorExpr)
+	if !ok || len(param.Names) > 1 {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "context" && sel.Sel.Name == "Context"
+}
+
+// checkUnknownAnnotations reports the annotations of the file which are not known to gorest
+// along with the closest known annotation, which is most likely the one that was intended.
+func (p *Parser) checkUnknownAnnotations() {
+	var known []string
+	for _, annotations := range []map[string]empty{httpMethods, annotationTypes, interfaceAnnotationTypes, modifierAnnotationTypes} {
+		for key := range annotations {
+			known = append(known, key)
+		}
+	}
+	sort.Strings(known)
+
+	for _, group := range p.file.Comments {
+		for _, a := range scanAnnotations(group) {
+			if isKnownAnnotation(a.Key) {
+				continue
+			}
+			suggestion, distance := "", -1
+			for _, key := range known {
+				if d := editDistance(strings.ToUpper(a.Key), key); distance < 0 || d < distance {
+					suggestion, distance = key, d
+				}
+			}
+			if distance <= len(suggestion)/2 {
+				p.errorf(a.pos, "Unknown annotation @%s, did you mean @%s?", a.Key, suggestion)
+			} else {
+				p.errorf(a.pos, "Unknown annotation @%s", a.Key)
+			}
+		}
+	}
+}
+
+// isKnownAnnotation returns true if the annotation is known to gorest
+func isKnownAnnotation(key string) bool {
+	return httpAnnotationFilter(key) || requestAnnotationFilter(key) || interfaceAnnotationFilter(key) || modifierAnnotationFilter(key)
+}
+
+// editDistance returns the number of insertions, deletions, substitutions and transpositions
+// of adjacent characters needed to turn a in to b
+func editDistance(a string, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = d[i-1][j-1] + cost
+			if d[i-1][j]+1 < d[i][j] {
+				d[i][j] = d[i-1][j] + 1
+			}
+			if d[i][j-1]+1 < d[i][j] {
+				d[i][j] = d[i][j-1] + 1
+			}
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && d[i-2][j-2]+1 < d[i][j] {
+				d[i][j] = d[i-2][j-2] + 1
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
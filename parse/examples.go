@@ -0,0 +1,169 @@
+package parse
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jsaund/gorest/internal/uritemplate"
+)
+
+// checkExamples matches the arguments of the @EXAMPLE annotations of the request builder to its
+// setters and records the type underlying the parameter of each setter, which is needed to turn
+// the example values in to Go literals. Arguments which do not match a setter, setters whose
+// parameter type cannot be given as an example and values which do not fit the type are reported.
+func (p *Parser) checkExamples() {
+	if p.result.GraphQL != nil {
+		// Reported by checkInterfaceAnnotations
+		return
+	}
+	setters := make(map[string]*ast.Field)
+	for _, params := range []map[string]*ast.Field{
+		p.result.PathSubstitutions,
+		p.result.QueryParams,
+		p.result.PostFormParams,
+		p.result.PostMultiPartParams,
+		p.result.HeaderParams,
+	} {
+		for name, f := range params {
+			setters[strings.ToLower(name)] = f
+		}
+	}
+
+	for _, a := range scanAnnotations(p.result.Doc) {
+		if a.Key != example || !a.valid {
+			continue
+		}
+		if expression := p.templateExpression(); expression != "" {
+			p.errorf(a.pos, "@%s requires the variables of endpoint %s to be simple expressions such as {id}, got {%s}", a.Key, p.result.ApiEndpoint, expression)
+			continue
+		}
+		keys := make([]string, 0, len(a.Args))
+		for key := range a.Args {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := a.Args[key]
+			f, ok := setters[strings.ToLower(key)]
+			if !ok {
+				p.errorf(a.pos, "@%s argument %s does not match a @PATH, @QUERY, @FIELD, @PART or @HEADER method", a.Key, key)
+				continue
+			}
+			function := f.Type.(*ast.FuncType)
+			if len(function.Params.List) == 0 {
+				continue
+			}
+			param := function.Params.List[0].Type
+			underlying := p.underlyingType(param, map[string]bool{})
+			if underlying == "" {
+				p.errorf(a.pos, "@%s argument %s sets a parameter of type %s, only basic types, time.Time and time.Duration can be given as examples", a.Key, key, types.ExprString(param))
+				continue
+			}
+			if !isExampleValue(underlying, value, f) {
+				p.errorf(a.pos, "@%s argument %s=%q is not a valid %s", a.Key, key, value, underlying)
+				continue
+			}
+			p.result.ExampleTypes[f.Names[0].Name] = underlying
+		}
+	}
+}
+
+// underlyingType returns the name of the basic type underlying the type expression, or time.Time
+// and time.Duration which are given as examples in their own format.
+// The empty string is returned for other types and types which cannot be resolved.
+func (p *Parser) underlyingType(expr ast.Expr, visited map[string]bool) string {
+	if p.info != nil {
+		if t := p.info.TypeOf(expr); t != nil {
+			if named, ok := t.(*types.Named); ok {
+				if obj := named.Obj(); obj.Pkg() != nil && obj.Pkg().Path() == "time" && (obj.Name() == "Time" || obj.Name() == "Duration") {
+					return "time." + obj.Name()
+				}
+			}
+			if basic, ok := t.Underlying().(*types.Basic); ok {
+				return basic.Name()
+			}
+			return ""
+		}
+	}
+
+	// Without type information only basic types and the types declared in the file are resolved
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if obj, ok := types.Universe.Lookup(e.Name).(*types.TypeName); ok {
+			if basic, ok := obj.Type().Underlying().(*types.Basic); ok {
+				return basic.Name()
+			}
+			return ""
+		}
+		if visited[e.Name] {
+			return ""
+		}
+		visited[e.Name] = true
+		if spec := p.lookupType(e.Name); spec != nil {
+			return p.underlyingType(spec.Type, visited)
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := e.X.(*ast.Ident); ok && pkg.Name == "time" && (e.Sel.Name == "Time" || e.Sel.Name == "Duration") {
+			return "time." + e.Sel.Name
+		}
+	case *ast.ParenExpr:
+		return p.underlyingType(e.X, visited)
+	}
+	return ""
+}
+
+// templateExpression returns the first variable of the endpoint which is not a simple expression,
+// such as ?fields in {?fields}, or the empty string
+func (p *Parser) templateExpression() string {
+	template, err := uritemplate.Parse(p.result.ApiEndpoint)
+	if err != nil {
+		// Reported by checkPathSubstitutions
+		return ""
+	}
+	for _, v := range template.Variables() {
+		switch {
+		case v.Operator != 0:
+			return string(v.Operator) + v.Name
+		case v.Prefix > 0:
+			return v.Name + ":" + strconv.Itoa(v.Prefix)
+		}
+	}
+	return ""
+}
+
+// isExampleValue returns true if value is a valid example of the setter f whose parameter has the
+// underlying type
+func isExampleValue(underlying string, value string, f *ast.Field) bool {
+	var err error
+	switch underlying {
+	case "string":
+	case "bool":
+		_, err = strconv.ParseBool(value)
+	case "int", "int8", "int16", "int32", "int64":
+		_, err = strconv.ParseInt(value, 0, 64)
+	case "uint", "uint8", "uint16", "uint32", "uint64", "uintptr":
+		_, err = strconv.ParseUint(value, 0, 64)
+	case "float32", "float64":
+		_, err = strconv.ParseFloat(value, 64)
+	case "time.Duration":
+		_, err = time.ParseDuration(value)
+	case "time.Time":
+		annotation, _ := ExtractAnnotation(format, f.Doc.Text())
+		switch layout := annotation.Value; {
+		case layout == "unix" || layout == "unixmilli" || layout == "unixnano":
+			_, err = strconv.ParseInt(value, 10, 64)
+		case layout == "" || strings.HasPrefix(layout, "%"):
+			_, err = time.Parse(time.RFC3339, value)
+		default:
+			_, err = time.Parse(layout, value)
+		}
+	default:
+		// Complex numbers are not supported
+		return false
+	}
+	return err == nil
+}
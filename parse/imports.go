@@ -0,0 +1,103 @@
+package parse
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// resolveImports records the import path of every package referenced by the
+// parameter, response and callback types of the request builder r. When the
+// parser was created with type information (see NewTypedParser) the package is
+// resolved by the type checker, otherwise the import declarations of the input
+// file are used.
+func (p *Parser) resolveImports(r *ParseResult) {
+	fields := []*ast.Field{r.SyncResponse, r.AsyncResponse, r.PaginatedResponse, r.Progress, r.Download, r.BodyStream, r.Connect, r.PollResponse}
+	for _, params := range []map[string]*ast.Field{
+		r.PathSubstitutions,
+		r.QueryParams,
+		r.QueryStructParams,
+		r.PostFormParams,
+		r.PostMultiPartParams,
+		r.PostParams,
+		r.HeaderParams,
+		r.Variables,
+	} {
+		for _, f := range params {
+			fields = append(fields, f)
+		}
+	}
+
+	for _, f := range fields {
+		if f == nil {
+			continue
+		}
+		ast.Inspect(f.Type, func(node ast.Node) bool {
+			if sel, ok := node.(*ast.SelectorExpr); ok {
+				if ident, ok := sel.X.(*ast.Ident); ok {
+					p.addImport(r, ident)
+				}
+				return false
+			}
+			return true
+		})
+	}
+
+	// The decoder is a function rather than a type, which is parsed the same way
+	typeNames := []string{r.ResponseType, r.CallbackType, r.Decoder}
+	if r.WebSocket != nil {
+		typeNames = append(typeNames, r.WebSocket.Send, r.WebSocket.Receive)
+	}
+	for _, typeName := range typeNames {
+		if typeName == "" {
+			continue
+		}
+		// The type may be generic, such as models.Page[models.Photo]
+		expr, err := parser.ParseExpr(typeName)
+		if err != nil {
+			continue
+		}
+		ast.Inspect(expr, func(node ast.Node) bool {
+			if sel, ok := node.(*ast.SelectorExpr); ok {
+				if ident, ok := sel.X.(*ast.Ident); ok {
+					p.addImport(r, ast.NewIdent(ident.Name))
+				}
+				return false
+			}
+			return true
+		})
+	}
+}
+
+// addImport resolves the package identified by ident and adds it to the import set of r.
+func (p *Parser) addImport(r *ParseResult, ident *ast.Ident) {
+	if obj, ok := p.info.Uses[ident].(*types.PkgName); ok {
+		pkg := obj.Imported()
+		name := ""
+		if obj.Name() != pkg.Name() {
+			name = obj.Name()
+		}
+		r.Imports[pkg.Path()] = name
+		return
+	}
+
+	for _, spec := range p.file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		if spec.Name != nil {
+			if spec.Name.Name == ident.Name {
+				r.Imports[importPath] = spec.Name.Name
+				return
+			}
+			continue
+		}
+		if importPath[strings.LastIndex(importPath, "/")+1:] == ident.Name {
+			r.Imports[importPath] = ""
+			return
+		}
+	}
+}
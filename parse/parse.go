@@ -2,18 +2,46 @@ package parse
 
 import (
 	"go/ast"
+	"go/scanner"
+	"go/token"
 	"go/types"
 	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
 )
 
 const (
 	sync               string = "SYNC"
 	async              string = "ASYNC"
+	paginated          string = "PAGINATED"
+	progress           string = "PROGRESS"
+	download           string = "DOWNLOAD"
+	bodyStream         string = "BODY_STREAM"
+	example            string = "EXAMPLE"
+	dictionary         string = "DICTIONARY"
 	header             string = "HEADER"
 	path               string = "PATH"
 	query              string = "QUERY"
+	queryStruct        string = "QUERYSTRUCT"
 	field              string = "FIELD"
 	part               string = "PART"
+	format             string = "FORMAT"
+	deprecated         string = "DEPRECATED"
+	idempotent         string = "IDEMPOTENT"
+	hedge              string = "HEDGE"
+	allowStatus        string = "ALLOW_STATUS"
+	accept             string = "ACCEPT"
+	variable           string = "VAR"
+	graphql            string = "GRAPHQL"
+	websocket          string = "WS"
+	connect            string = "CONNECT"
+	poll               string = "POLL"
+	maxBody            string = "MAX_BODY"
+	body               string = "BODY"
+	allowBody          string = "ALLOW_BODY"
+	version            string = "VERSION"
+	decoder            string = "DECODER"
 	httpMethodGet      string = "GET"
 	httpMethodPost     string = "POST"
 	httpMethodPostForm string = "POST_FORM"
@@ -21,36 +49,128 @@ const (
 	httpMethodDelete   string = "DELETE"
 	httpMethodHead     string = "HEAD"
 
-	// pattern represents the annotation regex pattern
+	// idempotencyHeader is the default header carrying the idempotency key of an @IDEMPOTENT request
+	idempotencyHeader string = "Idempotency-Key"
+
+	// pattern represents the annotation regex pattern which matches the start of an annotation
 	// A valid annotation example is: @GET("/photos/{id}/comments"), where we return
-	// ['GET("/photos/{id}/comments")', 'GET', '/photos/{id}/comments']
-	pattern string = `@(\w+)\(\"(.*)\"\)`
+	// ['GET(', 'GET'] and the arguments following the match are parsed by parseArguments
+	pattern string = `@(\w+)\(`
 )
 
 var re *regexp.Regexp = regexp.MustCompile(pattern)
 
 var annotationTypes = map[string]empty{
-	field:  empty{},
-	header: empty{},
-	part:   empty{},
-	path:   empty{},
-	query:  empty{},
-	sync:   empty{},
-	async:  empty{},
+	field:       empty{},
+	header:      empty{},
+	part:        empty{},
+	path:        empty{},
+	query:       empty{},
+	queryStruct: empty{},
+	sync:        empty{},
+	async:       empty{},
+	paginated:   empty{},
+	progress:    empty{},
+	download:    empty{},
+	bodyStream:  empty{},
+	variable:    empty{},
+	connect:     empty{},
+	poll:        empty{},
+	body:        empty{},
+}
+
+var interfaceAnnotationTypes = map[string]empty{
+	example:     empty{},
+	dictionary:  empty{},
+	deprecated:  empty{},
+	idempotent:  empty{},
+	hedge:       empty{},
+	allowStatus: empty{},
+	accept:      empty{},
+	maxBody:     empty{},
+	allowBody:   empty{},
+	version:     empty{},
+	decoder:     empty{},
+}
+
+// modifierAnnotationTypes are annotations which modify the request annotation of a method
+var modifierAnnotationTypes = map[string]empty{
+	format:     empty{},
+	deprecated: empty{},
 }
 
 var httpMethods = map[string]empty{
+	graphql:            empty{},
 	httpMethodDelete:   empty{},
 	httpMethodGet:      empty{},
 	httpMethodHead:     empty{},
 	httpMethodPost:     empty{},
 	httpMethodPostForm: empty{},
 	httpMethodPut:      empty{},
+	websocket:          empty{},
 }
 
+// Annotation is a parsed annotation such as @QUERY("page") or @PAGINATED(cursor="next", param="cursor").
+// Value is the leading quoted argument of the annotation and Args contains the named arguments.
+// Arguments without a value, such as required in @QUERY("q", required), have the value "true".
 type Annotation struct {
 	Key   string
 	Value string
+	Args  map[string]string
+}
+
+// Pagination describes how the next page of a paginated response is requested.
+// The next page is either selected by a cursor returned in the response or by a page number.
+type Pagination struct {
+	// Param is the query parameter which selects the page
+	Param string
+	// Cursor is the response field containing the cursor of the next page
+	Cursor string
+	// Pages is the response field containing the total number of pages
+	Pages string
+}
+
+// Hedge describes when identical requests are sent to cut the latency of slow responses.
+type Hedge struct {
+	// After is the duration to wait for a response before sending the next request, such as 150ms
+	After string
+	// Max is the maximum number of requests sent
+	Max int
+}
+
+// Poll describes how the long running operation started by a request is polled until it reaches a
+// terminal state.
+type Poll struct {
+	// ResponseType is the type the final state of the operation is decoded in to
+	ResponseType string
+	// StatusField is the response field containing the state of the operation, such as status
+	StatusField string
+	// Until are the terminal states of the operation
+	Until []string
+	// Interval is the duration to wait before the first poll, such as 2s
+	Interval string
+	// Timeout is the duration after which polling gives up, or empty to poll until the context is done
+	Timeout string
+	// URL is the response field containing the URL of the operation. The Location header of the
+	// response or the URL of the request is polled when empty.
+	URL string
+}
+
+// GraphQL describes the operation sent by a @GRAPHQL request builder.
+// The operation is either a query document or the name of a query persisted by the server.
+type GraphQL struct {
+	// Query is the name of the constant containing the query document
+	Query string
+	// Persisted is the name of the persisted query
+	Persisted string
+}
+
+// WebSocket describes the messages of the connection opened by a @WS request builder.
+type WebSocket struct {
+	// Send is the type of the messages sent
+	Send string
+	// Receive is the type of the messages received
+	Receive string
 }
 
 type annotationFilter func(key string) bool
@@ -60,18 +180,45 @@ type empty struct{}
 type ParseResult struct {
 	PackageName         string
 	RequestType         string
+	Doc                 *ast.CommentGroup
 	ApiEndpoint         string
 	HttpMethod          string
 	PathSubstitutions   map[string]*ast.Field
 	QueryParams         map[string]*ast.Field
+	QueryStructParams   map[string]*ast.Field
 	PostFormParams      map[string]*ast.Field
 	PostMultiPartParams map[string]*ast.Field
 	PostParams          map[string]*ast.Field
 	HeaderParams        map[string]*ast.Field
+	Variables           map[string]*ast.Field
 	SyncResponse        *ast.Field
 	AsyncResponse       *ast.Field
+	PaginatedResponse   *ast.Field
+	Progress            *ast.Field
+	Download            *ast.Field
+	BodyStream          *ast.Field
+	Connect             *ast.Field
+	PollResponse        *ast.Field
+	Pagination          *Pagination
+	Poll                *Poll
 	CallbackType        string
 	ResponseType        string
+	Imports             map[string]string
+	Examples            []map[string]string
+	ExampleTypes        map[string]string
+	Dictionary          string
+	IdempotencyHeader   string
+	Hedge               *Hedge
+	AllowedStatus       []int
+	Accept              string
+	GraphQL             *GraphQL
+	WebSocket           *WebSocket
+	MaxBody             int64
+	AllowBody           bool
+	Version             string
+	// Decoder is the function decoding the body of the response in to the response type, instead
+	// of the constructor of the response type
+	Decoder string
 }
 
 func newParseResult(pkg string) *ParseResult {
@@ -79,18 +226,38 @@ func newParseResult(pkg string) *ParseResult {
 		PackageName:         pkg,
 		PathSubstitutions:   make(map[string]*ast.Field),
 		QueryParams:         make(map[string]*ast.Field),
+		QueryStructParams:   make(map[string]*ast.Field),
 		PostFormParams:      make(map[string]*ast.Field),
 		PostMultiPartParams: make(map[string]*ast.Field),
 		PostParams:          make(map[string]*ast.Field),
 		HeaderParams:        make(map[string]*ast.Field),
+		Variables:           make(map[string]*ast.Field),
+		Imports:             make(map[string]string),
+		ExampleTypes:        make(map[string]string),
 	}
 }
 
 type Parser struct {
+	// Strict reports annotations which are not known to gorest as errors, which catches
+	// misspelled annotations that would otherwise be ignored
+	Strict bool
+	// PackageFiles are the files of the package of the file, in which the interfaces embedded by
+	// the request builders of the file are looked up along with the file itself
+	PackageFiles []*ast.File
+
+	fset         *token.FileSet
 	file         *ast.File
 	info         *types.Info
+	pkg          string
 	result       *ParseResult
+	parsed       []*ParseResult
+	results      []*ParseResult
 	buildRequest bool
+	httpKey      string
+	httpPos      token.Pos
+	declared     map[string]string
+	doc          *ast.CommentGroup
+	errors       scanner.ErrorList
 }
 
 func NewParser(file *ast.File, pkg string) *Parser {
@@ -101,15 +268,57 @@ func NewParser(file *ast.File, pkg string) *Parser {
 	}
 
 	return &Parser{
-		file:   file,
-		info:   info,
-		result: newParseResult(pkg),
+		Strict:   true,
+		file:     file,
+		declared: make(map[string]string),
+		info:     info,
+		pkg:      pkg,
 	}
 }
 
+// NewTypedParser returns a Parser which resolves the packages of referenced types
+// using the type information of the package containing file, such as the one
+// produced by golang.org/x/tools/go/packages.
+// Errors are reported with positions relative to fset, which must be the file set file was parsed with.
+func NewTypedParser(fset *token.FileSet, file *ast.File, pkg string, info *types.Info) *Parser {
+	p := NewParser(file, pkg)
+	p.fset = fset
+	if info != nil {
+		p.info = info
+	}
+	return p
+}
+
+// Parse parses the first request builder of the file. Errors found while parsing are reported by Err.
 func (p *Parser) Parse() *ParseResult {
-	ast.Walk(p, p.file)
-	return p.result
+	results := p.ParseAll()
+	if len(results) == 0 {
+		return newParseResult(p.pkg)
+	}
+	return results[0]
+}
+
+// ParseAll parses every request builder of the file in the order they are declared.
+// Errors found while parsing are reported by Err.
+func (p *Parser) ParseAll() []*ParseResult {
+	if p.results == nil {
+		ast.Walk(p, p.file)
+		if p.buildRequest {
+			p.errorf(p.httpPos, "@%s must annotate a request builder interface", p.httpKey)
+		}
+
+		p.results = []*ParseResult{}
+		for _, r := range p.parsed {
+			if r.RequestType != "" {
+				p.resolveImports(r)
+				p.results = append(p.results, r)
+			}
+		}
+		if p.Strict {
+			p.checkUnknownAnnotations()
+		}
+	}
+	return p.results
 }
 
 func (p *Parser) Visit(node ast.Node) ast.Visitor {
@@ -123,6 +332,11 @@ func (p *Parser) Visit(node ast.Node) ast.Visitor {
 		// Reset builder flags
 		p.buildRequest = false
 		break
+	case *ast.GenDecl:
+		// Retain the declaration comment as the comment of a single type declaration
+		// belongs to the declaration rather than the type
+		p.doc = node.(*ast.GenDecl).Doc
+		break
 	case *ast.TypeSpec:
 		// Check if we are at the beginning of a request builder declaration
 		// or a response / callback declaration
@@ -132,8 +346,21 @@ func (p *Parser) Visit(node ast.Node) ast.Visitor {
 		case *ast.InterfaceType:
 			if p.buildRequest {
 				p.result.RequestType = typeSpec.Name.Name
+				p.declared = make(map[string]string)
+				doc := typeSpec.Doc
+				if doc == nil {
+					doc = p.doc
+				}
+				p.result.Doc = doc
+				p.checkInterfaceAnnotations(doc)
+				p.parseInterfaceAnnotations(doc)
 			}
 			break
+		default:
+			if p.buildRequest {
+				p.errorf(p.httpPos, "@%s must annotate a request builder interface, %s is not an interface", p.httpKey, typeSpec.Name.Name)
+				p.buildRequest = false
+			}
 		}
 		break
 	case *ast.InterfaceType:
@@ -146,41 +373,36 @@ func (p *Parser) Visit(node ast.Node) ast.Visitor {
 		// the query parameter and argument name and type information to implement
 		// the interface
 		ifc := node.(*ast.InterfaceType)
-		methods := ifc.Methods
-		for _, f := range methods.List {
-			annotation, valid := ExtractRequestAnnotation(f.Doc.List[0].Text)
-			if !valid {
-				continue
-			}
-			param := f.Names[0].Name
-
-			switch annotation.Key {
-			case field:
-				p.result.PostFormParams[param] = f
-			case header:
-				p.result.HeaderParams[param] = f
-			case part:
-				p.result.PostMultiPartParams[param] = f
-			case path:
-				p.result.PathSubstitutions[param] = f
-			case query:
-				p.result.QueryParams[param] = f
-			case sync:
-				p.result.SyncResponse = f
-				p.result.ResponseType = annotation.Value
-			case async:
-				p.result.AsyncResponse = f
-				p.result.CallbackType = annotation.Value
-			}
-		}
-		break
+		p.parseMethods(ifc.Methods, map[string]bool{})
+		p.checkPathSubstitutions()
+		p.checkExamples()
+		p.checkDecoder()
+		// Only the interface following the HTTP annotation is a request builder
+		p.buildRequest = false
+		// The annotations of the methods have been parsed, including any misplaced HTTP annotation
+		return nil
 	case *ast.Comment:
 		comment := node.(*ast.Comment)
-		if annotation, valid := ExtractHttpAnnotation(comment.Text); valid {
+		if annotation, valid := ExtractHttpAnnotation(comment.Text); valid && !p.buildRequest {
 			// Extract the HTTP Method and API from the Interface declaration
+			// A conflicting second HTTP annotation is reported by checkInterfaceAnnotations
+			p.result = newParseResult(p.pkg)
+			p.parsed = append(p.parsed, p.result)
 			p.buildRequest = true
+			p.httpKey = annotation.Key
+			p.httpPos = comment.Slash + token.Pos(strings.Index(comment.Text, "@"+annotation.Key))
 			p.result.HttpMethod = annotation.Key
 			p.result.ApiEndpoint = annotation.Value
+			if annotation.Key == graphql {
+				// The operation is sent to the endpoint, such as @GRAPHQL("POST /graphql", query="GetUserQuery")
+				p.result.HttpMethod, p.result.ApiEndpoint = splitGraphQLEndpoint(annotation.Value)
+				p.result.GraphQL = &GraphQL{Query: annotation.Args["query"], Persisted: annotation.Args["persisted"]}
+			}
+			if annotation.Key == websocket {
+				// The connection is opened by upgrading a GET request
+				p.result.HttpMethod = httpMethodGet
+				p.result.WebSocket = &WebSocket{}
+			}
 		}
 		break
 	}
@@ -188,16 +410,222 @@ func (p *Parser) Visit(node ast.Node) ast.Visitor {
 	return p
 }
 
+// parseInterfaceAnnotations extracts the annotations of the request builder interface
+// other than the HTTP annotation.
+func (p *Parser) parseInterfaceAnnotations(doc *ast.CommentGroup) {
+	if doc == nil {
+		return
+	}
+	for _, comment := range doc.List {
+		annotation, valid := ExtractInterfaceAnnotation(comment.Text)
+		if !valid {
+			continue
+		}
+
+		switch annotation.Key {
+		case example:
+			p.result.Examples = append(p.result.Examples, annotation.Args)
+		case dictionary:
+			p.result.Dictionary = annotation.Value
+		case accept:
+			p.result.Accept = annotation.Value
+		case maxBody:
+			p.result.MaxBody, _ = parseSize(annotation.Value)
+		case allowBody:
+			p.result.AllowBody = true
+		case version:
+			p.result.Version = annotation.Value
+		case decoder:
+			p.result.Decoder = annotation.Value
+		case allowStatus:
+			for _, code := range strings.Split(annotation.Value, ",") {
+				if status, err := strconv.Atoi(strings.TrimSpace(code)); err == nil {
+					p.result.AllowedStatus = append(p.result.AllowedStatus, status)
+				}
+			}
+		case hedge:
+			p.result.Hedge = &Hedge{After: annotation.Args["after"], Max: 2}
+			if max, err := strconv.Atoi(annotation.Args["max"]); err == nil {
+				p.result.Hedge.Max = max
+			}
+		case idempotent:
+			p.result.IdempotencyHeader = annotation.Value
+			if p.result.IdempotencyHeader == "" {
+				p.result.IdempotencyHeader = idempotencyHeader
+			}
+		}
+	}
+}
+
+// parseMethods maps the annotated methods of an interface to the request details.
+// Methods of embedded interfaces declared in the same package are included as well, which allows
+// common parameters to be declared once and shared by many request builders.
+func (p *Parser) parseMethods(methods *ast.FieldList, embedded map[string]bool) {
+	for _, f := range methods.List {
+		if len(f.Names) == 0 {
+			// An embedded interface
+			ident, ok := f.Type.(*ast.Ident)
+			if !ok || embedded[ident.Name] {
+				continue
+			}
+			embedded[ident.Name] = true
+			if spec := p.lookupType(ident.Name); spec != nil {
+				if ifc, ok := spec.Type.(*ast.InterfaceType); ok {
+					p.parseMethods(ifc.Methods, embedded)
+				}
+			}
+			continue
+		}
+
+		if f.Doc == nil {
+			continue
+		}
+		p.checkMethodAnnotations(f)
+		annotation, valid := ExtractRequestAnnotation(f.Doc.Text())
+		if !valid {
+			continue
+		}
+		param := f.Names[0].Name
+
+		switch annotation.Key {
+		case body:
+			p.result.PostParams[param] = f
+		case field:
+			p.result.PostFormParams[param] = f
+		case header:
+			p.result.HeaderParams[param] = f
+		case part:
+			p.result.PostMultiPartParams[param] = f
+		case path:
+			p.result.PathSubstitutions[param] = f
+		case query:
+			p.result.QueryParams[param] = f
+		case queryStruct:
+			p.result.QueryStructParams[param] = f
+		case variable:
+			p.result.Variables[param] = f
+		case sync:
+			p.result.SyncResponse = f
+			p.result.ResponseType = annotation.Value
+		case async:
+			p.result.AsyncResponse = f
+			p.result.CallbackType = annotation.Value
+		case progress:
+			p.result.Progress = f
+		case download:
+			p.result.Download = f
+		case bodyStream:
+			p.result.BodyStream = f
+		case poll:
+			p.result.PollResponse = f
+			p.result.Poll = &Poll{
+				StatusField: annotation.Args["statusField"],
+				Interval:    annotation.Args["interval"],
+				Timeout:     annotation.Args["timeout"],
+				URL:         annotation.Args["url"],
+			}
+			for _, state := range strings.Split(annotation.Args["until"], ",") {
+				p.result.Poll.Until = append(p.result.Poll.Until, strings.TrimSpace(state))
+			}
+			if p.result.Poll.Interval == "" {
+				p.result.Poll.Interval = "1s"
+			}
+			if function, ok := f.Type.(*ast.FuncType); ok && function.Results != nil && len(function.Results.List) > 0 {
+				p.result.Poll.ResponseType = types.ExprString(function.Results.List[0].Type)
+			}
+		case connect:
+			p.result.Connect = f
+			if p.result.WebSocket != nil {
+				p.result.WebSocket.Send = annotation.Args["send"]
+				p.result.WebSocket.Receive = annotation.Args["receive"]
+			}
+		case paginated:
+			p.result.PaginatedResponse = f
+			p.result.Pagination = &Pagination{
+				Param:  annotation.Args["param"],
+				Cursor: annotation.Args["cursor"],
+				Pages:  annotation.Args["pages"],
+			}
+			if page, ok := annotation.Args["page"]; ok {
+				p.result.Pagination.Param = page
+			}
+		}
+	}
+}
+
+// lookupType returns the type declared with the given name in the file or in the other files of
+// its package.
+func (p *Parser) lookupType(name string) *ast.TypeSpec {
+	for _, file := range append([]*ast.File{p.file}, p.PackageFiles...) {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				if typeSpec, ok := spec.(*ast.TypeSpec); ok && typeSpec.Name.Name == name {
+					return typeSpec
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// splitGraphQLEndpoint splits the argument of @GRAPHQL in to the HTTP method and the endpoint.
+// The method is optional and defaults to POST, for example "GET /graphql" or "/graphql".
+func splitGraphQLEndpoint(s string) (string, string) {
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 1:
+		return httpMethodPost, fields[0]
+	case 2:
+		return strings.ToUpper(fields[0]), fields[1]
+	}
+	return "", s
+}
+
+// parseSize parses a size in bytes with an optional unit of B, KB, MB or GB, where a KB is 1024 bytes.
+// Example: 10MB -> 10485760
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix     string
+		multiplier int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"B", 1}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, multiplier = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix)), unit.multiplier
+			break
+		}
+	}
+	size, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return size * multiplier, nil
+}
+
 func httpAnnotationFilter(s string) bool {
 	_, ok := httpMethods[s]
 	return ok
 }
 
+func interfaceAnnotationFilter(s string) bool {
+	_, ok := interfaceAnnotationTypes[s]
+	return ok
+}
+
 func requestAnnotationFilter(s string) bool {
 	_, ok := annotationTypes[s]
 	return ok
 }
 
+func modifierAnnotationFilter(s string) bool {
+	_, ok := modifierAnnotationTypes[s]
+	return ok
+}
+
 func ExtractHttpAnnotation(s string) (Annotation, bool) {
 	annotation, valid := extractAnnotation(httpAnnotationFilter, s)
 	if annotation.Key == httpMethodPostForm {
@@ -206,20 +634,135 @@ func ExtractHttpAnnotation(s string) (Annotation, bool) {
 	return annotation, valid
 }
 
+func ExtractInterfaceAnnotation(s string) (Annotation, bool) {
+	return extractAnnotation(interfaceAnnotationFilter, s)
+}
+
 func ExtractRequestAnnotation(s string) (Annotation, bool) {
 	return extractAnnotation(requestAnnotationFilter, s)
 }
 
+// ExtractAnnotation extracts the annotation with the given key, such as FORMAT for @FORMAT("unix").
+func ExtractAnnotation(key string, s string) (Annotation, bool) {
+	return extractAnnotation(func(s string) bool { return s == key }, s)
+}
+
+// extractAnnotation extracts the first annotation accepted by the filter.
+// A comment may contain several annotations, for example @QUERY("since") followed by @FORMAT("unix").
 func extractAnnotation(filter annotationFilter, s string) (Annotation, bool) {
-	annotation := Annotation{}
-	valid := false
-	match := re.FindStringSubmatch(s)
-	if len(match) == 3 && filter(match[1]) {
-		annotation = Annotation{
-			Key:   match[1],
-			Value: match[2],
+	for _, match := range re.FindAllStringSubmatchIndex(s, -1) {
+		if !filter(s[match[2]:match[3]]) {
+			continue
+		}
+		value, args, valid := parseArguments(s[match[1]:])
+		if !valid {
+			return Annotation{}, false
 		}
-		valid = true
+		return Annotation{
+			Key:   s[match[2]:match[3]],
+			Value: value,
+			Args:  args,
+		}, true
 	}
-	return annotation, valid
+	return Annotation{}, false
+}
+
+// parseArguments parses the comma separated arguments of an annotation up to the closing parenthesis.
+// The first argument may be a quoted string which is returned as the value of the annotation.
+// All other arguments are named (key="value" or key=value) or flags (key).
+func parseArguments(s string) (string, map[string]string, bool) {
+	value, args, _, valid := parseArgumentList(s)
+	return value, args, valid
+}
+
+// parseArgumentList parses the arguments of an annotation like parseArguments and additionally
+// returns the remainder of s following the closing parenthesis.
+func parseArgumentList(s string) (string, map[string]string, string, bool) {
+	var value string
+	var args map[string]string
+	for i := 0; ; i++ {
+		s = strings.TrimLeft(s, " \t")
+		if i == 0 && strings.HasPrefix(s, ")") {
+			return value, args, s[1:], true
+		}
+
+		if strings.HasPrefix(s, "\"") {
+			if i > 0 {
+				return "", nil, "", false
+			}
+			quoted, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return "", nil, "", false
+			}
+			value, _ = strconv.Unquote(quoted)
+			s = s[len(quoted):]
+		} else {
+			key := s[:strings.IndexFunc(s+")", isNotIdentifier)]
+			if key == "" {
+				return "", nil, "", false
+			}
+			s = strings.TrimLeft(s[len(key):], " \t")
+			arg := "true"
+			if strings.HasPrefix(s, "=") {
+				s = strings.TrimLeft(s[1:], " \t")
+				if strings.HasPrefix(s, "\"") {
+					quoted, err := strconv.QuotedPrefix(s)
+					if err != nil {
+						return "", nil, "", false
+					}
+					arg, _ = strconv.Unquote(quoted)
+					s = s[len(quoted):]
+				} else {
+					arg = s[:strings.IndexAny(s+")", ", \t)")]
+					if arg == "" {
+						return "", nil, "", false
+					}
+					s = s[len(arg):]
+				}
+			}
+			if args == nil {
+				args = make(map[string]string)
+			}
+			args[key] = arg
+		}
+
+		s = strings.TrimLeft(s, " \t")
+		switch {
+		case strings.HasPrefix(s, ")"):
+			return value, args, s[1:], true
+		case strings.HasPrefix(s, ","):
+			s = s[1:]
+		default:
+			return "", nil, "", false
+		}
+	}
+}
+
+// StripAnnotations removes the annotations from the text of a doc comment.
+// Lines which only contain annotations are removed, as are leading and trailing blank lines.
+func StripAnnotations(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		stripped := line
+		for {
+			match := re.FindStringSubmatchIndex(stripped)
+			if match == nil {
+				break
+			}
+			_, _, rest, valid := parseArgumentList(stripped[match[1]:])
+			if !valid {
+				break
+			}
+			stripped = strings.TrimRight(stripped[:match[0]], " \t") + rest
+		}
+		if stripped != line && strings.TrimSpace(stripped) == "" {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(stripped, " \t"))
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+func isNotIdentifier(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
 }
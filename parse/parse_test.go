@@ -2,8 +2,11 @@ package parse
 
 import (
 	"go/ast"
+	"go/importer"
 	"go/parser"
+	"go/scanner"
 	"go/token"
+	"go/types"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -24,42 +27,42 @@ func TestExtractHttpAnnotation(t *testing.T) {
 		{
 			"@DELETE(\"/test\")",
 			result{
-				Annotation{"DELETE", "/test"},
+				Annotation{"DELETE", "/test", nil},
 				true,
 			},
 		},
 		{
 			"@GET(\"/test\")",
 			result{
-				Annotation{"GET", "/test"},
+				Annotation{"GET", "/test", nil},
 				true,
 			},
 		},
 		{
 			"@HEAD(\"/test\")",
 			result{
-				Annotation{"HEAD", "/test"},
+				Annotation{"HEAD", "/test", nil},
 				true,
 			},
 		},
 		{
 			"@POST(\"/test\")",
 			result{
-				Annotation{"POST", "/test"},
+				Annotation{"POST", "/test", nil},
 				true,
 			},
 		},
 		{
 			"@POST_FORM(\"/test\")",
 			result{
-				Annotation{"POST", "/test"},
+				Annotation{"POST", "/test", nil},
 				true,
 			},
 		},
 		{
 			"@PUT(\"/test\")",
 			result{
-				Annotation{"PUT", "/test"},
+				Annotation{"PUT", "/test", nil},
 				true,
 			},
 		},
@@ -101,7 +104,7 @@ func TestExtractHttpAnnotation(t *testing.T) {
 		{
 			"@GET(\"\")",
 			result{
-				Annotation{"GET", ""},
+				Annotation{"GET", "", nil},
 				true,
 			},
 		},
@@ -129,49 +132,49 @@ func TestExtractRequestAnnotation(t *testing.T) {
 		{
 			"@FIELD(\"test_1\")",
 			result{
-				Annotation{"FIELD", "test_1"},
+				Annotation{"FIELD", "test_1", nil},
 				true,
 			},
 		},
 		{
 			"@HEADER(\"test_2\")",
 			result{
-				Annotation{"HEADER", "test_2"},
+				Annotation{"HEADER", "test_2", nil},
 				true,
 			},
 		},
 		{
 			"@PART(\"test_3\")",
 			result{
-				Annotation{"PART", "test_3"},
+				Annotation{"PART", "test_3", nil},
 				true,
 			},
 		},
 		{
 			"@PATH(\"test_4\")",
 			result{
-				Annotation{"PATH", "test_4"},
+				Annotation{"PATH", "test_4", nil},
 				true,
 			},
 		},
 		{
 			"@QUERY(\"test_5\")",
 			result{
-				Annotation{"QUERY", "test_5"},
+				Annotation{"QUERY", "test_5", nil},
 				true,
 			},
 		},
 		{
 			"@SYNC(\"test_6\")",
 			result{
-				Annotation{"SYNC", "test_6"},
+				Annotation{"SYNC", "test_6", nil},
 				true,
 			},
 		},
 		{
 			"@ASYNC(\"test_7\")",
 			result{
-				Annotation{"ASYNC", "test_7"},
+				Annotation{"ASYNC", "test_7", nil},
 				true,
 			},
 		},
@@ -182,6 +185,55 @@ func TestExtractRequestAnnotation(t *testing.T) {
 				false,
 			},
 		},
+		{
+			"@PAGINATED(cursor=\"next_cursor\", param=\"cursor\")",
+			result{
+				Annotation{"PAGINATED", "", map[string]string{"cursor": "next_cursor", "param": "cursor"}},
+				true,
+			},
+		},
+		{
+			"@QUERY(\"q\", required, max=2) trailing comment (ignored)",
+			result{
+				Annotation{"QUERY", "q", map[string]string{"required": "true", "max": "2"}},
+				true,
+			},
+		},
+		{
+			"@QUERY(\"a,b)\")",
+			result{
+				Annotation{"QUERY", "a,b)", nil},
+				true,
+			},
+		},
+		{
+			"@QUERY(\"q\",)",
+			result{
+				nilAnnotaiton,
+				false,
+			},
+		},
+		{
+			"@QUERY(required, \"q\")",
+			result{
+				nilAnnotaiton,
+				false,
+			},
+		},
+		{
+			"@QUERY(\"q\"",
+			result{
+				nilAnnotaiton,
+				false,
+			},
+		},
+		{
+			"@FORMAT(\"unix\") @QUERY(\"since\")",
+			result{
+				Annotation{"QUERY", "since", nil},
+				true,
+			},
+		},
 		{
 			"@field(\"invalid\")",
 			result{
@@ -375,3 +427,957 @@ func TestParseValidCases(t *testing.T) {
 	actualResult := p.Parse()
 	assert.ObjectsAreEqualValues(expectedResult, actualResult)
 }
+
+func TestParseImports(t *testing.T) {
+	src := `
+		package test
+
+		import (
+			"time"
+
+			m "example.com/api/models"
+		)
+
+		// @GET("/photos")
+		type GetPhotosRequestBuilder interface {
+			// @QUERY("since")
+			Since(t time.Time) GetPhotosRequestBuilder
+
+			// @HEADER("x-filter")
+			Filter(f *m.Filter) GetPhotosRequestBuilder
+
+			// @SYNC("m.PhotosResponse")
+			Run() (m.PhotosResponse, error)
+		}
+		`
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	result := NewParser(f, "test").Parse()
+	assert.Equal(t, map[string]string{
+		"time":                   "",
+		"example.com/api/models": "m",
+	}, result.Imports)
+}
+
+func TestParseTypedImports(t *testing.T) {
+	src := `
+		package test
+
+		import clock "time"
+
+		// @GET("/photos")
+		type GetPhotosRequestBuilder interface {
+			// @QUERY("since")
+			Since(t clock.Time) GetPhotosRequestBuilder
+		}
+		`
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	info := &types.Info{
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{Importer: importer.Default()}
+	_, err = conf.Check("test", fset, []*ast.File{f}, info)
+	assert.NoError(t, err)
+
+	result := NewTypedParser(fset, f, "test", info).Parse()
+	assert.Equal(t, map[string]string{"time": "clock"}, result.Imports)
+}
+
+func TestParseEmbeddedInterfaces(t *testing.T) {
+	src := `
+		package test
+
+		type Paginated interface {
+			// @QUERY("page")
+			Page(page int) Paginated
+
+			// @QUERY("per_page")
+			PerPage(count int) Paginated
+		}
+
+		type Authenticated interface {
+			Paginated
+
+			// @HEADER("Authorization")
+			Token(token string) Authenticated
+		}
+
+		// @GET("/photos")
+		type GetPhotosRequestBuilder interface {
+			Authenticated
+			Paginated
+
+			// @QUERY("feature")
+			Feature(feature string) GetPhotosRequestBuilder
+		}
+
+		type Unrelated interface {
+			// @QUERY("unrelated")
+			Unrelated(u string) Unrelated
+		}
+		`
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	result := NewParser(f, "test").Parse()
+	assert.Equal(t, "GetPhotosRequestBuilder", result.RequestType)
+	assert.Len(t, result.QueryParams, 3)
+	assert.Contains(t, result.QueryParams, "Page")
+	assert.Contains(t, result.QueryParams, "PerPage")
+	assert.Contains(t, result.QueryParams, "Feature")
+	assert.Len(t, result.HeaderParams, 1)
+	assert.Contains(t, result.HeaderParams, "Token")
+}
+
+func TestParseEmbeddedInterfacesOfPackage(t *testing.T) {
+	common := `
+		package test
+
+		type Paginated interface {
+			// @QUERY("page")
+			Page(page int) Paginated
+		}
+		`
+	src := `
+		package test
+
+		// @GET("/photos")
+		type GetPhotosRequestBuilder interface {
+			Paginated
+
+			// @QUERY("feature")
+			Feature(feature string) GetPhotosRequestBuilder
+		}
+		`
+
+	fset := token.NewFileSet()
+	commonFile, err := parser.ParseFile(fset, "common.go", common, parser.ParseComments)
+	assert.NoError(t, err)
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	p := NewParser(f, "test")
+	p.PackageFiles = []*ast.File{commonFile, f}
+	result := p.Parse()
+	assert.NoError(t, p.Err())
+	assert.Len(t, result.QueryParams, 2)
+	assert.Contains(t, result.QueryParams, "Page")
+	assert.Contains(t, result.QueryParams, "Feature")
+}
+
+func TestParsePagination(t *testing.T) {
+	var testCases = []struct {
+		annotation string
+		output     Pagination
+	}{
+		{
+			`@PAGINATED(cursor="next_cursor", param="cursor")`,
+			Pagination{Param: "cursor", Cursor: "next_cursor"},
+		},
+		{
+			`@PAGINATED(page="page", pages="meta.total_pages")`,
+			Pagination{Param: "page", Pages: "meta.total_pages"},
+		},
+	}
+
+	for _, tc := range testCases {
+		src := `
+			package test
+			// @GET("/photos")
+			type GetPhotosRequestBuilder interface {
+				// ` + tc.annotation + `
+				Iterate(ctx context.Context, fn func(page GetPhotosResponse) bool) error
+			}
+			`
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+		assert.NoError(t, err)
+
+		result := NewParser(f, "test").Parse()
+		assert.NotNil(t, result.PaginatedResponse)
+		assert.Equal(t, &tc.output, result.Pagination)
+	}
+}
+
+func TestParseExamples(t *testing.T) {
+	src := `
+		package test
+		// GetPhotoDetailsRequestBuilder requests the details of a photo.
+		// @GET("/photos/{id}")
+		// @EXAMPLE(photoID="123", imageSize=3)
+		// @EXAMPLE(photoID="abc")
+		type GetPhotoDetailsRequestBuilder interface {
+			// @PATH("id")
+			PhotoID(id string) GetPhotoDetailsRequestBuilder
+
+			// @QUERY("image_size")
+			ImageSize(size int) GetPhotoDetailsRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	result := NewParser(f, "test").Parse()
+	assert.Equal(t, []map[string]string{
+		{"photoID": "123", "imageSize": "3"},
+		{"photoID": "abc"},
+	}, result.Examples)
+}
+
+func TestParseQueryStruct(t *testing.T) {
+	src := `
+		package test
+		// @GET("/photos")
+		type GetPhotosRequestBuilder interface {
+			// @QUERYSTRUCT()
+			Filter(filter models.PhotoFilter) GetPhotosRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	result := NewParser(f, "test").Parse()
+	assert.Contains(t, result.QueryStructParams, "Filter")
+	assert.Empty(t, result.QueryParams)
+}
+
+func TestParseGraphQL(t *testing.T) {
+	var testCases = []struct {
+		annotation string
+		method     string
+		endpoint   string
+		output     GraphQL
+	}{
+		{`@GRAPHQL("POST /graphql", query="GetUserQuery")`, "POST", "/graphql", GraphQL{Query: "GetUserQuery"}},
+		{`@GRAPHQL("GET /graphql", persisted="GetUser")`, "GET", "/graphql", GraphQL{Persisted: "GetUser"}},
+		{`@GRAPHQL("/graphql", query="GetUserQuery")`, "POST", "/graphql", GraphQL{Query: "GetUserQuery"}},
+	}
+
+	for _, tc := range testCases {
+		src := `
+			package test
+			// ` + tc.annotation + `
+			type GetUserRequestBuilder interface {
+				// @VAR("login")
+				Login(login string) GetUserRequestBuilder
+
+				// @SYNC("GetUserResponse")
+				Run() (GetUserResponse, error)
+			}
+			`
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+		assert.NoError(t, err)
+
+		p := NewParser(f, "test")
+		result := p.Parse()
+		assert.NoError(t, p.Err())
+		assert.Equal(t, tc.method, result.HttpMethod)
+		assert.Equal(t, tc.endpoint, result.ApiEndpoint)
+		assert.Equal(t, &tc.output, result.GraphQL)
+		assert.Contains(t, result.Variables, "Login")
+	}
+}
+
+func TestParsePoll(t *testing.T) {
+	src := `
+		package test
+		// @POST("/instances")
+		type CreateInstanceRequestBuilder interface {
+			// @POLL(statusField="status", until="done, failed", interval="2s", timeout="5m")
+			RunAndWait(ctx context.Context) (models.Operation, error)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	p := NewParser(f, "test")
+	result := p.Parse()
+	assert.NoError(t, p.Err())
+	assert.NotNil(t, result.PollResponse)
+	assert.Equal(t, &Poll{
+		ResponseType: "models.Operation",
+		StatusField:  "status",
+		Until:        []string{"done", "failed"},
+		Interval:     "2s",
+		Timeout:      "5m",
+	}, result.Poll)
+}
+
+func TestParseBody(t *testing.T) {
+	src := `package test
+		// @DELETE("/{index}/_query")
+		// @ALLOW_BODY()
+		type DeleteByQueryRequestBuilder interface {
+			// @PATH("index")
+			Index(index string) DeleteByQueryRequestBuilder
+
+			// @BODY("query")
+			Query(query Query) DeleteByQueryRequestBuilder
+		}`
+	f, err := parser.ParseFile(token.NewFileSet(), "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	p := NewParser(f, "test")
+	result := p.Parse()
+	assert.NoError(t, p.Err())
+	assert.True(t, result.AllowBody)
+	assert.Contains(t, result.PostParams, "Query")
+}
+
+func TestParseVersion(t *testing.T) {
+	src := `package test
+		// @GET("/charges")
+		// @VERSION("2024-01-01")
+		type ListChargesRequestBuilder interface {
+		}`
+	f, err := parser.ParseFile(token.NewFileSet(), "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	p := NewParser(f, "test")
+	result := p.Parse()
+	assert.NoError(t, p.Err())
+	assert.Equal(t, "2024-01-01", result.Version)
+}
+
+func TestParseDecoder(t *testing.T) {
+	src := `package test
+		import "example.com/feeds"
+
+		// @GET("/photos/feed")
+		// @DECODER("feeds.ParsePhotoFeed")
+		type GetPhotoFeedRequestBuilder interface {
+			// @SYNC("PhotoFeed")
+			Run() (PhotoFeed, error)
+		}`
+	f, err := parser.ParseFile(token.NewFileSet(), "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	p := NewParser(f, "test")
+	result := p.Parse()
+	assert.NoError(t, p.Err())
+	assert.Equal(t, "feeds.ParsePhotoFeed", result.Decoder)
+	assert.Equal(t, map[string]string{"example.com/feeds": ""}, result.Imports)
+}
+
+func TestParseSize(t *testing.T) {
+	var testCases = []struct {
+		input  string
+		output int64
+	}{
+		{"512", 512},
+		{"100B", 100},
+		{"64KB", 64 << 10},
+		{"10MB", 10 << 20},
+		{"2gb", 2 << 30},
+	}
+	for _, tc := range testCases {
+		size, err := parseSize(tc.input)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.output, size)
+	}
+	_, err := parseSize("10 megabytes")
+	assert.Error(t, err)
+}
+
+func TestParseWebSocket(t *testing.T) {
+	src := `
+		package test
+		// @WS("/v1/stream")
+		type StreamRequestBuilder interface {
+			// @QUERY("channel")
+			Channel(channel string) StreamRequestBuilder
+
+			// @CONNECT(send="Subscription", receive="models.Event")
+			Connect(ctx context.Context) (*restclient.WebSocket[Subscription, models.Event], error)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	p := NewParser(f, "test")
+	result := p.Parse()
+	assert.NoError(t, p.Err())
+	assert.Equal(t, "GET", result.HttpMethod)
+	assert.Equal(t, "/v1/stream", result.ApiEndpoint)
+	assert.Equal(t, &WebSocket{Send: "Subscription", Receive: "models.Event"}, result.WebSocket)
+	assert.NotNil(t, result.Connect)
+}
+
+func TestParseErrors(t *testing.T) {
+	var testCases = []struct {
+		src    string
+		errors []string
+	}{
+		{
+			`
+			// @GET("/photos/{id}")
+			type GetPhotoRequestBuilder interface {
+				// @PATH("id")
+				PhotoID(id string) GetPhotoRequestBuilder
+			}`,
+			nil,
+		},
+		{
+			`
+			// @GET("/photos")
+			// @DEPRECATED("use ListPhotosRequestBuilder")
+			type GetPhotosRequestBuilder interface {
+				// @QUERY("page") @DEPRECATED()
+				Page(page int) GetPhotosRequestBuilder
+			}`,
+			nil,
+		},
+		{
+			`
+			// @GET("/photos/{id}")
+			type GetPhotoRequestBuilder interface {
+				// @QUERY()
+				Page(page int) GetPhotoRequestBuilder
+
+				// @PATH("photo_id")
+				PhotoID(id string) GetPhotoRequestBuilder
+
+				// @HEADER("x-token" 
+				Token(token string) GetPhotoRequestBuilder
+			}`,
+			[]string{
+				`input.go:3:7: Endpoint /photos/{id} has no @PATH method for {id}`,
+				`input.go:5:8: @QUERY requires a name argument`,
+				`input.go:8:8: @PATH("photo_id") does not match a {photo_id} segment of endpoint /photos/{id}`,
+				`input.go:11:8: @HEADER has malformed arguments`,
+			},
+		},
+		{
+			`
+			// @GET("/photos")
+			// @QUERY("page")
+			type GetPhotosRequestBuilder interface {
+				// @POST("/photos")
+				Run() (GetPhotosResponse, error)
+
+				// @SYNC("GetPhotosResponse")
+				Page(page int) GetPhotosRequestBuilder
+			}`,
+			[]string{
+				`input.go:4:7: @QUERY must annotate a method of the request builder`,
+				`input.go:6:8: @POST must annotate the request builder interface`,
+				`input.go:9:8: @SYNC method Page must have no parameters or a context.Context parameter`,
+				`input.go:9:8: @SYNC method Page must return the response and an error`,
+			},
+		},
+		{
+			`
+			// @GET("/photos")
+			type GetPhotosRequest struct {
+			}`,
+			[]string{
+				`input.go:3:7: @GET must annotate a request builder interface, GetPhotosRequest is not an interface`,
+			},
+		},
+		{
+			`
+			// @GET()
+			type GetPhotosRequestBuilder interface {
+				// @PAGINATED(cursor="next")
+				Iterate(ctx context.Context, fn func(page GetPhotosResponse) bool) error
+			}`,
+			[]string{
+				`input.go:3:7: @GET requires an endpoint argument`,
+				`input.go:5:8: @PAGINATED requires either cursor and param arguments or page and pages arguments`,
+			},
+		},
+		{
+			`
+			// @POST("/photos")
+			// @HEDGE(after="soon", max=1)
+			type CreatePhotoRequestBuilder interface {
+			}`,
+			[]string{
+				`input.go:4:7: @HEDGE max must be a number of requests of at least 2`,
+				`input.go:4:7: @HEDGE requires a GET request, which is safe to send more than once`,
+				`input.go:4:7: @HEDGE requires an after argument with a duration, for example @HEDGE(after="150ms")`,
+			},
+		},
+		{
+			`
+			// @POST("/photos")
+			type CreatePhotoRequestBuilder interface {
+				// @PROGRESS()
+				OnProgress(sent, total int64) CreatePhotoRequestBuilder
+			}`,
+			[]string{
+				`input.go:5:8: @PROGRESS method OnProgress must have a func(sent, total int64) parameter and return the request builder`,
+			},
+		},
+		{
+			`
+			// @GET("/photos")
+			// @ACCEPT()
+			type GetPhotosRequestBuilder interface {
+			}`,
+			[]string{
+				`input.go:4:7: @ACCEPT requires a media type, for example @ACCEPT("application/json")`,
+			},
+		},
+		{
+			`
+			// @GET("/photos")
+			// @ALLOW_STATUS("404, not found")
+			type GetPhotosRequestBuilder interface {
+			}`,
+			[]string{
+				`input.go:4:7: @ALLOW_STATUS requires a comma separated list of status codes, for example @ALLOW_STATUS("404")`,
+			},
+		},
+		{
+			`
+			// @POST("/photos")
+			// @DICTIONARY("photos")
+			type CreatePhotoRequestBuilder interface {
+				// @BODY_STREAM("image/jpeg")
+				Content(r io.Reader, size int64) CreatePhotoRequestBuilder
+			}`,
+			[]string{
+				`input.go:6:8: @BODY_STREAM method Content cannot stream the body of a request compressed with @DICTIONARY`,
+			},
+		},
+		{
+			`
+			// @GET("/photos/{id}")
+			// @EXAMPLE(id="abc", page="first", filter="x", colour="red")
+			type GetPhotoRequestBuilder interface {
+				// @PATH("id")
+				ID(id string) GetPhotoRequestBuilder
+
+				// @QUERY("page")
+				Page(page int) GetPhotoRequestBuilder
+
+				// @QUERY("filter")
+				Filter(filter []string) GetPhotoRequestBuilder
+			}`,
+			[]string{
+				`input.go:4:7: @EXAMPLE argument colour does not match a @PATH, @QUERY, @FIELD, @PART or @HEADER method`,
+				`input.go:4:7: @EXAMPLE argument filter sets a parameter of type []string, only basic types, time.Time and time.Duration can be given as examples`,
+				`input.go:4:7: @EXAMPLE argument page="first" is not a valid int`,
+			},
+		},
+		{
+			`
+			// @GRAPHQL("PUT /graphql")
+			// @EXAMPLE(login="octocat")
+			type GetUserRequestBuilder interface {
+				// @VAR("login")
+				Login(login string) GetUserRequestBuilder
+
+				// @FIELD("name")
+				Name(name string) GetUserRequestBuilder
+			}`,
+			[]string{
+				`input.go:3:7: @GRAPHQL requires an endpoint preceded by an optional GET or POST method, for example @GRAPHQL("POST /graphql")`,
+				`input.go:3:7: @GRAPHQL requires either a query argument naming the constant of the query document or a persisted argument naming a persisted query`,
+				`input.go:4:7: @EXAMPLE is not supported by @GRAPHQL request builders`,
+				`input.go:9:8: @FIELD method Name is not supported by @GRAPHQL request builders, the body of the request is the GraphQL operation`,
+			},
+		},
+		{
+			`
+			// @GRAPHQL("POST /graphql", query="{ viewer { login } }")
+			type GetUserRequestBuilder interface {
+				// @VAR("login")
+				Login(login string) GetUserRequestBuilder
+
+				// @VAR("login")
+				User(login string) GetUserRequestBuilder
+			}`,
+			[]string{
+				`input.go:3:7: @GRAPHQL query must name the constant containing the query document, "{ viewer { login } }" is not an identifier`,
+				`input.go:8:8: @VAR("login") is already declared by method Login`,
+			},
+		},
+		{
+			`
+			// @GET("/photos")
+			// @MAX_BODY("lots")
+			type GetPhotosRequestBuilder interface {
+			}`,
+			[]string{
+				`input.go:4:7: @MAX_BODY requires a size in bytes with an optional unit of B, KB, MB or GB, for example @MAX_BODY("10MB")`,
+			},
+		},
+		{
+			`
+			// @POST("/instances")
+			type CreateInstanceRequestBuilder interface {
+				// @POLL(statusField="status", interval="soon")
+				RunAndWait() error
+			}`,
+			[]string{
+				`input.go:5:8: @POLL interval must be a positive duration, for example interval="2s"`,
+				`input.go:5:8: @POLL method RunAndWait must have a context.Context parameter`,
+				`input.go:5:8: @POLL method RunAndWait must return the final state of the operation and an error`,
+				`input.go:5:8: @POLL requires statusField and until arguments, for example @POLL(statusField="status", until="done")`,
+			},
+		},
+		{
+			`
+			// @WS("/v1/stream")
+			// @HEDGE(after="100ms")
+			type StreamRequestBuilder interface {
+				// @SYNC("Event")
+				Run() (Event, error)
+
+				// @CONNECT(send="Subscription")
+				Connect(ctx context.Context, retries int) (*restclient.WebSocket[Subscription, Event], error)
+			}`,
+			[]string{
+				`input.go:4:7: @HEDGE is not supported by @WS request builders`,
+				`input.go:6:8: @SYNC method Run is not supported by @WS request builders`,
+				`input.go:9:8: @CONNECT method Connect must have no parameters or a context.Context parameter`,
+				`input.go:9:8: @CONNECT requires the types of the messages sent and received, for example @CONNECT(send="Subscription", receive="Event")`,
+			},
+		},
+		{
+			`
+			// @GET("/v1/stream")
+			type StreamRequestBuilder interface {
+				// @CONNECT(send="Subscription", receive="Event")
+				Connect() (*restclient.WebSocket[Subscription, Event], error)
+			}`,
+			[]string{
+				`input.go:5:8: @CONNECT method Connect requires a @WS request builder`,
+			},
+		},
+		{
+			`
+			// @POST("/users")
+			type CreateUserRequestBuilder interface {
+				// @VAR("login")
+				Login(login string) CreateUserRequestBuilder
+			}`,
+			[]string{
+				`input.go:5:8: @VAR method Login requires a @GRAPHQL request builder`,
+			},
+		},
+		{
+			`
+			// @DELETE("/photos/{id}")
+			type DeletePhotoRequestBuilder interface {
+				// @PATH("id")
+				ID(id string) DeletePhotoRequestBuilder
+				// @FIELD("reason")
+				Reason(reason string) DeletePhotoRequestBuilder
+			}`,
+			[]string{
+				`input.go:7:8: @FIELD method Reason sets the body of a DELETE request, which is only sent when the request builder is annotated with @ALLOW_BODY()`,
+			},
+		},
+		{
+			`
+			// @DELETE("/photos/{id}")
+			// @ALLOW_BODY()
+			type DeletePhotoRequestBuilder interface {
+				// @PATH("id")
+				ID(id string) DeletePhotoRequestBuilder
+				// @BODY("reason")
+				Reason(reason DeleteReason) DeletePhotoRequestBuilder
+			}`,
+			nil,
+		},
+		{
+			`
+			// @HEAD("/photos/{id}")
+			type PhotoExistsRequestBuilder interface {
+				// @PATH("id")
+				ID(id string) PhotoExistsRequestBuilder
+				// @SYNC()
+				Run(ctx context.Context) error
+			}`,
+			nil,
+		},
+		{
+			`
+			// @GET("/charges")
+			// @VERSION()
+			type ListChargesRequestBuilder interface {
+			}`,
+			[]string{
+				`input.go:4:7: @VERSION requires the version of the API, for example @VERSION("2023-10-01")`,
+			},
+		},
+		{
+			`
+			// @HEAD("/photos/{id}")
+			type PhotoExistsRequestBuilder interface {
+				// @PATH("id")
+				ID(id string) PhotoExistsRequestBuilder
+				// @SYNC()
+				Run() (http.Header, error)
+			}`,
+			[]string{
+				`input.go:7:8: @SYNC requires a response type argument`,
+			},
+		},
+		{
+			`
+			// @GET("/photos/feed")
+			// @DECODER("ParsePhotoFeed(")
+			type GetPhotoFeedRequestBuilder interface {
+				// @SYNC("PhotoFeed")
+				Run() (PhotoFeed, error)
+			}`,
+			[]string{
+				`input.go:4:7: @DECODER requires the name of a function decoding the response, for example @DECODER("ParsePhotoFeed")`,
+			},
+		},
+		{
+			`
+			// @DELETE("/photos/{id}")
+			// @DECODER("ParsePhoto")
+			type DeletePhotoRequestBuilder interface {
+				// @PATH("id")
+				ID(id string) DeletePhotoRequestBuilder
+				// @SYNC()
+				Run() error
+			}`,
+			[]string{
+				`input.go:4:7: @DECODER requires a response type decoded from the body of the response, such as @SYNC("PhotoFeed")`,
+			},
+		},
+		{
+			`
+			// @GET("/repos/{owner}/contents{/path*}{?ref,fields*}")
+			type GetContentsRequestBuilder interface {
+				// @PATH("owner")
+				Owner(owner string) GetContentsRequestBuilder
+				// @PATH("path")
+				Path(path ...string) GetContentsRequestBuilder
+				// @PATH("fields")
+				Fields(fields []string) GetContentsRequestBuilder
+			}`,
+			nil,
+		},
+		{
+			`
+			// @GET("/photos/{id")
+			type GetPhotoRequestBuilder interface {
+			}`,
+			[]string{
+				`input.go:3:7: Endpoint /photos/{id is not a valid URI template: Unterminated expression {id`,
+			},
+		},
+		{
+			`
+			// @GET("/photos{/id}{?fields}")
+			// @EXAMPLE(id="1")
+			type GetPhotoRequestBuilder interface {
+				// @PATH("id")
+				ID(id string) GetPhotoRequestBuilder
+				// @PATH("fields")
+				Fields(fields string) GetPhotoRequestBuilder
+			}`,
+			[]string{
+				`input.go:4:7: @EXAMPLE requires the variables of endpoint /photos{/id}{?fields} to be simple expressions such as {id}, got {/id}`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "input.go", "package test\n"+tc.src, parser.ParseComments)
+		assert.NoError(t, err)
+
+		p := NewTypedParser(fset, f, "test", nil)
+		p.Parse()
+		var errors []string
+		if err, ok := p.Err().(scanner.ErrorList); ok {
+			for _, e := range err {
+				errors = append(errors, e.Error())
+			}
+		}
+		assert.Equal(t, tc.errors, errors)
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	src := `package test
+		// @GET("/photos")
+		type GetPhotosRequestBuilder interface {
+			// @QEURY("page")
+			Page(page int) GetPhotosRequestBuilder
+
+			// @Query("feature")
+			Feature(feature string) GetPhotosRequestBuilder
+
+			// @RETRY(3)
+			Retry() GetPhotosRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	p := NewTypedParser(fset, f, "test", nil)
+	p.Parse()
+	err = p.Err()
+	if assert.Error(t, err) {
+		errors := err.(scanner.ErrorList)
+		assert.Len(t, errors, 3)
+		assert.Equal(t, `input.go:4:7: Unknown annotation @QEURY, did you mean @QUERY?`, errors[0].Error())
+		assert.Equal(t, `input.go:7:7: Unknown annotation @Query, did you mean @QUERY?`, errors[1].Error())
+		assert.Equal(t, `input.go:10:7: Unknown annotation @RETRY`, errors[2].Error())
+	}
+
+	p = NewTypedParser(fset, f, "test", nil)
+	p.Strict = false
+	p.Parse()
+	assert.NoError(t, p.Err())
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("QUERY", "QUERY"))
+	assert.Equal(t, 1, editDistance("QEURY", "QUERY"))
+	assert.Equal(t, 1, editDistance("QUERYS", "QUERY"))
+	assert.Equal(t, 2, editDistance("PTH", "PATCH"))
+}
+
+func TestParseConflicts(t *testing.T) {
+	src := `package test
+		// @GET("/users/{user}/photos/{id}")
+		// @POST("/photos")
+		type GetPhotoRequestBuilder interface {
+			// @PATH("id")
+			PhotoID(id string) GetPhotoRequestBuilder
+
+			// @PATH("id")
+			ID(id string) GetPhotoRequestBuilder
+
+			// @SYNC("GetPhotoResponse") @ASYNC("GetPhotoCallback")
+			Run() (GetPhotoResponse, error)
+
+			// @SYNC("GetPhotoResponse")
+			Get() (GetPhotoResponse, error)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	p := NewTypedParser(fset, f, "test", nil)
+	p.Parse()
+	var errors []string
+	if err, ok := p.Err().(scanner.ErrorList); ok {
+		for _, e := range err {
+			errors = append(errors, e.Error())
+		}
+	}
+	assert.Equal(t, []string{
+		`input.go:2:6: Endpoint /users/{user}/photos/{id} has no @PATH method for {user}`,
+		`input.go:3:6: @POST conflicts with @GET, a request builder has a single HTTP method`,
+		`input.go:8:7: @PATH("id") is already declared by method PhotoID`,
+		`input.go:11:33: @ASYNC conflicts with @SYNC, method Run must have a single request annotation`,
+		`input.go:11:33: @ASYNC method Run must have the callback as its only parameter, optionally preceded by a context.Context`,
+		`input.go:14:7: @SYNC is already declared by method Run`,
+	}, errors)
+}
+
+func TestParseAll(t *testing.T) {
+	src := `package test
+		import "time"
+
+		// @GET("/photos")
+		type GetPhotosRequestBuilder interface {
+			// @QUERY("since")
+			Since(t time.Time) GetPhotosRequestBuilder
+		}
+
+		type Paginated interface {
+			// @QUERY("page")
+			Page(page int) Paginated
+		}
+
+		// @DELETE("/photos/{id}")
+		type DeletePhotoRequestBuilder interface {
+			Paginated
+
+			// @PATH("id")
+			PhotoID(id string) DeletePhotoRequestBuilder
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	p := NewTypedParser(fset, f, "test", nil)
+	results := p.ParseAll()
+	assert.NoError(t, p.Err())
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "GetPhotosRequestBuilder", results[0].RequestType)
+		assert.Equal(t, "GET", results[0].HttpMethod)
+		assert.Contains(t, results[0].QueryParams, "Since")
+		assert.Equal(t, map[string]string{"time": ""}, results[0].Imports)
+
+		assert.Equal(t, "DeletePhotoRequestBuilder", results[1].RequestType)
+		assert.Equal(t, "DELETE", results[1].HttpMethod)
+		assert.Equal(t, "/photos/{id}", results[1].ApiEndpoint)
+		assert.Contains(t, results[1].PathSubstitutions, "PhotoID")
+		assert.Contains(t, results[1].QueryParams, "Page")
+		assert.NotContains(t, results[1].QueryParams, "Since")
+		assert.Empty(t, results[1].Imports)
+	}
+	assert.Equal(t, results[0], p.Parse())
+}
+
+func TestStripAnnotations(t *testing.T) {
+	var testCases = []struct {
+		input  string
+		output string
+	}{
+		{"@GET(\"/photos\")\n", ""},
+		{"GetPhotos lists the photos of a user.\n@GET(\"/users/{id}/photos\")\n", "GetPhotos lists the photos of a user."},
+		{"@QUERY(\"since\") @FORMAT(\"unix\")\nSince filters photos uploaded after t.\n\nDefaults to all photos.\n", "Since filters photos uploaded after t.\n\nDefaults to all photos."},
+		{"Page selects the page, @QUERY(\"page\", required) starting at 1\n", "Page selects the page, starting at 1"},
+		{"Contact support@example.com (or @QUERY( for help)\n", "Contact support@example.com (or @QUERY( for help)"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.output, StripAnnotations(tc.input))
+	}
+}
+
+func TestParseGenericImports(t *testing.T) {
+	src := `
+		package test
+
+		import (
+			"example.com/api/models"
+			"example.com/api/photos"
+		)
+
+		// @GET("/photos")
+		type GetPhotosRequestBuilder interface {
+			// @SYNC("models.Page[photos.Photo]")
+			Run() (models.Page[photos.Photo], error)
+		}
+		`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+
+	result := NewParser(f, "test").Parse()
+	assert.Equal(t, "models.Page[photos.Photo]", result.ResponseType)
+	assert.Equal(t, map[string]string{"example.com/api/models": "", "example.com/api/photos": ""}, result.Imports)
+}
@@ -0,0 +1,19 @@
+package restclient
+
+import "sync/atomic"
+
+var defaultAccept atomic.Value
+
+// SetDefaultAccept sets the Accept header sent by request builders without an @ACCEPT annotation.
+// Supplying the empty string restores the default, application/json.
+func SetDefaultAccept(accept string) {
+	defaultAccept.Store(accept)
+}
+
+// DefaultAccept returns the Accept header sent by request builders without an @ACCEPT annotation
+func DefaultAccept() string {
+	if accept, _ := defaultAccept.Load().(string); accept != "" {
+		return accept
+	}
+	return "application/json"
+}
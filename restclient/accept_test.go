@@ -0,0 +1,15 @@
+package restclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultAccept(t *testing.T) {
+	assert.Equal(t, "application/json", DefaultAccept())
+	SetDefaultAccept("application/vnd.api+json")
+	assert.Equal(t, "application/vnd.api+json", DefaultAccept())
+	SetDefaultAccept("")
+	assert.Equal(t, "application/json", DefaultAccept())
+}
@@ -0,0 +1,42 @@
+package restclient
+
+import (
+	"net/http"
+	"strings"
+)
+
+// APIVersion returns the version of the API requested by the client along with the header
+// carrying it, which are the APIVersion of clients implementing
+// interface{ APIVersion() (string, string) }. An empty header sends the version as a path prefix.
+func APIVersion(client Client) (string, string) {
+	if c, ok := client.(interface{ APIVersion() (string, string) }); ok {
+		return c.APIVersion()
+	}
+	return "", ""
+}
+
+// VersionPath returns the path prefix of a request to the API with the version of the client, or
+// with version when it is not empty, such as /v2. The prefix is empty when the version is sent in
+// a header or no version is requested.
+func VersionPath(client Client, version string) string {
+	clientVersion, header := APIVersion(client)
+	if version == "" {
+		version = clientVersion
+	}
+	if header != "" || version == "" {
+		return ""
+	}
+	return "/" + strings.Trim(version, "/")
+}
+
+// SetVersionHeader sets the header carrying the version of the API of the client, or version when
+// it is not empty. The header is left unset when the version is sent as a path prefix.
+func SetVersionHeader(request *http.Request, client Client, version string) {
+	clientVersion, header := APIVersion(client)
+	if version == "" {
+		version = clientVersion
+	}
+	if header != "" && version != "" {
+		request.Header.Set(header, version)
+	}
+}
@@ -0,0 +1,32 @@
+package restclient
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIVersion(t *testing.T) {
+	client := NewDefaultClient("http://example.com", false, http.DefaultClient)
+	assert.Equal(t, "", VersionPath(client, ""))
+	assert.Equal(t, "/2024-01-01", VersionPath(client, "2024-01-01"))
+
+	client.SetAPIVersion("v2")
+	assert.Equal(t, "/v2", VersionPath(client, ""))
+	assert.Equal(t, "/v3", VersionPath(client, "/v3/"))
+	request, _ := http.NewRequest(http.MethodGet, "http://example.com/photos", nil)
+	SetVersionHeader(request, client, "")
+	assert.Empty(t, request.Header)
+
+	client.SetAPIVersion("2023-10-01")
+	client.SetAPIVersionHeader("Stripe-Version")
+	version, header := APIVersion(client)
+	assert.Equal(t, "2023-10-01", version)
+	assert.Equal(t, "Stripe-Version", header)
+	assert.Equal(t, "", VersionPath(client, "2024-01-01"))
+	SetVersionHeader(request, client, "")
+	assert.Equal(t, "2023-10-01", request.Header.Get("Stripe-Version"))
+	SetVersionHeader(request, client, "2024-01-01")
+	assert.Equal(t, "2024-01-01", request.Header.Get("Stripe-Version"))
+}
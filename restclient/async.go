@@ -0,0 +1,93 @@
+package restclient
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrAsyncQueueFull is reported to the callback of an asynchronous request which is rejected
+// because the queue of the AsyncExecutor is full.
+var ErrAsyncQueueFull = errors.New("The queue of asynchronous requests is full")
+
+// AsyncExecutor runs asynchronous requests with a bounded number of goroutines. Requests which
+// cannot run right away wait in a queue of bounded length, and are rejected when it is full.
+type AsyncExecutor struct {
+	tasks   chan func()
+	pending int64
+	mu      sync.RWMutex
+	closed  bool
+	workers sync.WaitGroup
+}
+
+// NewAsyncExecutor returns an executor running at most maxConcurrency requests at the same time
+// with at most queueLength requests waiting to run.
+func NewAsyncExecutor(maxConcurrency int, queueLength int) *AsyncExecutor {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	if queueLength < 0 {
+		queueLength = 0
+	}
+	// The tasks which are running or waiting always fit in the channel, so submitting never blocks
+	e := &AsyncExecutor{tasks: make(chan func(), maxConcurrency+queueLength)}
+	e.workers.Add(maxConcurrency)
+	for i := 0; i < maxConcurrency; i++ {
+		go e.work()
+	}
+	return e
+}
+
+func (e *AsyncExecutor) work() {
+	defer e.workers.Done()
+	for task := range e.tasks {
+		task()
+		atomic.AddInt64(&e.pending, -1)
+	}
+}
+
+// Submit runs task once a goroutine of the executor is available.
+// Returns ErrAsyncQueueFull when the task cannot be queued or the executor is closed.
+func (e *AsyncExecutor) Submit(task func()) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed || atomic.AddInt64(&e.pending, 1) > int64(cap(e.tasks)) {
+		if !e.closed {
+			atomic.AddInt64(&e.pending, -1)
+		}
+		return ErrAsyncQueueFull
+	}
+	e.tasks <- task
+	return nil
+}
+
+// Close rejects the tasks submitted from now on, waits for the queued tasks to complete and
+// stops the goroutines of the executor.
+func (e *AsyncExecutor) Close() {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.tasks)
+	}
+	e.mu.Unlock()
+	e.workers.Wait()
+}
+
+var asyncExecutor atomic.Value
+
+// SetAsyncExecutor sets the executor running the requests of RunAsync methods.
+// Supplying nil runs every asynchronous request in its own goroutine, which is the default.
+func SetAsyncExecutor(executor *AsyncExecutor) {
+	asyncExecutor.Store(executor)
+}
+
+// RunAsync runs task with the executor set with SetAsyncExecutor, or in a new goroutine when no
+// executor is set. Returns ErrAsyncQueueFull when the executor rejects the task.
+func RunAsync(task func()) error {
+	executor, _ := asyncExecutor.Load().(*AsyncExecutor)
+	if executor == nil {
+		go task()
+		return nil
+	}
+	return executor.Submit(task)
+}
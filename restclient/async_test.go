@@ -0,0 +1,45 @@
+package restclient
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncExecutor(t *testing.T) {
+	executor := NewAsyncExecutor(1, 1)
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	task := func() {
+		<-release
+		wg.Done()
+	}
+
+	assert.NoError(t, executor.Submit(task))
+	assert.NoError(t, executor.Submit(task))
+	assert.Equal(t, ErrAsyncQueueFull, executor.Submit(task))
+
+	close(release)
+	wg.Wait()
+	executor.Close()
+	assert.Equal(t, ErrAsyncQueueFull, executor.Submit(task))
+}
+
+func TestAsyncExecutorClose(t *testing.T) {
+	executor := NewAsyncExecutor(2, 8)
+	var mu sync.Mutex
+	completed := 0
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, executor.Submit(func() {
+			mu.Lock()
+			completed++
+			mu.Unlock()
+		}))
+	}
+
+	// Close waits for the queued tasks
+	executor.Close()
+	assert.Equal(t, 10, completed)
+}
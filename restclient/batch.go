@@ -0,0 +1,54 @@
+package restclient
+
+import (
+	"context"
+	"sync"
+)
+
+// Runner is implemented by every generated request builder with a @SYNC method.
+// RunRequest sends the request with the context ctx and returns the decoded response.
+type Runner interface {
+	RunRequest(ctx context.Context) (interface{}, error)
+}
+
+// BatchResult is the response or error of a request builder run by Batch
+type BatchResult struct {
+	Response interface{}
+	Err      error
+}
+
+// Batch runs the request builders with at most concurrency requests at the same time and returns
+// their results in the order of the request builders. Every request is sent with ctx, so cancelling
+// ctx cancels the requests which are still running. A concurrency below 1 runs every request at the
+// same time. The returned error is the error of the first request builder which failed, if any.
+func Batch(ctx context.Context, concurrency int, runners ...Runner) ([]BatchResult, error) {
+	if concurrency < 1 || concurrency > len(runners) {
+		concurrency = len(runners)
+	}
+
+	results := make([]BatchResult, len(runners))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				response, err := runners[index].RunRequest(ctx)
+				results[index] = BatchResult{Response: response, Err: err}
+			}
+		}()
+	}
+	for i := range runners {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, result := range results {
+		if result.Err != nil {
+			return results, result.Err
+		}
+	}
+	return results, nil
+}
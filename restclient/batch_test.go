@@ -0,0 +1,73 @@
+package restclient
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runnerFunc is a Runner implemented by a function
+type runnerFunc func(ctx context.Context) (interface{}, error)
+
+func (f runnerFunc) RunRequest(ctx context.Context) (interface{}, error) {
+	return f(ctx)
+}
+
+func TestBatch(t *testing.T) {
+	var running, maxRunning int32
+	runners := make([]Runner, 10)
+	for i := range runners {
+		i := i
+		runners[i] = runnerFunc(func(ctx context.Context) (interface{}, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			// Later requests finish first
+			time.Sleep(time.Duration(10-i) * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return i, nil
+		})
+	}
+
+	results, err := Batch(context.Background(), 3, runners...)
+	assert.NoError(t, err)
+	if assert.Len(t, results, 10) {
+		for i, result := range results {
+			assert.Equal(t, i, result.Response)
+			assert.NoError(t, result.Err)
+		}
+	}
+	assert.True(t, atomic.LoadInt32(&maxRunning) <= 3, "ran %d requests at the same time", maxRunning)
+}
+
+func TestBatchErrors(t *testing.T) {
+	first := errors.New("first")
+	second := errors.New("second")
+	runners := []Runner{
+		runnerFunc(func(ctx context.Context) (interface{}, error) { return 0, nil }),
+		runnerFunc(func(ctx context.Context) (interface{}, error) {
+			time.Sleep(20 * time.Millisecond)
+			return nil, first
+		}),
+		runnerFunc(func(ctx context.Context) (interface{}, error) { return nil, second }),
+	}
+
+	// The error of the first request builder which failed is returned, along with every result
+	results, err := Batch(context.Background(), 0, runners...)
+	assert.Equal(t, first, err)
+	assert.Equal(t, []BatchResult{{Response: 0}, {Err: first}, {Err: second}}, results)
+}
+
+func TestBatchEmpty(t *testing.T) {
+	results, err := Batch(context.Background(), 4)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
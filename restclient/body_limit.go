@@ -0,0 +1,82 @@
+package restclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// BodyTooLargeError is the error of a response whose body exceeds the limit of its request builder,
+// see LimitBody.
+type BodyTooLargeError struct {
+	URL   string
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("Response body of %s exceeds the limit of %d bytes", e.URL, e.Limit)
+}
+
+// MaxBodySize returns the largest response body accepted by request builders without a @MAX_BODY
+// annotation, which is the MaxBodySize of clients implementing interface{ MaxBodySize() int64 }.
+// Returns 0, which accepts bodies of any size, for other clients.
+func MaxBodySize(client Client) int64 {
+	if c, ok := client.(interface{ MaxBodySize() int64 }); ok {
+		return c.MaxBodySize()
+	}
+	return 0
+}
+
+// HasContent returns false when the response legitimately has no body to decode, which is the case
+// for responses to HEAD requests and responses with the status 204 No Content
+func HasContent(response *http.Response) bool {
+	if response.StatusCode == http.StatusNoContent {
+		return false
+	}
+	return response.Request == nil || response.Request.Method != http.MethodHead
+}
+
+// LimitBody limits the body of response to limit bytes. A *BodyTooLargeError is returned right away
+// when the Content-Length of the response exceeds the limit, otherwise reading the body fails with
+// a *BodyTooLargeError once the limit is exceeded. A limit of 0 or less leaves the body unlimited.
+func LimitBody(response *http.Response, limit int64) error {
+	if limit <= 0 {
+		return nil
+	}
+	err := &BodyTooLargeError{Limit: limit}
+	if response.Request != nil {
+		err.URL = response.Request.URL.String()
+	}
+	if response.ContentLength > limit {
+		return err
+	}
+	response.Body = &limitedBody{ReadCloser: response.Body, remaining: limit, err: err}
+	return nil
+}
+
+// limitedBody is a response body which fails with err once more than remaining bytes are read
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	err       error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if b.remaining <= 0 {
+		// Any further byte exceeds the limit
+		n, err := b.ReadCloser.Read(p[:1])
+		if n > 0 {
+			return 0, b.err
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
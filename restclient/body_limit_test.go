@@ -0,0 +1,61 @@
+package restclient
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitBody(t *testing.T) {
+	requestURL, _ := url.Parse("https://api.example.com/photos")
+	response := func(body string, contentLength int64) *http.Response {
+		return &http.Response{
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: contentLength,
+			Request:       &http.Request{URL: requestURL},
+		}
+	}
+
+	r := response("0123456789", -1)
+	assert.NoError(t, LimitBody(r, 10))
+	data, err := io.ReadAll(r.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789", string(data))
+
+	r = response("0123456789", -1)
+	assert.NoError(t, LimitBody(r, 4))
+	data, err = io.ReadAll(r.Body)
+	assert.Equal(t, "0123", string(data))
+	assert.EqualError(t, err, "Response body of https://api.example.com/photos exceeds the limit of 4 bytes")
+
+	err = LimitBody(response("0123456789", 10), 4)
+	assert.Equal(t, &BodyTooLargeError{URL: "https://api.example.com/photos", Limit: 4}, err)
+
+	// A limit of 0 leaves the body unlimited
+	r = response("0123456789", 10)
+	assert.NoError(t, LimitBody(r, 0))
+	data, err = io.ReadAll(r.Body)
+	assert.NoError(t, err)
+	assert.Len(t, data, 10)
+}
+
+func TestMaxBodySize(t *testing.T) {
+	client := NewDefaultClient("https://api.example.com", false, http.DefaultClient)
+	assert.Equal(t, int64(0), MaxBodySize(client))
+	client.SetMaxBodySize(1 << 20)
+	assert.Equal(t, int64(1<<20), MaxBodySize(client))
+}
+
+func TestHasContent(t *testing.T) {
+	get, _ := http.NewRequest(http.MethodGet, "http://example.com/photos/abc", nil)
+	head, _ := http.NewRequest(http.MethodHead, "http://example.com/photos/abc", nil)
+
+	assert.True(t, HasContent(&http.Response{StatusCode: http.StatusOK, Request: get}))
+	assert.True(t, HasContent(&http.Response{StatusCode: http.StatusOK}))
+	assert.False(t, HasContent(&http.Response{StatusCode: http.StatusNoContent, Request: get}))
+	assert.False(t, HasContent(&http.Response{StatusCode: http.StatusOK, Request: head}))
+}
@@ -20,13 +20,20 @@ type Client interface {
 	HttpClient() *http.Client
 }
 
+// DebugRequest logs the request in the format set with SetDebugFormat
 func DebugRequest(request *http.Request) {
+	if DebugFormat(debugFormat.Load()) == DebugCurl {
+		command, err := CurlCommand(request)
+		logDebugOutput(requestTag, []byte(command), err)
+		return
+	}
 	data, err := httputil.DumpRequestOut(request, true)
 	logDebugOutput(requestTag, data, err)
 }
 
 func DebugResponse(response *http.Response) {
-	data, err := httputil.DumpResponse(response, true)
+	// The body of a response switching protocols is the connection, which is left unread
+	data, err := httputil.DumpResponse(response, response.StatusCode != http.StatusSwitchingProtocols)
 	logDebugOutput(responseTag, data, err)
 }
 
@@ -1,6 +1,16 @@
 package restclient
 
+import (
+	"net/http"
+	"sync/atomic"
+)
+
 type ClientManager struct {
+	client atomic.Pointer[registeredClient]
+}
+
+// registeredClient holds the registered client, as an atomic.Pointer cannot point to an interface
+type registeredClient struct {
 	client Client
 }
 
@@ -10,10 +20,66 @@ func init() {
 	clientManager = &ClientManager{}
 }
 
+// RegisterClient registers the client sending the requests of the request builders. It is safe to
+// register another client while requests are sent, a request being sent completes with the client
+// it was sent with. Supplying nil unregisters the client.
 func RegisterClient(client Client) {
-	clientManager.client = client
+	clientManager.client.Store(&registeredClient{client: client})
 }
 
 func GetClient() Client {
-	return clientManager.client
+	if registered := clientManager.client.Load(); registered != nil {
+		return registered.client
+	}
+	return nil
+}
+
+// Config is the configuration of the registered client, which is modified with UpdateClient
+type Config struct {
+	BaseURL    string
+	Debug      bool
+	HttpClient *http.Client
+}
+
+// UpdateClient replaces the registered client with a DefaultClient configured by update, which is
+// given the configuration of the registered client, such as to point the client at a new base URL
+// or to send requests with a transport carrying rotated credentials. The user agent, the maximum
+// body size and the version of the API of the registered client are kept. update is called again
+// when another client is registered at the same time, so it must not have side effects.
+func UpdateClient(update func(Config) Config) {
+	replaceClient(func(current Client) Client {
+		var config Config
+		if current != nil {
+			config = Config{BaseURL: current.BaseURL(), Debug: current.Debug(), HttpClient: current.HttpClient()}
+		}
+		return configuredClient(current, update(config))
+	})
+}
+
+// replaceClient registers the client returned by replace, which is given the registered client and
+// is called again when another client is registered at the same time
+func replaceClient(replace func(current Client) Client) {
+	for {
+		registered := clientManager.client.Load()
+		var current Client
+		if registered != nil {
+			current = registered.client
+		}
+		if clientManager.client.CompareAndSwap(registered, &registeredClient{client: replace(current)}) {
+			return
+		}
+	}
+}
+
+// configuredClient returns a DefaultClient with the configuration and with the user agent, the
+// maximum body size and the version of the API of current, unless current is nil
+func configuredClient(current Client, config Config) *DefaultClient {
+	client := &DefaultClient{baseURL: config.BaseURL, debug: config.Debug, client: config.HttpClient}
+	if current != nil {
+		client.SetUserAgent(UserAgent(current))
+		client.SetMaxBodySize(MaxBodySize(current))
+		version, header := APIVersion(current)
+		client.apiVersion.Store(apiVersion{version: version, header: header})
+	}
+	return client
 }
@@ -0,0 +1,73 @@
+package restclient
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterClient(t *testing.T) {
+	defer RegisterClient(nil)
+
+	RegisterClient(nil)
+	assert.Nil(t, GetClient())
+
+	clients := []Client{
+		NewDefaultClient("https://a.example.com", false, http.DefaultClient),
+		NewDefaultClient("https://b.example.com", false, http.DefaultClient),
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				RegisterClient(clients[(i+j)%2])
+				assert.NotNil(t, GetClient())
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Contains(t, clients, GetClient())
+}
+
+func TestUpdateClient(t *testing.T) {
+	defer RegisterClient(nil)
+
+	RegisterClient(nil)
+	UpdateClient(func(config Config) Config {
+		assert.Equal(t, Config{}, config)
+		config.BaseURL = "https://api.example.com"
+		return config
+	})
+	assert.Equal(t, "https://api.example.com", GetClient().BaseURL())
+
+	client := NewDefaultClient("https://api.example.com", true, http.DefaultClient)
+	client.SetUserAgent("photos/1.0")
+	client.SetMaxBodySize(1024)
+	client.SetAPIVersion("2024-01-01")
+	client.SetAPIVersionHeader("Api-Version")
+	RegisterClient(client)
+
+	rotated := &http.Client{}
+	UpdateClient(func(config Config) Config {
+		assert.Equal(t, Config{BaseURL: "https://api.example.com", Debug: true, HttpClient: http.DefaultClient}, config)
+		config.BaseURL = "https://eu.api.example.com"
+		config.HttpClient = rotated
+		return config
+	})
+	updated := GetClient()
+	assert.Equal(t, "https://eu.api.example.com", updated.BaseURL())
+	assert.True(t, updated.Debug())
+	assert.Same(t, rotated, updated.HttpClient())
+	assert.Equal(t, "photos/1.0", UserAgent(updated))
+	assert.Equal(t, int64(1024), MaxBodySize(updated))
+	version, header := APIVersion(updated)
+	assert.Equal(t, "2024-01-01", version)
+	assert.Equal(t, "Api-Version", header)
+
+	// The registered client is left unchanged
+	assert.Equal(t, "https://api.example.com", client.BaseURL())
+}
@@ -0,0 +1,88 @@
+package restclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
+)
+
+// JSONCodec marshals and unmarshals JSON. The generated request builders encode request bodies
+// with the registered codec, which can be backed by a faster library such as jsoniter, go-json
+// or sonic, or configured to be stricter than the defaults of encoding/json.
+type JSONCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// StandardJSON is a JSONCodec based on encoding/json, which is used until another codec is registered
+type StandardJSON struct {
+	// UseNumber decodes numbers in to an interface{} as a json.Number instead of a float64
+	UseNumber bool
+	// DisallowUnknownFields fails to decode an object with a field missing from the destination struct
+	DisallowUnknownFields bool
+}
+
+func (c StandardJSON) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (c StandardJSON) Unmarshal(data []byte, v interface{}) error {
+	if !c.UseNumber && !c.DisallowUnknownFields {
+		return json.Unmarshal(data, v)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if c.UseNumber {
+		decoder.UseNumber()
+	}
+	if c.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(v)
+}
+
+// registeredCodec holds the registered codec, as an atomic.Pointer cannot point to an interface
+type registeredCodec struct {
+	codec JSONCodec
+}
+
+var jsonCodec atomic.Pointer[registeredCodec]
+
+// RegisterJSONCodec sets the codec used to marshal and unmarshal JSON.
+// It should be registered along with the client, before any request is built, although it is safe
+// to register another codec while requests are sent.
+func RegisterJSONCodec(codec JSONCodec) {
+	if codec == nil {
+		codec = StandardJSON{}
+	}
+	jsonCodec.Store(&registeredCodec{codec: codec})
+}
+
+// GetJSONCodec returns the registered JSON codec
+func GetJSONCodec() JSONCodec {
+	if registered := jsonCodec.Load(); registered != nil {
+		return registered.codec
+	}
+	return StandardJSON{}
+}
+
+// MarshalJSON returns the JSON encoding of v with the registered codec
+func MarshalJSON(v interface{}) ([]byte, error) {
+	return GetJSONCodec().Marshal(v)
+}
+
+// UnmarshalJSON decodes the JSON encoded data in to v with the registered codec
+func UnmarshalJSON(data []byte, v interface{}) error {
+	return GetJSONCodec().Unmarshal(data, v)
+}
+
+// DecodeJSON reads r to the end and decodes it in to v with the registered codec.
+// It can be used by the constructors of response types to decode a response body.
+func DecodeJSON(r io.Reader, v interface{}) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return GetJSONCodec().Unmarshal(data, v)
+}
@@ -0,0 +1,83 @@
+package restclient
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// upperCodec marshals JSON in upper case, so tests can tell it was used
+type upperCodec struct {
+	StandardJSON
+}
+
+func (c upperCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := c.StandardJSON.Marshal(v)
+	return []byte(strings.ToUpper(string(data))), err
+}
+
+func TestStandardJSON(t *testing.T) {
+	var photo struct {
+		ID string `json:"id"`
+	}
+	data := []byte(`{"id": "abc", "likes": 12}`)
+	assert.NoError(t, StandardJSON{}.Unmarshal(data, &photo))
+	assert.Equal(t, "abc", photo.ID)
+	assert.Error(t, StandardJSON{DisallowUnknownFields: true}.Unmarshal(data, &photo))
+
+	var value map[string]interface{}
+	assert.NoError(t, StandardJSON{UseNumber: true}.Unmarshal(data, &value))
+	assert.Equal(t, json.Number("12"), value["likes"])
+}
+
+func TestRegisterJSONCodec(t *testing.T) {
+	defer RegisterJSONCodec(nil)
+
+	RegisterJSONCodec(upperCodec{})
+	data, err := MarshalJSON(map[string]string{"id": "abc"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ID":"ABC"}`, string(data))
+
+	request, err := NewGraphQLRequest("POST", "http://example.com/graphql", GraphQLOperation{Query: "query { me }"})
+	assert.NoError(t, err)
+	body, err := io.ReadAll(request.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"QUERY":"QUERY { ME }"}`, string(body))
+
+	RegisterJSONCodec(nil)
+	assert.Equal(t, StandardJSON{}, GetJSONCodec())
+
+	var photo struct {
+		ID string `json:"id"`
+	}
+	assert.NoError(t, DecodeJSON(strings.NewReader(`{"id": "abc"}`), &photo))
+	assert.Equal(t, "abc", photo.ID)
+}
+
+func TestRegisterJSONCodecConcurrently(t *testing.T) {
+	defer RegisterJSONCodec(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				RegisterJSONCodec(upperCodec{})
+				RegisterJSONCodec(nil)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, err := MarshalJSON(map[string]string{"id": "abc"})
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,80 @@
+package restclient
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// SandboxURLEnv is the environment variable containing the base URL of the sandbox environment
+	// the generated conformance tests are run against
+	SandboxURLEnv = "GOREST_SANDBOX_URL"
+	// ConformanceReportEnv is the environment variable containing the name of the file the results
+	// of the generated conformance tests are appended to
+	ConformanceReportEnv = "GOREST_CONFORMANCE_REPORT"
+)
+
+// ConformanceResult is the outcome of running an example request against a sandbox environment.
+type ConformanceResult struct {
+	Endpoint string        `json:"endpoint"`
+	Example  int           `json:"example"`
+	Method   string        `json:"method"`
+	URL      string        `json:"url"`
+	Status   int           `json:"status"`
+	Pages    int           `json:"pages,omitempty"`
+	Passed   bool          `json:"passed"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+var reportMutex sync.Mutex
+
+// RecordConformance appends the result as a line of JSON to the report named by the
+// GOREST_CONFORMANCE_REPORT environment variable. Results are discarded when it is not set.
+func RecordConformance(result ConformanceResult) error {
+	filename := os.Getenv(ConformanceReportEnv)
+	if filename == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	reportMutex.Lock()
+	defer reportMutex.Unlock()
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadConformanceReport reads the results recorded in the report.
+func ReadConformanceReport(filename string) ([]ConformanceResult, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var results []ConformanceResult
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var result ConformanceResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			return nil, fmt.Errorf("Invalid conformance report %s: %v", filename, err)
+		}
+		results = append(results, result)
+	}
+	return results, scanner.Err()
+}
@@ -0,0 +1,39 @@
+package restclient
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordConformance(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "conformance.jsonl")
+	results := []ConformanceResult{
+		{Endpoint: "GetPhotoDetailsRequestBuilder", Method: "GET", URL: "https://sandbox.example.com/photos/1", Status: 200, Passed: true, Duration: time.Millisecond},
+		{Endpoint: "ListPhotosRequestBuilder", Example: 1, Method: "GET", URL: "https://sandbox.example.com/photos", Status: 500, Pages: 2, Error: "Unexpected status 500"},
+	}
+
+	// Results are discarded when no report is set
+	t.Setenv(ConformanceReportEnv, "")
+	assert.NoError(t, RecordConformance(results[0]))
+
+	t.Setenv(ConformanceReportEnv, filename)
+	for _, result := range results {
+		assert.NoError(t, RecordConformance(result))
+	}
+
+	recorded, err := ReadConformanceReport(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, results, recorded)
+
+	_, err = ReadConformanceReport(filepath.Join(t.TempDir(), "missing.jsonl"))
+	assert.True(t, os.IsNotExist(err))
+
+	invalid := filepath.Join(t.TempDir(), "invalid.jsonl")
+	os.WriteFile(invalid, []byte("{\"endpoint\":\n"), 0644)
+	_, err = ReadConformanceReport(invalid)
+	assert.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package restclient
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// HeaderExtractor returns the value of a header taken from the context of a request, such as a
+// request ID or tenant ID. Returning the empty string leaves the header unset.
+type HeaderExtractor func(ctx context.Context) string
+
+var (
+	headerExtractors   atomic.Value
+	headerExtractorsMu sync.Mutex
+)
+
+// SetHeaderExtractor sets the header name of every request to the value extracted from the
+// context of the request by extractor, which propagates values such as request IDs to the
+// services being called. Supplying nil removes the extractor of the header.
+func SetHeaderExtractor(name string, extractor HeaderExtractor) {
+	headerExtractorsMu.Lock()
+	defer headerExtractorsMu.Unlock()
+
+	// The extractors are copied on write, so that requests read them without locking
+	current, _ := headerExtractors.Load().(map[string]HeaderExtractor)
+	extractors := make(map[string]HeaderExtractor, len(current)+1)
+	for key, value := range current {
+		extractors[key] = value
+	}
+	name = http.CanonicalHeaderKey(name)
+	if extractor == nil {
+		delete(extractors, name)
+	} else {
+		extractors[name] = extractor
+	}
+	headerExtractors.Store(extractors)
+}
+
+// ApplyContextHeaders sets the headers extracted from the context of the request by the extractors
+// set with SetHeaderExtractor. Headers already set on the request are left unchanged.
+func ApplyContextHeaders(request *http.Request) {
+	extractors, _ := headerExtractors.Load().(map[string]HeaderExtractor)
+	for name, extractor := range extractors {
+		if request.Header.Get(name) != "" {
+			continue
+		}
+		if value := extractor(request.Context()); value != "" {
+			request.Header.Set(name, value)
+		}
+	}
+}
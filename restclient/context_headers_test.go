@@ -0,0 +1,44 @@
+package restclient
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type contextKey string
+
+func TestApplyContextHeaders(t *testing.T) {
+	defer SetHeaderExtractor("x-request-id", nil)
+	defer SetHeaderExtractor("X-Tenant-Id", nil)
+
+	extract := func(key contextKey) HeaderExtractor {
+		return func(ctx context.Context) string {
+			value, _ := ctx.Value(key).(string)
+			return value
+		}
+	}
+	SetHeaderExtractor("x-request-id", extract("request"))
+	SetHeaderExtractor("X-Tenant-Id", extract("tenant"))
+
+	ctx := context.WithValue(context.Background(), contextKey("request"), "request-1")
+	ctx = context.WithValue(ctx, contextKey("tenant"), "tenant-1")
+	request, _ := http.NewRequestWithContext(ctx, "GET", "https://api.example.com/photos", nil)
+	request.Header.Set("X-Tenant-Id", "explicit")
+	ApplyContextHeaders(request)
+	assert.Equal(t, "request-1", request.Header.Get("X-Request-Id"))
+	assert.Equal(t, "explicit", request.Header.Get("X-Tenant-Id"))
+
+	// Empty values leave the header unset
+	request, _ = http.NewRequest("GET", "https://api.example.com/photos", nil)
+	ApplyContextHeaders(request)
+	assert.Empty(t, request.Header)
+
+	// Removed extractors are no longer applied
+	SetHeaderExtractor("X-Request-Id", nil)
+	request, _ = http.NewRequestWithContext(ctx, "GET", "https://api.example.com/photos", nil)
+	ApplyContextHeaders(request)
+	assert.Equal(t, http.Header{"X-Tenant-Id": {"tenant-1"}}, request.Header)
+}
@@ -0,0 +1,129 @@
+package restclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// DebugFormat selects how requests are logged by clients in debug mode, see SetDebugFormat
+type DebugFormat int32
+
+const (
+	// DebugDump logs the requests as they are sent on the wire, which is the default
+	DebugDump DebugFormat = iota
+	// DebugCurl logs the requests as equivalent curl commands with the secrets of their headers
+	// and query parameters redacted, which are convenient to reproduce a request or to share it with the provider of an API
+	DebugCurl
+)
+
+// redactedValue replaces the values of secret headers and query parameters
+const redactedValue = "REDACTED"
+
+// RedactedHeaders are the headers whose values are redacted from curl commands. Headers whose
+// names contain token, secret, password or key are redacted as well.
+var RedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+var debugFormat atomic.Int32
+
+// SetDebugFormat sets how requests are logged by clients in debug mode
+func SetDebugFormat(format DebugFormat) {
+	debugFormat.Store(int32(format))
+}
+
+// CurlCommand returns a curl command sending the same request, with the values of secret headers,
+// and of query parameters whose names contain token, secret, password or key replaced by REDACTED
+// and the password of the URL masked. The body of the request is read and replaced by a copy.
+func CurlCommand(request *http.Request) (string, error) {
+	var command strings.Builder
+	command.WriteString("curl")
+	if request.Method != http.MethodGet {
+		command.WriteString(" -X " + request.Method)
+	}
+	command.WriteString(" " + shellQuote(redactURL(request.URL)))
+
+	headers := make([]string, 0, len(request.Header))
+	for name, values := range request.Header {
+		for _, value := range values {
+			if isSecretHeader(name) {
+				value = redactedValue
+			}
+			headers = append(headers, name+": "+value)
+		}
+	}
+	if request.Host != "" && request.Host != request.URL.Host {
+		headers = append(headers, "Host: "+request.Host)
+	}
+	sort.Strings(headers)
+	for _, header := range headers {
+		command.WriteString(" \\\n  -H " + shellQuote(header))
+	}
+
+	if request.Body != nil && request.Body != http.NoBody {
+		body, err := io.ReadAll(request.Body)
+		request.Body.Close()
+		request.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		if utf8.Valid(body) {
+			command.WriteString(" \\\n  --data-raw " + shellQuote(string(body)))
+		} else {
+			command.WriteString(" \\\n  --data-binary @- # the binary body is omitted")
+		}
+	}
+	return command.String(), nil
+}
+
+// isSecretHeader returns true if the value of the header is redacted from curl commands
+func isSecretHeader(name string) bool {
+	for _, redacted := range RedactedHeaders {
+		if strings.EqualFold(name, redacted) {
+			return true
+		}
+	}
+	return isSecretName(name)
+}
+
+// isSecretName returns true if the name of a header or of a query parameter suggests its value is
+// a secret, such as api_key or X-Auth-Token
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, secret := range []string{"token", "secret", "password", "key"} {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL returns the URL with the values of its secret query parameters and its password
+// redacted. The other query parameters are kept as they are encoded, in their order.
+func redactURL(u *url.URL) string {
+	redacted := *u
+	if redacted.RawQuery != "" {
+		params := strings.Split(redacted.RawQuery, "&")
+		for i, param := range params {
+			rawName, _, found := strings.Cut(param, "=")
+			name, err := url.QueryUnescape(rawName)
+			if err != nil {
+				name = rawName
+			}
+			if found && isSecretName(name) {
+				params[i] = rawName + "=" + redactedValue
+			}
+		}
+		redacted.RawQuery = strings.Join(params, "&")
+	}
+	return redacted.Redacted()
+}
+
+// shellQuote quotes s as a single argument of a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,138 @@
+package restclient
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ContentDecoder returns a reader of the decompressed body r of a response with a content encoding
+type ContentDecoder func(r io.Reader) (io.ReadCloser, error)
+
+var contentDecoders = struct {
+	sync.RWMutex
+	m map[string]ContentDecoder
+}{m: map[string]ContentDecoder{
+	"gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+}}
+
+// RegisterContentDecoder registers the decoder of bodies with the content encoding, such as br,
+// which is then decompressed by DecompressTransport. The zstd decoder is provided by the
+// restclient/zstdencoding package, which keeps the zstd dependency out of clients which do not use it.
+// gzip is registered by default. Supplying nil unregisters the encoding.
+func RegisterContentDecoder(encoding string, decoder ContentDecoder) {
+	contentDecoders.Lock()
+	if decoder == nil {
+		delete(contentDecoders.m, strings.ToLower(encoding))
+	} else {
+		contentDecoders.m[strings.ToLower(encoding)] = decoder
+	}
+	contentDecoders.Unlock()
+}
+
+func getContentDecoder(encoding string) ContentDecoder {
+	contentDecoders.RLock()
+	defer contentDecoders.RUnlock()
+	return contentDecoders.m[strings.ToLower(encoding)]
+}
+
+// DecompressTransport is a http.RoundTripper which negotiates the content encoding of responses
+// and transparently decompresses their bodies, as net/http only does for gzip. Requests which set
+// their own Accept-Encoding are sent as is, and only the responses in a registered encoding are
+// decompressed. A decompressed response has no Content-Encoding and an unknown Content-Length.
+type DecompressTransport struct {
+	// Transport sends the requests, http.DefaultTransport is used when nil
+	Transport http.RoundTripper
+	// Encodings are the accepted content encodings in order of preference, for example
+	// []string{"zstd", "br", "gzip"}. Every registered encoding is accepted when empty.
+	Encodings []string
+}
+
+func (t *DecompressTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	if request.Header.Get("Accept-Encoding") == "" {
+		if encodings := t.acceptEncodings(); len(encodings) > 0 {
+			// The request is sent with a copy of the headers, leaving the request unmodified
+			request = request.Clone(request.Context())
+			request.Header.Set("Accept-Encoding", strings.Join(encodings, ", "))
+		}
+	}
+
+	response, err := transport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	encoding := response.Header.Get("Content-Encoding")
+	decoder := getContentDecoder(encoding)
+	if encoding == "" || decoder == nil || !hasBody(response) {
+		return response, nil
+	}
+
+	body, err := decoder(response.Body)
+	if err != nil {
+		response.Body.Close()
+		return nil, err
+	}
+	response.Body = &decodedBody{ReadCloser: body, compressed: response.Body}
+	response.Header.Del("Content-Encoding")
+	response.Header.Del("Content-Length")
+	response.ContentLength = -1
+	response.Uncompressed = true
+	return response, nil
+}
+
+// acceptEncodings returns the encodings of the Accept-Encoding header of requests
+func (t *DecompressTransport) acceptEncodings() []string {
+	if len(t.Encodings) > 0 {
+		var encodings []string
+		for _, encoding := range t.Encodings {
+			if getContentDecoder(encoding) != nil {
+				encodings = append(encodings, encoding)
+			}
+		}
+		return encodings
+	}
+
+	contentDecoders.RLock()
+	defer contentDecoders.RUnlock()
+	encodings := make([]string, 0, len(contentDecoders.m))
+	for encoding := range contentDecoders.m {
+		encodings = append(encodings, encoding)
+	}
+	sort.Strings(encodings)
+	return encodings
+}
+
+// hasBody returns false for responses which never have a body, which cannot be decompressed
+func hasBody(response *http.Response) bool {
+	switch {
+	case response.Body == nil || response.Body == http.NoBody || response.ContentLength == 0:
+		return false
+	case response.StatusCode == http.StatusNoContent || response.StatusCode == http.StatusNotModified:
+		return false
+	}
+	return response.Request == nil || response.Request.Method != http.MethodHead
+}
+
+// decodedBody is a decompressed body, which closes both the decoder and the compressed body
+type decodedBody struct {
+	io.ReadCloser
+	compressed io.ReadCloser
+}
+
+func (b *decodedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if closeErr := b.compressed.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package restclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecompressTransport(t *testing.T) {
+	payload := `{"id":"1","title":"photo"}`
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(payload))
+	writer.Close()
+
+	var acceptEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		switch r.URL.Path {
+		case "/upper":
+			w.Header().Set("Content-Encoding", "upper")
+			io.WriteString(w, strings.ToUpper(payload))
+		case "/empty":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compressed.Bytes())
+		}
+	}))
+	defer server.Close()
+
+	RegisterContentDecoder("upper", func(r io.Reader) (io.ReadCloser, error) {
+		data, err := io.ReadAll(r)
+		return io.NopCloser(strings.NewReader(strings.ToLower(string(data)))), err
+	})
+	defer RegisterContentDecoder("upper", nil)
+
+	client := &http.Client{Transport: &DecompressTransport{}}
+	get := func(path string, header http.Header) (*http.Response, string) {
+		request, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		for key, values := range header {
+			request.Header[key] = values
+		}
+		response, err := client.Do(request)
+		if !assert.NoError(t, err) {
+			return nil, ""
+		}
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		assert.NoError(t, err)
+		return response, string(body)
+	}
+
+	response, body := get("/gzip", nil)
+	assert.Equal(t, "gzip, upper", acceptEncoding)
+	assert.Equal(t, payload, body)
+	assert.Empty(t, response.Header.Get("Content-Encoding"))
+	assert.Equal(t, int64(-1), response.ContentLength)
+
+	_, body = get("/upper", nil)
+	assert.Equal(t, payload, body)
+
+	response, body = get("/empty", nil)
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+	assert.Empty(t, body)
+
+	// A request negotiating its own encoding is sent as is
+	_, body = get("/upper", http.Header{"Accept-Encoding": {"identity"}})
+	assert.Equal(t, "identity", acceptEncoding)
+	assert.Equal(t, payload, body)
+
+	client.Transport = &DecompressTransport{Encodings: []string{"br", "gzip"}}
+	_, body = get("/gzip", nil)
+	assert.Equal(t, "gzip", acceptEncoding)
+	assert.Equal(t, payload, body)
+}
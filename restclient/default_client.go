@@ -1,21 +1,64 @@
 package restclient
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+const unixScheme = "unix://"
 
 type DefaultClient struct {
-	baseURL string
-	debug   bool
-	client  *http.Client
+	baseURL   string
+	debug     bool
+	client    *http.Client
+	userAgent atomic.Value
+
+	maxBodySize atomic.Int64
+	apiVersion  atomic.Value
+}
+
+// apiVersion is the version of the API requested by a client along with the header carrying it
+type apiVersion struct {
+	version string
+	header  string
 }
 
-func NewDefaultClient(baseURL string, debug bool, client *http.Client) Client {
+// NewDefaultClient returns a client sending requests to the API at baseURL with client.
+// Options tune the transport of a copy of client, which must have no transport or a *http.Transport.
+// A base URL such as unix:///var/run/api.sock:/v1 sends the requests over the Unix domain socket
+// /var/run/api.sock with the path prefix /v1, see WithUnixSocket.
+func NewDefaultClient(baseURL string, debug bool, client *http.Client, options ...ClientOption) *DefaultClient {
+	if socket, prefix, ok := splitUnixURL(baseURL); ok {
+		baseURL = "http://localhost" + prefix
+		options = append(options, WithUnixSocket(socket))
+	}
+	if len(options) > 0 {
+		client = tuneClient(client, options)
+	}
 	return &DefaultClient{
-		baseURL,
-		debug,
-		client,
+		baseURL: baseURL,
+		debug:   debug,
+		client:  client,
 	}
 }
 
+// splitUnixURL splits a base URL of the form unix://<socket>[:<prefix>] in to the path of the
+// socket and the path prefix of the API
+func splitUnixURL(baseURL string) (string, string, bool) {
+	if !strings.HasPrefix(baseURL, unixScheme) {
+		return "", "", false
+	}
+	socket, prefix := strings.TrimPrefix(baseURL, unixScheme), ""
+	if i := strings.Index(socket, ":"); i >= 0 {
+		socket, prefix = socket[:i], socket[i+1:]
+	}
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	return socket, strings.TrimSuffix(prefix, "/"), true
+}
+
 func (c *DefaultClient) BaseURL() string {
 	return c.baseURL
 }
@@ -27,3 +70,51 @@ func (c *DefaultClient) Debug() bool {
 func (c *DefaultClient) HttpClient() *http.Client {
 	return c.client
 }
+
+// SetUserAgent sets the User-Agent header of every request sent with the client.
+// Supplying the empty string restores the default, see DefaultUserAgent.
+func (c *DefaultClient) SetUserAgent(userAgent string) {
+	c.userAgent.Store(userAgent)
+}
+
+// UserAgent returns the User-Agent header of the requests sent with the client
+func (c *DefaultClient) UserAgent() string {
+	if userAgent, _ := c.userAgent.Load().(string); userAgent != "" {
+		return userAgent
+	}
+	return DefaultUserAgent
+}
+
+// SetMaxBodySize sets the largest response body, in bytes, accepted by request builders without a
+// @MAX_BODY annotation. Supplying 0 accepts bodies of any size, which is the default.
+func (c *DefaultClient) SetMaxBodySize(size int64) {
+	c.maxBodySize.Store(size)
+}
+
+// MaxBodySize returns the largest response body accepted by request builders without a @MAX_BODY annotation
+func (c *DefaultClient) MaxBodySize() int64 {
+	return c.maxBodySize.Load()
+}
+
+// SetAPIVersion sets the version of the API requested by every request sent with the client, such
+// as v2 or 2023-10-01. Request builders annotated with @VERSION request their own version instead.
+// The version prefixes the path of each request, unless a header is set with SetAPIVersionHeader.
+func (c *DefaultClient) SetAPIVersion(version string) {
+	v, _ := c.apiVersion.Load().(apiVersion)
+	v.version = version
+	c.apiVersion.Store(v)
+}
+
+// SetAPIVersionHeader sends the version of the API in the header, such as Stripe-Version, rather
+// than as a prefix of the path of each request. Supplying the empty string restores the path prefix.
+func (c *DefaultClient) SetAPIVersionHeader(header string) {
+	v, _ := c.apiVersion.Load().(apiVersion)
+	v.header = header
+	c.apiVersion.Store(v)
+}
+
+// APIVersion returns the version of the API requested by the client along with the header carrying it
+func (c *DefaultClient) APIVersion() (string, string) {
+	v, _ := c.apiVersion.Load().(apiVersion)
+	return v.version, v.header
+}
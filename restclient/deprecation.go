@@ -0,0 +1,42 @@
+package restclient
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// DeprecationHook receives the name of a deprecated request builder or setter the first time
+// it is used, along with the message of its @DEPRECATED annotation.
+type DeprecationHook func(name string, message string)
+
+var deprecationHook atomic.Value
+var deprecationsReported sync.Map
+
+// SetDeprecationHook enables the reporting of deprecated request builders and setters to hook.
+// Supplying nil disables the reporting, which is the default.
+func SetDeprecationHook(hook DeprecationHook) {
+	deprecationHook.Store(hook)
+}
+
+// LogDeprecation is a DeprecationHook which logs the use of deprecated request builders and setters
+// with the log package, like the debug output of requests.
+func LogDeprecation(name string, message string) {
+	if message == "" {
+		log.Printf("%s is deprecated", name)
+	} else {
+		log.Printf("%s is deprecated: %s", name, message)
+	}
+}
+
+// WarnDeprecated reports the use of the deprecated request builder or setter name to the
+// deprecation hook. Each name is reported once per process.
+func WarnDeprecated(name string, message string) {
+	hook, _ := deprecationHook.Load().(DeprecationHook)
+	if hook == nil {
+		return
+	}
+	if _, reported := deprecationsReported.LoadOrStore(name, true); !reported {
+		hook(name, message)
+	}
+}
@@ -0,0 +1,32 @@
+package restclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarnDeprecated(t *testing.T) {
+	defer SetDeprecationHook(nil)
+
+	var reported []string
+	hook := func(name string, message string) {
+		reported = append(reported, name+": "+message)
+	}
+
+	// Nothing is reported, or remembered, without a hook
+	WarnDeprecated("GetPhotoRequestBuilder", "Use GetPhotoDetails")
+
+	SetDeprecationHook(hook)
+	WarnDeprecated("GetPhotoRequestBuilder", "Use GetPhotoDetails")
+	WarnDeprecated("GetPhotoRequestBuilder", "Use GetPhotoDetails")
+	WarnDeprecated("ListPhotosRequestBuilder.Page", "")
+	assert.Equal(t, []string{
+		"GetPhotoRequestBuilder: Use GetPhotoDetails",
+		"ListPhotosRequestBuilder.Page: ",
+	}, reported)
+
+	SetDeprecationHook(nil)
+	WarnDeprecated("SearchPhotosRequestBuilder", "")
+	assert.Len(t, reported, 2)
+}
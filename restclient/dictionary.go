@@ -0,0 +1,108 @@
+package restclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+const (
+	// DictionaryEncoding is the content encoding of bodies compressed with a zstd dictionary
+	DictionaryEncoding = "zstd-dict"
+	// DictionaryHeader names the dictionary a body is compressed with
+	DictionaryHeader = "Compression-Dictionary"
+)
+
+// Dictionary compresses and decompresses bodies with a compression dictionary.
+// The zstd implementation is provided by the restclient/zstddict package, which keeps the zstd
+// dependency out of clients which do not use dictionaries.
+type Dictionary interface {
+	Encode(data []byte) []byte
+	Decode(data []byte) ([]byte, error)
+}
+
+var dictionaries = struct {
+	sync.RWMutex
+	m map[string]Dictionary
+}{m: make(map[string]Dictionary)}
+
+// RegisterDictionary registers a compression dictionary under name. Requests built by request
+// builders annotated with @DICTIONARY(name) are compressed with the dictionary, and responses
+// which are compressed with the dictionary are decompressed.
+func RegisterDictionary(name string, d Dictionary) {
+	dictionaries.Lock()
+	dictionaries.m[name] = d
+	dictionaries.Unlock()
+}
+
+func getDictionary(name string) (Dictionary, error) {
+	dictionaries.RLock()
+	defer dictionaries.RUnlock()
+	d, ok := dictionaries.m[name]
+	if !ok {
+		return nil, fmt.Errorf("Compression dictionary %s has not been registered. You must call restclient.RegisterDictionary first", name)
+	}
+	return d, nil
+}
+
+// CompressRequest compresses the body of the request with the named dictionary and advertises
+// that a response compressed with the same dictionary is accepted.
+func CompressRequest(request *http.Request, name string) error {
+	d, err := getDictionary(name)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Accept-Encoding", DictionaryEncoding)
+	request.Header.Set(DictionaryHeader, name)
+	if request.Body == nil || request.Body == http.NoBody {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	compressed := d.Encode(data)
+	request.Body = ioutil.NopCloser(bytes.NewReader(compressed))
+	request.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	request.ContentLength = int64(len(compressed))
+	request.Header.Set("Content-Encoding", DictionaryEncoding)
+	return nil
+}
+
+// DecompressResponse replaces the body of the response with its decompressed body when the
+// body is compressed with a dictionary.
+func DecompressResponse(response *http.Response) error {
+	if response.Header.Get("Content-Encoding") != DictionaryEncoding {
+		return nil
+	}
+
+	d, err := getDictionary(response.Header.Get(DictionaryHeader))
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	decompressed, err := d.Decode(data)
+	if err != nil {
+		return err
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(decompressed))
+	response.ContentLength = int64(len(decompressed))
+	response.Header.Del("Content-Encoding")
+	response.Header.Del("Content-Length")
+	return nil
+}
@@ -0,0 +1,55 @@
+package restclient
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// BaseURLEnv is the environment variable containing the base URL of the client created by NewClientFromEnv
+	BaseURLEnv = "GOREST_BASE_URL"
+	// DebugEnv is the environment variable enabling the debug mode of the client, such as true or 1
+	DebugEnv = "GOREST_DEBUG"
+	// TimeoutEnv is the environment variable containing the timeout of the requests, such as 30s
+	TimeoutEnv = "GOREST_TIMEOUT"
+	// TokenEnv is the environment variable containing the bearer token sent in the Authorization header
+	TokenEnv = "GOREST_TOKEN"
+)
+
+// NewClientFromEnv returns a client configured by the GOREST_BASE_URL, GOREST_DEBUG, GOREST_TIMEOUT
+// and GOREST_TOKEN environment variables, of which only the base URL is required.
+func NewClientFromEnv() (*DefaultClient, error) {
+	baseURL := os.Getenv(BaseURLEnv)
+	if baseURL == "" {
+		return nil, fmt.Errorf("Environment variable %s must contain the base URL of the API", BaseURLEnv)
+	}
+
+	debug := false
+	if value := os.Getenv(DebugEnv); value != "" {
+		var err error
+		if debug, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("Invalid %s %q: %v", DebugEnv, value, err)
+		}
+	}
+
+	httpClient := &http.Client{}
+	if value := os.Getenv(TimeoutEnv); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s %q: %v", TimeoutEnv, value, err)
+		}
+		httpClient.Timeout = timeout
+	}
+
+	client := NewDefaultClient(baseURL, debug, httpClient)
+	if token := os.Getenv(TokenEnv); token != "" {
+		client.client.Transport = &headerTransport{
+			header:    http.Header{"Authorization": {"Bearer " + token}},
+			transport: client.client.Transport,
+		}
+	}
+	return client, nil
+}
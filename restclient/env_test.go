@@ -0,0 +1,45 @@
+package restclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClientFromEnv(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	t.Setenv(BaseURLEnv, "")
+	_, err := NewClientFromEnv()
+	assert.EqualError(t, err, "Environment variable GOREST_BASE_URL must contain the base URL of the API")
+
+	t.Setenv(BaseURLEnv, server.URL)
+	t.Setenv(DebugEnv, "yes")
+	_, err = NewClientFromEnv()
+	assert.EqualError(t, err, `Invalid GOREST_DEBUG "yes": strconv.ParseBool: parsing "yes": invalid syntax`)
+
+	t.Setenv(DebugEnv, "true")
+	t.Setenv(TimeoutEnv, "5s")
+	t.Setenv(TokenEnv, "secret")
+	client, err := NewClientFromEnv()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, server.URL, client.BaseURL())
+	assert.True(t, client.Debug())
+	assert.Equal(t, 5*time.Second, client.HttpClient().Timeout)
+
+	response, err := client.HttpClient().Get(server.URL)
+	if assert.NoError(t, err) {
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		assert.Equal(t, "Bearer secret", string(body))
+	}
+}
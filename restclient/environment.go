@@ -0,0 +1,170 @@
+package restclient
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ErrUnknownEnvironment is returned when an environment is selected before it is registered
+var ErrUnknownEnvironment = errors.New("Unknown environment")
+
+// Environment is a deployment of the API, such as prod, staging or sandbox, with its own base URL,
+// credentials and TLS settings
+type Environment struct {
+	BaseURL string
+	// Token is the bearer token sent in the Authorization header of the requests without one
+	Token string
+	// Header is sent with every request which does not set it, such as the API key of the environment
+	Header http.Header
+	// TLSConfig configures the TLS connections to the environment, such as the certificate
+	// authority of a staging environment or a client certificate
+	TLSConfig *tls.Config
+	// MaxBodySize is the largest response body accepted from the environment, which replaces the
+	// MaxBodySize of the registered client when set, see LimitBody
+	MaxBodySize int64
+	// HttpClient sends the requests to the environment, the zero http.Client is used when nil.
+	// Its transport must be nil or a *http.Transport when TLSConfig is set.
+	HttpClient *http.Client
+}
+
+// registeredEnvironment is a registered environment along with the client sending its requests,
+// which is shared by the requests to the environment so that they share connections
+type registeredEnvironment struct {
+	Environment
+	client *http.Client
+}
+
+var environments = struct {
+	sync.RWMutex
+	m       map[string]*registeredEnvironment
+	current string
+}{m: make(map[string]*registeredEnvironment)}
+
+// RegisterEnvironment registers the environment of the API named name, such as staging, which is
+// selected for every request with SetEnvironment or for the requests of a request builder with its
+// WithEnvironment method. Registering an environment again replaces it, the registered client
+// keeps sending requests to the environment it was set to until SetEnvironment is called again.
+func RegisterEnvironment(name string, environment Environment) {
+	client := &http.Client{}
+	if environment.HttpClient != nil {
+		*client = *environment.HttpClient
+	}
+	if environment.TLSConfig != nil {
+		client = tuneClient(client, []ClientOption{WithTLSConfig(environment.TLSConfig)})
+	}
+	header := http.Header{}
+	for key, values := range environment.Header {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+	if environment.Token != "" {
+		header.Set("Authorization", "Bearer "+environment.Token)
+	}
+	if len(header) > 0 {
+		client.Transport = &headerTransport{header: header, transport: client.Transport}
+	}
+
+	environments.Lock()
+	environments.m[name] = &registeredEnvironment{Environment: environment, client: client}
+	environments.Unlock()
+}
+
+func getEnvironment(name string) (*registeredEnvironment, error) {
+	environments.RLock()
+	defer environments.RUnlock()
+	environment, ok := environments.m[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %s, it must be registered with restclient.RegisterEnvironment", ErrUnknownEnvironment, name)
+	}
+	return environment, nil
+}
+
+// configuredClient returns the client sending requests to the environment, which is configured
+// like current, see configuredClient
+func (e *registeredEnvironment) configuredClient(current Client) *DefaultClient {
+	config := Config{BaseURL: e.BaseURL, HttpClient: e.client}
+	if current != nil {
+		config.Debug = current.Debug()
+	}
+	client := configuredClient(current, config)
+	if e.MaxBodySize != 0 {
+		client.SetMaxBodySize(e.MaxBodySize)
+	}
+	return client
+}
+
+// SetEnvironment points the registered client at the environment registered as name, keeping its
+// debug mode, user agent, maximum body size unless the environment sets one and API version
+func SetEnvironment(name string) error {
+	environment, err := getEnvironment(name)
+	if err != nil {
+		return err
+	}
+	replaceClient(func(current Client) Client {
+		return environment.configuredClient(current)
+	})
+	environments.Lock()
+	environments.current = name
+	environments.Unlock()
+	return nil
+}
+
+// CurrentEnvironment returns the name of the environment selected with SetEnvironment, or the empty
+// string when no environment has been selected
+func CurrentEnvironment() string {
+	environments.RLock()
+	defer environments.RUnlock()
+	return environments.current
+}
+
+// EnvironmentClient returns the client sending requests to the environment registered as name,
+// which is configured like the registered client, or the registered client when name is empty.
+// It is called by request builders to send their requests to the environment selected with their
+// WithEnvironment method.
+func EnvironmentClient(name string) (Client, error) {
+	current := GetClient()
+	if name == "" {
+		if current == nil {
+			return nil, ErrNoClient
+		}
+		return current, nil
+	}
+
+	environment, err := getEnvironment(name)
+	if err != nil {
+		return nil, err
+	}
+	return environment.configuredClient(current), nil
+}
+
+// headerTransport sets the headers which the requests do not set themselves
+type headerTransport struct {
+	header    http.Header
+	transport http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	transport := t.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	var missing []string
+	for key := range t.header {
+		if request.Header.Get(key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return transport.RoundTrip(request)
+	}
+	// A RoundTripper must not modify the request
+	request = request.Clone(request.Context())
+	for _, key := range missing {
+		request.Header[key] = append([]string(nil), t.header[key]...)
+	}
+	return transport.RoundTrip(request)
+}
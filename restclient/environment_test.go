@@ -0,0 +1,99 @@
+package restclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvironment(t *testing.T) {
+	defer RegisterClient(nil)
+
+	staging := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("Authorization")+" "+r.Header.Get("X-Api-Key"))
+	}))
+	defer staging.Close()
+
+	RegisterEnvironment("staging", Environment{
+		BaseURL:   staging.URL,
+		Token:     "staging-token",
+		Header:    http.Header{"x-api-key": {"staging-key"}},
+		TLSConfig: staging.Client().Transport.(*http.Transport).TLSClientConfig,
+	})
+	RegisterEnvironment("prod", Environment{BaseURL: "https://api.example.com"})
+
+	client := NewDefaultClient("https://api.example.com", false, http.DefaultClient)
+	client.SetUserAgent("photos/1.0")
+	RegisterClient(client)
+
+	assert.NoError(t, SetEnvironment("staging"))
+	assert.Equal(t, "staging", CurrentEnvironment())
+	assert.Equal(t, staging.URL, GetClient().BaseURL())
+	assert.Equal(t, "photos/1.0", UserAgent(GetClient()))
+
+	response, err := GetClient().HttpClient().Get(staging.URL)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	assert.Equal(t, "Bearer staging-token staging-key", string(body))
+
+	request, _ := http.NewRequest("GET", staging.URL, nil)
+	request.Header.Set("Authorization", "Bearer override")
+	response, err = GetClient().HttpClient().Do(request)
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(response.Body)
+	response.Body.Close()
+	assert.Equal(t, "Bearer override staging-key", string(body))
+	assert.Equal(t, "Bearer override", request.Header.Get("Authorization"))
+
+	assert.NoError(t, SetEnvironment("prod"))
+	assert.Equal(t, "https://api.example.com", GetClient().BaseURL())
+
+	err = SetEnvironment("sandbox")
+	assert.True(t, errors.Is(err, ErrUnknownEnvironment))
+	assert.Equal(t, "prod", CurrentEnvironment())
+}
+
+func TestEnvironmentClient(t *testing.T) {
+	defer RegisterClient(nil)
+
+	RegisterClient(nil)
+	_, err := EnvironmentClient("")
+	assert.Equal(t, ErrNoClient, err)
+
+	RegisterEnvironment("sandbox", Environment{BaseURL: "https://sandbox.example.com"})
+	client, err := EnvironmentClient("sandbox")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://sandbox.example.com", client.BaseURL())
+
+	registered := NewDefaultClient("https://api.example.com", true, http.DefaultClient)
+	registered.SetUserAgent("photos/1.0")
+	RegisterClient(registered)
+
+	client, err = EnvironmentClient("")
+	assert.NoError(t, err)
+	assert.Equal(t, registered, client)
+
+	client, err = EnvironmentClient("sandbox")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://sandbox.example.com", client.BaseURL())
+	assert.True(t, client.Debug())
+	assert.Equal(t, "photos/1.0", UserAgent(client))
+	assert.Equal(t, "https://api.example.com", GetClient().BaseURL())
+
+	RegisterEnvironment("limited", Environment{BaseURL: "https://limited.example.com", MaxBodySize: 1024})
+	client, err = EnvironmentClient("limited")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1024), MaxBodySize(client))
+	assert.Equal(t, int64(0), MaxBodySize(GetClient()))
+	assert.NoError(t, SetEnvironment("limited"))
+	assert.Equal(t, int64(1024), MaxBodySize(GetClient()))
+	assert.Equal(t, "photos/1.0", UserAgent(GetClient()))
+
+	_, err = EnvironmentClient("unknown")
+	assert.True(t, errors.Is(err, ErrUnknownEnvironment))
+}
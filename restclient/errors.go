@@ -0,0 +1,133 @@
+package restclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// ErrNoClient is returned when a request is built or sent before a client is registered
+var ErrNoClient = errors.New("A rest client has not been registered yet. You must call restclient.RegisterClient first")
+
+// ErrMissingParam matches a *MissingParamsError with errors.Is
+var ErrMissingParam = errors.New("Missing required parameter")
+
+// ErrMissingPathParam matches a *MissingParamsError missing a path parameter with errors.Is
+var ErrMissingPathParam = errors.New("Missing path parameter")
+
+// MissingParamsError is the error of a request built without some of its required parameters
+type MissingParamsError struct {
+	Request string
+	// Missing describes each missing parameter, for example "path parameter id"
+	Missing []string
+}
+
+func (e *MissingParamsError) Error() string {
+	return fmt.Sprintf("%s is missing required values: %s", e.Request, strings.Join(e.Missing, ", "))
+}
+
+func (e *MissingParamsError) Is(target error) bool {
+	switch target {
+	case ErrMissingParam:
+		return true
+	case ErrMissingPathParam:
+		for _, missing := range e.Missing {
+			if strings.HasPrefix(missing, "path parameter ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ErrFallback matches a *FallbackError with errors.Is
+var ErrFallback = errors.New("Request failed, the fallback response was returned")
+
+// FallbackError is returned along with the fallback response of a request builder, see WithFallback
+type FallbackError struct {
+	Err error
+}
+
+func (e *FallbackError) Error() string {
+	return fmt.Sprintf("Request failed, the fallback response was returned: %v", e.Err)
+}
+
+func (e *FallbackError) Is(target error) bool {
+	return target == ErrFallback
+}
+
+func (e *FallbackError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError is the error of a response body which could not be decoded in to the response type
+type DecodeError struct {
+	Type string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("Failed to decode response in to %s: %v", e.Type, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Decode decodes the response body with the constructor of the response type, returning a
+// *DecodeError when it fails. A *BodyTooLargeError of a limited body is returned as is.
+func Decode[T any](constructor func(io.Reader) (T, error), r io.Reader) (T, error) {
+	result, err := constructor(r)
+	if err != nil {
+		var tooLarge *BodyTooLargeError
+		if errors.As(err, &tooLarge) {
+			return result, err
+		}
+		return result, &DecodeError{Type: reflect.TypeOf((*T)(nil)).Elem().String(), Err: err}
+	}
+	return result, nil
+}
+
+// MaxErrorBodySize is the number of bytes of the body of an unsuccessful response kept by HTTPError
+const MaxErrorBodySize = 4096
+
+// HTTPError is the error of a request whose response has a status other than 2xx.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	// Body holds up to MaxErrorBodySize bytes of the body of the response
+	Body []byte
+}
+
+func (e *HTTPError) Error() string {
+	if len(e.Body) == 0 {
+		return fmt.Sprintf("Request failed with status %s", e.Status)
+	}
+	return fmt.Sprintf("Request failed with status %s: %s", e.Status, e.Body)
+}
+
+// CheckStatus returns an *HTTPError when the status of the response is not 2xx and is not one of
+// the allowed statuses. The error holds the start of the body, the body of the response is left
+// to be closed by the caller.
+func CheckStatus(response *http.Response, allowed ...int) error {
+	if response.StatusCode >= 200 && response.StatusCode <= 299 {
+		return nil
+	}
+	for _, status := range allowed {
+		if response.StatusCode == status {
+			return nil
+		}
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(response.Body, MaxErrorBodySize))
+	return &HTTPError{
+		StatusCode: response.StatusCode,
+		Status:     response.Status,
+		Header:     response.Header,
+		Body:       body,
+	}
+}
@@ -0,0 +1,90 @@
+package restclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckStatus(t *testing.T) {
+	response := func(status int, body string) *http.Response {
+		return &http.Response{
+			StatusCode: status,
+			Status:     http.StatusText(status),
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}
+	}
+
+	assert.NoError(t, CheckStatus(response(http.StatusNoContent, "")))
+	assert.NoError(t, CheckStatus(response(http.StatusNotFound, ""), http.StatusGone, http.StatusNotFound))
+
+	err := CheckStatus(response(http.StatusNotFound, `{"error":"missing"}`))
+	if httpErr, ok := err.(*HTTPError); assert.True(t, ok) {
+		assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+		assert.Equal(t, "application/json", httpErr.Header.Get("Content-Type"))
+		assert.Equal(t, `{"error":"missing"}`, string(httpErr.Body))
+		assert.Equal(t, `Request failed with status Not Found: {"error":"missing"}`, httpErr.Error())
+	}
+
+	// The body kept by the error is capped
+	err = CheckStatus(response(http.StatusInternalServerError, strings.Repeat("x", 2*MaxErrorBodySize)))
+	if httpErr, ok := err.(*HTTPError); assert.True(t, ok) {
+		assert.Len(t, httpErr.Body, MaxErrorBodySize)
+	}
+}
+
+func TestMissingParamsError(t *testing.T) {
+	var err error = &MissingParamsError{Request: "GetPhotoRequestBuilder", Missing: []string{"path parameter id", "header Authorization"}}
+	assert.Equal(t, "GetPhotoRequestBuilder is missing required values: path parameter id, header Authorization", err.Error())
+	assert.True(t, errors.Is(err, ErrMissingParam))
+	assert.True(t, errors.Is(err, ErrMissingPathParam))
+
+	err = fmt.Errorf("Failed to build request: %w", &MissingParamsError{Request: "GetPhotoRequestBuilder", Missing: []string{"query parameter page"}})
+	assert.True(t, errors.Is(err, ErrMissingParam))
+	assert.False(t, errors.Is(err, ErrMissingPathParam))
+	var missing *MissingParamsError
+	if assert.True(t, errors.As(err, &missing)) {
+		assert.Equal(t, []string{"query parameter page"}, missing.Missing)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	type Photo struct {
+		ID string `json:"id"`
+	}
+	newPhoto := func(r io.Reader) (*Photo, error) {
+		var photo Photo
+		return &photo, DecodeJSON(r, &photo)
+	}
+
+	photo, err := Decode(newPhoto, strings.NewReader(`{"id": "abc"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", photo.ID)
+
+	_, err = Decode(newPhoto, strings.NewReader(`{"id": 12}`))
+	var decodeErr *DecodeError
+	if assert.True(t, errors.As(err, &decodeErr)) {
+		assert.Equal(t, "*restclient.Photo", decodeErr.Type)
+		var typeErr *json.UnmarshalTypeError
+		assert.True(t, errors.As(err, &typeErr))
+	}
+}
+
+func TestFallbackError(t *testing.T) {
+	httpErr := &HTTPError{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable"}
+	var err error = &FallbackError{Err: httpErr}
+	assert.Equal(t, "Request failed, the fallback response was returned: Request failed with status 503 Service Unavailable", err.Error())
+	assert.True(t, errors.Is(err, ErrFallback))
+	var target *HTTPError
+	if assert.True(t, errors.As(err, &target)) {
+		assert.Equal(t, http.StatusServiceUnavailable, target.StatusCode)
+	}
+	assert.False(t, errors.Is(httpErr, ErrFallback))
+}
@@ -0,0 +1,49 @@
+package restclient
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// FormatExample returns the string representation of the example value of a request parameter,
+// as formatted by FormatParam. It is used by the generated example tests and panics when the value
+// cannot be formatted.
+func FormatExample(value interface{}) string {
+	s, err := FormatParam(value)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to format example %v: %v", value, err))
+	}
+	return s
+}
+
+// ParseExampleTime returns the time of an example value in the layout. It is used by the generated
+// example tests and panics when the value cannot be parsed.
+func ParseExampleTime(layout string, value string) time.Time {
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to parse example time %s: %v", value, err))
+	}
+	return t
+}
+
+// EncodeExampleQuery returns the query string of a request built with the query parameters and
+// the parameters which are already encoded, which are appended in the order of their keys.
+func EncodeExampleQuery(query url.Values, encoded url.Values) string {
+	s := query.Encode()
+	keys := make([]string, 0, len(encoded))
+	for key := range encoded {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range encoded[key] {
+			if s != "" {
+				s += "&"
+			}
+			s += url.QueryEscape(key) + "=" + value
+		}
+	}
+	return s
+}
@@ -0,0 +1,114 @@
+package restclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// GraphQLOperation is the operation sent by a GraphQL request. Either the query document or the
+// ID of a query persisted by the server is sent along with the variables of the operation.
+type GraphQLOperation struct {
+	Query     string                 `json:"query,omitempty"`
+	ID        string                 `json:"id,omitempty"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// NewGraphQLRequest returns a request sending the operation to the GraphQL endpoint url.
+// A POST request sends the operation as a JSON body and a GET request sends it as the query,
+// id and variables query parameters, where the variables are encoded as JSON.
+func NewGraphQLRequest(method string, url string, operation GraphQLOperation) (*http.Request, error) {
+	switch method {
+	case http.MethodPost:
+		body, err := MarshalJSON(operation)
+		if err != nil {
+			return nil, err
+		}
+		request, err := http.NewRequest(method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", "application/json")
+		return request, nil
+	case http.MethodGet:
+		request, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		query := request.URL.Query()
+		if operation.Query != "" {
+			query.Set("query", operation.Query)
+		}
+		if operation.ID != "" {
+			query.Set("id", operation.ID)
+		}
+		if len(operation.Variables) > 0 {
+			variables, err := MarshalJSON(operation.Variables)
+			if err != nil {
+				return nil, err
+			}
+			query.Set("variables", string(variables))
+		}
+		request.URL.RawQuery = query.Encode()
+		return request, nil
+	}
+	return nil, fmt.Errorf("GraphQL operations must be sent with GET or POST, got %s", method)
+}
+
+// AddGraphQLQuery appends the encoded query parameters rawQuery to the query of a GraphQL request
+func AddGraphQLQuery(request *http.Request, rawQuery string) {
+	AddRawQuery(request, rawQuery)
+}
+
+// GraphQLLocation is the line and column of the query document an error refers to
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// GraphQLError is an error reported by a GraphQL response.
+type GraphQLError struct {
+	Message   string            `json:"message"`
+	Locations []GraphQLLocation `json:"locations,omitempty"`
+	// Path is the path of the response field which failed, made of field names and list indexes
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLErrors is the error of a GraphQL response which reports errors.
+type GraphQLErrors struct {
+	Errors []GraphQLError
+	// Data holds the partial data of the response, or null when the operation failed entirely
+	Data json.RawMessage
+}
+
+func (e *GraphQLErrors) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Message
+	}
+	return fmt.Sprintf("GraphQL request failed: %s", strings.Join(messages, "; "))
+}
+
+// DecodeGraphQL reads the data/errors envelope of a GraphQL response and returns a reader of
+// the data, which is decoded in to the response type of the request builder.
+// Returns a *GraphQLErrors when the response reports errors.
+func DecodeGraphQL(r io.Reader) (io.Reader, error) {
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []GraphQLError  `json:"errors"`
+	}
+	if err := DecodeJSON(r, &envelope); err != nil {
+		return nil, &DecodeError{Type: "GraphQL response", Err: err}
+	}
+	if len(envelope.Errors) > 0 {
+		return nil, &GraphQLErrors{Errors: envelope.Errors, Data: envelope.Data}
+	}
+	if len(envelope.Data) == 0 || string(envelope.Data) == "null" {
+		return nil, fmt.Errorf("GraphQL response has no data")
+	}
+	return bytes.NewReader(envelope.Data), nil
+}
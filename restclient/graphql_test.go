@@ -0,0 +1,59 @@
+package restclient
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGraphQLRequest(t *testing.T) {
+	operation := GraphQLOperation{
+		Query:     "query($login: String!) { user(login: $login) { name } }",
+		Variables: map[string]interface{}{"login": "octocat"},
+	}
+
+	request, err := NewGraphQLRequest("POST", "https://api.example.com/graphql", operation)
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+	body, err := io.ReadAll(request.Body)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"query":"query($login: String!) { user(login: $login) { name } }","variables":{"login":"octocat"}}`, string(body))
+
+	request, err = NewGraphQLRequest("GET", "https://api.example.com/graphql?v=2", GraphQLOperation{ID: "GetUser", Variables: operation.Variables})
+	assert.NoError(t, err)
+	assert.Nil(t, request.Body)
+	assert.Equal(t, "2", request.URL.Query().Get("v"))
+	assert.Equal(t, "GetUser", request.URL.Query().Get("id"))
+	assert.Equal(t, `{"login":"octocat"}`, request.URL.Query().Get("variables"))
+	assert.Empty(t, request.URL.Query().Get("query"))
+
+	AddGraphQLQuery(request, "trace=1")
+	assert.Equal(t, "1", request.URL.Query().Get("trace"))
+
+	_, err = NewGraphQLRequest("PUT", "https://api.example.com/graphql", operation)
+	assert.Error(t, err)
+}
+
+func TestDecodeGraphQL(t *testing.T) {
+	data, err := DecodeGraphQL(strings.NewReader(`{"data":{"user":{"name":"The Octocat"}}}`))
+	assert.NoError(t, err)
+	var result struct {
+		User struct{ Name string }
+	}
+	assert.NoError(t, json.NewDecoder(data).Decode(&result))
+	assert.Equal(t, "The Octocat", result.User.Name)
+
+	_, err = DecodeGraphQL(strings.NewReader(`{"data":{"user":null},"errors":[{"message":"Not found","path":["user"],"locations":[{"line":1,"column":2}]}]}`))
+	if graphQLErr, ok := err.(*GraphQLErrors); assert.True(t, ok) {
+		assert.Equal(t, "GraphQL request failed: Not found", graphQLErr.Error())
+		assert.Equal(t, []interface{}{"user"}, graphQLErr.Errors[0].Path)
+		assert.Equal(t, []GraphQLLocation{{Line: 1, Column: 2}}, graphQLErr.Errors[0].Locations)
+		assert.JSONEq(t, `{"user":null}`, string(graphQLErr.Data))
+	}
+
+	_, err = DecodeGraphQL(strings.NewReader(`{"data":null}`))
+	assert.EqualError(t, err, "GraphQL response has no data")
+}
@@ -0,0 +1,297 @@
+package restclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultHARBodySize is the number of bytes of each request and response body kept by a
+// HARRecorder without a MaxBodySize
+const DefaultHARBodySize = 1 << 20
+
+// HARRecorder is a http.RoundTripper which records the requests sent with it and their responses,
+// along with their timings, in an HTTP Archive (HAR). The archive can be written at any time, for
+// example to attach it to a support ticket or to inspect the traffic in the developer tools of a
+// browser. The values of secret headers are redacted like in curl commands, see RedactedHeaders.
+// An exchange is recorded once the body of its response is read to the end or closed.
+type HARRecorder struct {
+	// Transport sends the requests, http.DefaultTransport is used when nil
+	Transport http.RoundTripper
+	// MaxBodySize is the number of bytes of each body kept, DefaultHARBodySize is used when 0
+	MaxBodySize int
+
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+// harTimings are the durations of the phases of an exchange in milliseconds
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+func (r *HARRecorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	entry := harEntry{
+		StartedDateTime: time.Now(),
+		Request: harRequest{
+			Method:      request.Method,
+			URL:         request.URL.String(),
+			HTTPVersion: request.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(request.Header),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    0,
+		},
+	}
+	if entry.Request.HTTPVersion == "" {
+		entry.Request.HTTPVersion = "HTTP/1.1"
+	}
+	for name, values := range request.URL.Query() {
+		for _, value := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{name, value})
+		}
+	}
+	sort.Slice(entry.Request.QueryString, func(i, j int) bool {
+		return entry.Request.QueryString[i].Name < entry.Request.QueryString[j].Name
+	})
+	if request.Body != nil && request.Body != http.NoBody {
+		body, err := io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		// The request is sent with a copy of the body, leaving the request unmodified
+		request = request.Clone(request.Context())
+		request.Body = io.NopCloser(bytes.NewReader(body))
+		entry.Request.BodySize = int64(len(body))
+		entry.Request.PostData = &harPostData{MimeType: request.Header.Get("Content-Type"), Text: r.bodyText(body)}
+	}
+
+	response, err := transport.RoundTrip(request)
+	wait := time.Since(entry.StartedDateTime)
+	entry.Timings.Wait = milliseconds(wait)
+	if err != nil {
+		entry.Time = entry.Timings.Wait
+		entry.Response = harResponse{Cookies: []harNameValue{}, Headers: []harNameValue{}, HeadersSize: -1, BodySize: -1}
+		entry.Error = err.Error()
+		r.record(entry)
+		return nil, err
+	}
+
+	entry.Response = harResponse{
+		Status:      response.StatusCode,
+		StatusText:  http.StatusText(response.StatusCode),
+		HTTPVersion: response.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(response.Header),
+		Content:     harContent{MimeType: response.Header.Get("Content-Type")},
+		RedirectURL: response.Header.Get("Location"),
+		HeadersSize: -1,
+	}
+	if response.StatusCode == http.StatusSwitchingProtocols {
+		// The body is the connection of another protocol, such as a WebSocket
+		entry.Time = entry.Timings.Wait
+		r.record(entry)
+		return response, nil
+	}
+	response.Body = &harBody{ReadCloser: response.Body, recorder: r, entry: entry, wait: wait}
+	return response, nil
+}
+
+// WriteTo writes the archive of the exchanges recorded so far as JSON
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	entries := append([]harEntry{}, r.entries...)
+	r.mu.Unlock()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+	})
+	data, err := json.MarshalIndent(struct {
+		Log harLog `json:"log"`
+	}{harLog{Version: "1.2", Creator: harCreator{Name: "gorest", Version: moduleVersion()}, Entries: entries}}, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// Flush writes the archive of the exchanges recorded so far to w and discards them, so the next
+// archive starts with the exchanges recorded after the flush
+func (r *HARRecorder) Flush(w io.Writer) error {
+	r.mu.Lock()
+	entries := r.entries
+	r.entries = nil
+	r.mu.Unlock()
+
+	flushed := &HARRecorder{entries: entries}
+	_, err := flushed.WriteTo(w)
+	return err
+}
+
+func (r *HARRecorder) record(entry harEntry) {
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+}
+
+// bodyText returns up to MaxBodySize bytes of the body as text, or the empty string for a binary body
+func (r *HARRecorder) bodyText(body []byte) string {
+	if max := r.maxBodySize(); len(body) > max {
+		body = body[:max]
+		// Drop a character cut in half by the limit
+		for i := 0; i < utf8.UTFMax && !utf8.Valid(body); i++ {
+			body = body[:len(body)-1]
+		}
+	}
+	if !utf8.Valid(body) {
+		return ""
+	}
+	return string(body)
+}
+
+// maxBodySize returns the number of bytes of each body kept
+func (r *HARRecorder) maxBodySize() int {
+	if r.MaxBodySize <= 0 {
+		return DefaultHARBodySize
+	}
+	return r.MaxBodySize
+}
+
+// harBody is the body of a recorded response, which records the exchange once it is read to the
+// end or closed
+type harBody struct {
+	io.ReadCloser
+	recorder *HARRecorder
+	entry    harEntry
+	wait     time.Duration
+	body     bytes.Buffer
+	size     int64
+	once     sync.Once
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if remaining := b.recorder.maxBodySize() - b.body.Len(); remaining > 0 {
+		b.body.Write(p[:min(n, remaining)])
+	}
+	if err == io.EOF {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	b.done()
+	return b.ReadCloser.Close()
+}
+
+func (b *harBody) done() {
+	b.once.Do(func() {
+		total := time.Since(b.entry.StartedDateTime)
+		b.entry.Time = milliseconds(total)
+		b.entry.Timings.Receive = milliseconds(total - b.wait)
+		b.entry.Response.BodySize = b.size
+		b.entry.Response.Content.Size = b.size
+		b.entry.Response.Content.Text = b.recorder.bodyText(b.body.Bytes())
+		b.recorder.record(b.entry)
+	})
+}
+
+// harHeaders returns the headers sorted by name, with the values of secret headers redacted
+func harHeaders(header http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for name, values := range header {
+		for _, value := range values {
+			if isSecretHeader(name) {
+				value = redactedValue
+			}
+			headers = append(headers, harNameValue{name, value})
+		}
+	}
+	sort.Slice(headers, func(i, j int) bool {
+		return headers[i].Name < headers[j].Name
+	})
+	return headers
+}
+
+// milliseconds returns the duration in milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,85 @@
+package restclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHARRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	recorder := &HARRecorder{Transport: server.Client().Transport}
+	client := &http.Client{Transport: recorder}
+
+	request, _ := http.NewRequest(http.MethodPost, server.URL+"/photos?tag=cat", strings.NewReader(`{"title":"Sunset"}`))
+	request.Header.Set("Authorization", "Bearer secret")
+	request.Header.Set("Content-Type", "application/json")
+	response, err := client.Do(request)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	assert.Equal(t, `{"title":"Sunset"}`, string(body))
+
+	var archive bytes.Buffer
+	_, err = recorder.WriteTo(&archive)
+	assert.NoError(t, err)
+
+	var har struct {
+		Log harLog `json:"log"`
+	}
+	assert.NoError(t, json.Unmarshal(archive.Bytes(), &har))
+	assert.Equal(t, "1.2", har.Log.Version)
+	if assert.Len(t, har.Log.Entries, 1) {
+		entry := har.Log.Entries[0]
+		assert.Equal(t, "POST", entry.Request.Method)
+		assert.Equal(t, []harNameValue{{"tag", "cat"}}, entry.Request.QueryString)
+		assert.Contains(t, entry.Request.Headers, harNameValue{"Authorization", "REDACTED"})
+		assert.Equal(t, &harPostData{MimeType: "application/json", Text: `{"title":"Sunset"}`}, entry.Request.PostData)
+		assert.Equal(t, http.StatusCreated, entry.Response.Status)
+		assert.Equal(t, harContent{Size: 18, MimeType: "application/json", Text: `{"title":"Sunset"}`}, entry.Response.Content)
+		assert.True(t, entry.Time >= entry.Timings.Wait)
+	}
+
+	// A flush discards the recorded exchanges
+	archive.Reset()
+	assert.NoError(t, recorder.Flush(&archive))
+	assert.Contains(t, archive.String(), `"url": "`+server.URL+`/photos?tag=cat"`)
+	archive.Reset()
+	_, err = recorder.WriteTo(&archive)
+	assert.NoError(t, err)
+	assert.Contains(t, archive.String(), `"entries": []`)
+}
+
+func TestHARRecorderError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	recorder := &HARRecorder{}
+	_, err := (&http.Client{Transport: recorder}).Get(server.URL)
+	assert.Error(t, err)
+	if assert.Len(t, recorder.entries, 1) {
+		assert.NotEmpty(t, recorder.entries[0].Error)
+		assert.Equal(t, 0, recorder.entries[0].Response.Status)
+	}
+}
+
+func TestHARBodyText(t *testing.T) {
+	recorder := &HARRecorder{MaxBodySize: 4}
+	assert.Equal(t, "abcd", recorder.bodyText([]byte("abcdef")))
+	// A character cut in half by the limit is dropped
+	assert.Equal(t, "abc", recorder.bodyText([]byte("abcé")))
+	assert.Equal(t, "", recorder.bodyText([]byte{0xff, 0xfe}))
+}
@@ -0,0 +1,123 @@
+package restclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HedgePolicy describes how a request is hedged. When no response is received within After,
+// an identical request is sent, up to Max requests in total.
+type HedgePolicy struct {
+	After time.Duration
+	Max   int
+}
+
+// DoHedged sends the request with client and sends identical requests according to the policy
+// when the previous requests are slow to respond. The first successful response is returned and
+// the other requests are cancelled. A response is successful when its status is below 500.
+// When every request fails the last failure is returned.
+// Only requests which are safe to send more than once, such as GET requests, should be hedged.
+func DoHedged(client *http.Client, request *http.Request, policy HedgePolicy) (*http.Response, error) {
+	if policy.Max < 2 || request.Body != nil && request.Body != http.NoBody && request.GetBody == nil {
+		return client.Do(request)
+	}
+
+	type attempt struct {
+		index    int
+		response *http.Response
+		err      error
+	}
+	attempts := make(chan attempt, policy.Max)
+	var cancels []context.CancelFunc
+	send := func() {
+		ctx, cancel := context.WithCancel(request.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		hedged := request.Clone(ctx)
+		if request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				attempts <- attempt{index: index, err: err}
+				return
+			}
+			hedged.Body = body
+		}
+		go func() {
+			response, err := client.Do(hedged)
+			attempts <- attempt{index: index, response: response, err: err}
+		}()
+	}
+
+	send()
+	timer := time.NewTimer(policy.After)
+	defer timer.Stop()
+
+	var last *attempt
+	for received := 0; received < len(cancels); {
+		select {
+		case <-timer.C:
+			if len(cancels) < policy.Max {
+				send()
+				timer.Reset(policy.After)
+			}
+		case a := <-attempts:
+			received++
+			if last != nil {
+				closeAttempt(last.response, cancels[last.index])
+			}
+			last = &a
+			if a.err == nil && a.response.StatusCode < 500 {
+				// Cancel the slower requests and discard their responses
+				for i, cancel := range cancels {
+					if i != a.index {
+						cancel()
+					}
+				}
+				go func(pending int) {
+					for ; pending > 0; pending-- {
+						loser := <-attempts
+						closeAttempt(loser.response, cancels[loser.index])
+					}
+				}(len(cancels) - received)
+				return withCancel(a.response, cancels[a.index]), nil
+			}
+			if received == len(cancels) && len(cancels) < policy.Max {
+				// Every request failed, send the next one right away
+				send()
+				timer.Reset(policy.After)
+			}
+		}
+	}
+	if last.err != nil {
+		cancels[last.index]()
+		return nil, last.err
+	}
+	return withCancel(last.response, cancels[last.index]), nil
+}
+
+// closeAttempt discards the response of a request which was not chosen
+func closeAttempt(response *http.Response, cancel context.CancelFunc) {
+	if response != nil {
+		response.Body.Close()
+	}
+	cancel()
+}
+
+// withCancel cancels the context of the request once the body of the response is closed
+func withCancel(response *http.Response, cancel context.CancelFunc) *http.Response {
+	response.Body = &cancelOnClose{ReadCloser: response.Body, cancel: cancel}
+	return response
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
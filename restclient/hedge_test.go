@@ -0,0 +1,98 @@
+package restclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoHedgedSlowAttempt(t *testing.T) {
+	var requests int32
+	cancelled := make(chan int32, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		if n < 3 {
+			// The first attempts are slow and are cancelled once the last attempt responds
+			<-r.Context().Done()
+			cancelled <- n
+			return
+		}
+		io.WriteString(w, "fast")
+	}))
+	defer server.Close()
+
+	request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	response, err := DoHedged(server.Client(), request, HedgePolicy{After: 20 * time.Millisecond, Max: 3})
+	if assert.NoError(t, err) {
+		body, err := io.ReadAll(response.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "fast", string(body))
+		response.Body.Close()
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("Slower attempts were not cancelled")
+		}
+	}
+}
+
+func TestDoHedgedServerError(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	// A failed attempt is retried without waiting for the hedging delay
+	request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	start := time.Now()
+	response, err := DoHedged(server.Client(), request, HedgePolicy{After: time.Minute, Max: 2})
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		response.Body.Close()
+	}
+	assert.True(t, time.Since(start) < time.Minute)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestDoHedgedAllAttemptsFail(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	// The last failed response is returned
+	request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	response, err := DoHedged(server.Client(), request, HedgePolicy{After: time.Minute, Max: 3})
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+		response.Body.Close()
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	// The last error is returned when no attempt receives a response
+	server.Close()
+	request, err = http.NewRequest(http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	response, err = DoHedged(server.Client(), request, HedgePolicy{After: time.Minute, Max: 3})
+	assert.Error(t, err)
+	assert.Nil(t, response)
+}
@@ -0,0 +1,18 @@
+package restclient
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// NewIdempotencyKey returns a random version 4 UUID identifying a request, which lets the server
+// recognize the attempts of a request which is sent more than once, such as by a retrying transport.
+func NewIdempotencyKey() string {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		panic(fmt.Sprintf("Failed to generate idempotency key: %v", err))
+	}
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
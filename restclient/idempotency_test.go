@@ -0,0 +1,19 @@
+package restclient
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewIdempotencyKey(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	keys := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		key := NewIdempotencyKey()
+		assert.Regexp(t, uuid, key)
+		keys[key] = true
+	}
+	assert.Len(t, keys, 100)
+}
@@ -0,0 +1,37 @@
+package restclient
+
+import (
+	"bytes"
+	"mime/multipart"
+	"sort"
+)
+
+// MultipartBody encodes the parts as a multipart/form-data body, in the order of their names, and
+// returns the body along with its content type. The buffer is sized for the parts up front, so
+// that the body is allocated once rather than grown while the parts are written.
+func MultipartBody(parts map[string][]byte) ([]byte, string, error) {
+	names := make([]string, 0, len(parts))
+	size := 0
+	for name, part := range parts {
+		names = append(names, name)
+		// The boundary and the headers of a part take up about 150 bytes
+		size += len(name) + len(part) + 150
+	}
+	sort.Strings(names)
+
+	buffer := bytes.NewBuffer(make([]byte, 0, size))
+	writer := multipart.NewWriter(buffer)
+	for _, name := range names {
+		part, err := writer.CreateFormField(name)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(parts[name]); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buffer.Bytes(), writer.FormDataContentType(), nil
+}
@@ -0,0 +1,48 @@
+package restclient
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultipartBody(t *testing.T) {
+	body, contentType, err := MultipartBody(map[string][]byte{
+		"photo_id": []byte("42"),
+		"caption":  []byte("Sunset, Bali"),
+	})
+	assert.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/form-data", mediaType)
+
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	var names, values []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		value, _ := io.ReadAll(part)
+		names = append(names, part.FormName())
+		values = append(values, string(value))
+	}
+	assert.Equal(t, []string{"caption", "photo_id"}, names)
+	assert.Equal(t, []string{"Sunset, Bali", "42"}, values)
+
+	// Binary parts are sent as is
+	binary := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	body, contentType, err = MultipartBody(map[string][]byte{"thumbnail": binary})
+	assert.NoError(t, err)
+	_, params, _ = mime.ParseMediaType(contentType)
+	part, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).NextPart()
+	assert.NoError(t, err)
+	value, _ := io.ReadAll(part)
+	assert.Equal(t, binary, value)
+}
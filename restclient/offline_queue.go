@@ -0,0 +1,466 @@
+package restclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMinReplayBackoff is the time waited after the first failed replay of an OfflineQueue
+	DefaultMinReplayBackoff = time.Second
+	// DefaultMaxReplayBackoff is the longest time waited between two replays of an OfflineQueue
+	DefaultMaxReplayBackoff = 5 * time.Minute
+)
+
+// QueuedRequest is a write request which failed and is persisted by an OfflineQueue to be replayed.
+type QueuedRequest struct {
+	ID     string      `json:"id"`
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body,omitempty"`
+	Queued time.Time   `json:"queued"`
+	// Attempts is the number of times the request was replayed without success
+	Attempts int `json:"attempts"`
+}
+
+// QueueStore persists the requests of an OfflineQueue. Implementations must be safe for concurrent use.
+type QueueStore interface {
+	// Append adds the request to the end of the queue
+	Append(request QueuedRequest) error
+	// List returns the requests of the queue in the order they were appended
+	List() ([]QueuedRequest, error)
+	// Update replaces the request with the same ID
+	Update(request QueuedRequest) error
+	// Remove removes the request with the ID from the queue
+	Remove(id string) error
+}
+
+// ConflictResolution is the outcome of a replayed request which was rejected by the server.
+type ConflictResolution int
+
+const (
+	// DropRequest removes the request from the queue
+	DropRequest ConflictResolution = iota
+	// RetryRequest keeps the request, as modified by the conflict hook, at the head of the queue and
+	// replays it after a backoff
+	RetryRequest
+)
+
+// QueuedError is the error of a write request which failed and was queued to be replayed later.
+type QueuedError struct {
+	// ID is the ID of the queued request
+	ID string
+	// Err is the error of the request, or nil when the request was queued behind requests waiting to
+	// be replayed without being sent
+	Err error
+}
+
+func (e *QueuedError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("Request queued for replay as %s", e.ID)
+	}
+	return fmt.Sprintf("Request queued for replay as %s: %v", e.ID, e.Err)
+}
+
+// OfflineQueue is a http.RoundTripper which persists the POST, PUT and PATCH requests failing with a
+// network error or a 5xx status in a QueueStore and replays them in order once the server can be
+// reached again, which suits deployments with flaky connectivity. A queued request fails with a
+// *QueuedError. While requests are waiting to be replayed, new write requests are queued behind them
+// without being sent, so that the writes reach the server in the order they were made.
+// The requests are replayed by Run, which is started in its own goroutine. Queued requests are
+// persisted with their headers, including credentials.
+type OfflineQueue struct {
+	// Transport sends the requests, http.DefaultTransport is used when nil
+	Transport http.RoundTripper
+	// Store persists the queued requests, a MemoryQueueStore is used when nil
+	Store QueueStore
+	// MinBackoff is the time waited after a failed replay, which doubles after each failed replay up
+	// to MaxBackoff. They default to DefaultMinReplayBackoff and DefaultMaxReplayBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// OnConflict is called with a replayed request rejected with a 4xx status and its response. It
+	// resolves the conflict by modifying the request and retrying it, or by dropping it, which is the
+	// default when nil.
+	OnConflict func(request *QueuedRequest, response *http.Response) ConflictResolution
+	// OnReplay is called with a replayed request and its successful response
+	OnReplay func(request QueuedRequest, response *http.Response)
+
+	once     sync.Once
+	store    QueueStore
+	notify   chan struct{}
+	replayMu sync.Mutex
+}
+
+func (q *OfflineQueue) RoundTrip(request *http.Request) (*http.Response, error) {
+	transport := q.transport()
+	if request.Method != http.MethodPost && request.Method != http.MethodPut && request.Method != http.MethodPatch {
+		return transport.RoundTrip(request)
+	}
+	if err := request.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	var body []byte
+	if request.Body != nil && request.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pending, err := q.getStore().List()
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) > 0 {
+		return nil, q.enqueue(request, body, nil)
+	}
+
+	// The request is sent with a copy of the body, leaving the request unmodified
+	send := request.Clone(request.Context())
+	send.Body = io.NopCloser(bytes.NewReader(body))
+	response, err := transport.RoundTrip(send)
+	if err != nil {
+		if request.Context().Err() != nil {
+			// The caller gave up on the request
+			return nil, err
+		}
+		return nil, q.enqueue(request, body, err)
+	}
+	if response.StatusCode >= 500 {
+		io.Copy(io.Discard, io.LimitReader(response.Body, MaxErrorBodySize))
+		response.Body.Close()
+		return nil, q.enqueue(request, body, fmt.Errorf("Request failed with status %s", response.Status))
+	}
+	return response, nil
+}
+
+// Run replays the queued requests until the context is done, waiting for requests to be queued when
+// the queue is empty. A failed replay is retried after a backoff. Returns the error of the context.
+func (q *OfflineQueue) Run(ctx context.Context) error {
+	q.getStore()
+	backoff := q.MinBackoff
+	if backoff <= 0 {
+		backoff = DefaultMinReplayBackoff
+	}
+	maxBackoff := q.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxReplayBackoff
+	}
+
+	wait := backoff
+	for {
+		err := q.Replay(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil {
+			wait = backoff
+			select {
+			case <-q.notify:
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if wait *= 2; wait > maxBackoff {
+			wait = maxBackoff
+		}
+	}
+}
+
+// Replay replays the queued requests in order until the queue is empty. Returns the error of the
+// first request which could not be replayed, which is left at the head of the queue.
+func (q *OfflineQueue) Replay(ctx context.Context) error {
+	q.replayMu.Lock()
+	defer q.replayMu.Unlock()
+
+	store := q.getStore()
+	for {
+		requests, err := store.List()
+		if err != nil {
+			return err
+		}
+		if len(requests) == 0 {
+			return nil
+		}
+
+		queued := requests[0]
+		response, err := q.send(ctx, queued)
+		if err != nil {
+			queued.Attempts++
+			if err := store.Update(queued); err != nil {
+				return err
+			}
+			return err
+		}
+
+		switch {
+		case response.StatusCode >= 500:
+			response.Body.Close()
+			queued.Attempts++
+			if err := store.Update(queued); err != nil {
+				return err
+			}
+			return fmt.Errorf("Replay of %s %s failed with status %s", queued.Method, queued.URL, response.Status)
+		case response.StatusCode >= 400:
+			resolution := DropRequest
+			if q.OnConflict != nil {
+				resolution = q.OnConflict(&queued, response)
+			}
+			response.Body.Close()
+			if resolution == RetryRequest {
+				queued.Attempts++
+				if err := store.Update(queued); err != nil {
+					return err
+				}
+				return fmt.Errorf("Replay of %s %s conflicted with status %s", queued.Method, queued.URL, response.Status)
+			}
+		default:
+			if q.OnReplay != nil {
+				q.OnReplay(queued, response)
+			}
+			response.Body.Close()
+		}
+		if err := store.Remove(queued.ID); err != nil {
+			return err
+		}
+	}
+}
+
+// send sends a queued request
+func (q *OfflineQueue) send(ctx context.Context, queued QueuedRequest) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, queued.Method, queued.URL, bytes.NewReader(queued.Body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header = queued.Header.Clone()
+	if request.Header == nil {
+		request.Header = http.Header{}
+	}
+	return q.transport().RoundTrip(request)
+}
+
+// enqueue persists the request with its body and returns the error the request fails with
+func (q *OfflineQueue) enqueue(request *http.Request, body []byte, cause error) error {
+	queued := QueuedRequest{
+		ID:     NewIdempotencyKey(),
+		Method: request.Method,
+		URL:    request.URL.String(),
+		Header: request.Header.Clone(),
+		Body:   body,
+		Queued: time.Now(),
+	}
+	if err := q.getStore().Append(queued); err != nil {
+		return fmt.Errorf("Failed to queue request: %v", err)
+	}
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return &QueuedError{ID: queued.ID, Err: cause}
+}
+
+func (q *OfflineQueue) transport() http.RoundTripper {
+	if q.Transport == nil {
+		return http.DefaultTransport
+	}
+	return q.Transport
+}
+
+// getStore returns the store of the queue, setting up the queue on first use
+func (q *OfflineQueue) getStore() QueueStore {
+	q.once.Do(func() {
+		q.store = q.Store
+		if q.store == nil {
+			q.store = &MemoryQueueStore{}
+		}
+		q.notify = make(chan struct{}, 1)
+	})
+	return q.store
+}
+
+// MemoryQueueStore is a QueueStore keeping the requests in memory, which does not survive a restart.
+type MemoryQueueStore struct {
+	mu       sync.Mutex
+	requests []QueuedRequest
+}
+
+func (s *MemoryQueueStore) Append(request QueuedRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, request)
+	return nil
+}
+
+func (s *MemoryQueueStore) List() ([]QueuedRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]QueuedRequest(nil), s.requests...), nil
+}
+
+func (s *MemoryQueueStore) Update(request QueuedRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.requests {
+		if s.requests[i].ID == request.ID {
+			s.requests[i] = request
+			return nil
+		}
+	}
+	return fmt.Errorf("No queued request %s", request.ID)
+}
+
+func (s *MemoryQueueStore) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.requests {
+		if s.requests[i].ID == id {
+			s.requests = append(s.requests[:i], s.requests[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// FileQueueStore is a QueueStore keeping each request in a JSON file of a directory, which survives
+// a restart of the application.
+type FileQueueStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileQueueStore returns a store keeping the requests in the directory dir, which is created when
+// it does not exist.
+func NewFileQueueStore(dir string) (*FileQueueStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileQueueStore{dir: dir}, nil
+}
+
+func (s *FileQueueStore) Append(request QueuedRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Files are named after the time the request was queued, so that they are listed in order
+	name := fmt.Sprintf("%020d-%s.json", request.Queued.UnixNano(), request.ID)
+	return s.write(name, request)
+}
+
+func (s *FileQueueStore) List() ([]QueuedRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names, err := s.names()
+	if err != nil {
+		return nil, err
+	}
+	requests := make([]QueuedRequest, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		var request QueuedRequest
+		if err := json.Unmarshal(data, &request); err != nil {
+			return nil, fmt.Errorf("Invalid queued request %s: %v", name, err)
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+func (s *FileQueueStore) Update(request QueuedRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name, err := s.find(request.ID)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("No queued request %s", request.ID)
+	}
+	return s.write(name, request)
+}
+
+func (s *FileQueueStore) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name, err := s.find(id)
+	if err != nil || name == "" {
+		return err
+	}
+	return os.Remove(filepath.Join(s.dir, name))
+}
+
+// write writes the request to the file name, replacing the file atomically
+func (s *FileQueueStore) write(name string, request QueuedRequest) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".queued-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, name))
+}
+
+// names returns the names of the files of the queued requests in order
+func (s *FileQueueStore) names() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// find returns the name of the file of the request with the ID, or the empty string when there is none
+func (s *FileQueueStore) find(id string) (string, error) {
+	names, err := s.names()
+	if err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		if strings.HasSuffix(name, "-"+id+".json") {
+			return name, nil
+		}
+	}
+	return "", nil
+}
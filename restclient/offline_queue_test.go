@@ -0,0 +1,203 @@
+package restclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// offlineServer records the bodies of the requests it accepts, and fails them with 503 Service
+// Unavailable while offline
+type offlineServer struct {
+	*httptest.Server
+	online   atomic.Bool
+	mu       sync.Mutex
+	received []string
+}
+
+func newOfflineServer() *offlineServer {
+	s := &offlineServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.online.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.received = append(s.received, r.Method+" "+string(body))
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	return s
+}
+
+func (s *offlineServer) bodies() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.received...)
+}
+
+func TestOfflineQueue(t *testing.T) {
+	server := newOfflineServer()
+	defer server.Close()
+
+	var replayed []string
+	store := &MemoryQueueStore{}
+	queue := &OfflineQueue{Store: store, OnReplay: func(request QueuedRequest, response *http.Response) {
+		replayed = append(replayed, string(request.Body))
+	}}
+	client := &http.Client{Transport: queue}
+
+	_, err := client.Post(server.URL, "text/plain", strings.NewReader("first"))
+	var queued *QueuedError
+	if assert.True(t, errors.As(err, &queued)) {
+		assert.EqualError(t, queued.Err, "Request failed with status 503 Service Unavailable")
+	}
+
+	// A write is queued behind the pending writes without being sent, reads are sent
+	server.online.Store(true)
+	request, _ := http.NewRequest(http.MethodPut, server.URL, strings.NewReader("second"))
+	_, err = client.Do(request)
+	if assert.True(t, errors.As(err, &queued)) {
+		assert.Nil(t, queued.Err)
+	}
+	response, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, []string{"GET "}, server.bodies())
+
+	server.online.Store(false)
+	assert.EqualError(t, queue.Replay(context.Background()), "Replay of POST "+server.URL+" failed with status 503 Service Unavailable")
+	requests, _ := store.List()
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, 1, requests[0].Attempts)
+		assert.Equal(t, "text/plain", requests[0].Header.Get("Content-Type"))
+	}
+
+	server.online.Store(true)
+	assert.NoError(t, queue.Replay(context.Background()))
+	assert.Equal(t, []string{"GET ", "POST first", "PUT second"}, server.bodies())
+	assert.Equal(t, []string{"first", "second"}, replayed)
+	requests, _ = store.List()
+	assert.Empty(t, requests)
+}
+
+func TestOfflineQueueNetworkError(t *testing.T) {
+	server := newOfflineServer()
+	server.Close()
+
+	store := &MemoryQueueStore{}
+	queue := &OfflineQueue{Store: store}
+	_, err := (&http.Client{Transport: queue}).Post(server.URL, "text/plain", strings.NewReader("body"))
+	var queued *QueuedError
+	if assert.True(t, errors.As(err, &queued)) {
+		assert.Error(t, queued.Err)
+	}
+
+	// A request given up by the caller is not queued
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	request, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader("body"))
+	_, err = (&http.Client{Transport: queue}).Do(request)
+	assert.False(t, errors.As(err, &queued))
+	requests, _ := store.List()
+	assert.Len(t, requests, 1)
+}
+
+func TestOfflineQueueConflict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Match") != "v2" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store := &MemoryQueueStore{}
+	store.Append(QueuedRequest{ID: "1", Method: http.MethodPut, URL: server.URL, Header: http.Header{"If-Match": {"v1"}}})
+	store.Append(QueuedRequest{ID: "2", Method: http.MethodPut, URL: server.URL + "/dropped"})
+
+	var conflicts int
+	queue := &OfflineQueue{Store: store, OnConflict: func(request *QueuedRequest, response *http.Response) ConflictResolution {
+		conflicts++
+		if request.ID == "1" {
+			request.Header.Set("If-Match", "v2")
+			return RetryRequest
+		}
+		return DropRequest
+	}}
+
+	assert.EqualError(t, queue.Replay(context.Background()), "Replay of PUT "+server.URL+" conflicted with status 412 Precondition Failed")
+	requests, _ := store.List()
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, "v2", requests[0].Header.Get("If-Match"))
+	}
+
+	assert.NoError(t, queue.Replay(context.Background()))
+	assert.Equal(t, 2, conflicts)
+	requests, _ = store.List()
+	assert.Empty(t, requests)
+}
+
+func TestOfflineQueueRun(t *testing.T) {
+	server := newOfflineServer()
+	defer server.Close()
+
+	queue := &OfflineQueue{MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- queue.Run(ctx)
+	}()
+
+	_, err := (&http.Client{Transport: queue}).Post(server.URL, "text/plain", strings.NewReader("offline"))
+	assert.Error(t, err)
+	time.Sleep(10 * time.Millisecond)
+	server.online.Store(true)
+	assert.Eventually(t, func() bool {
+		return len(server.bodies()) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"POST offline"}, server.bodies())
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
+
+func TestFileQueueStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileQueueStore(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	now := time.Now()
+	assert.NoError(t, store.Append(QueuedRequest{ID: "b", Method: http.MethodPost, Queued: now.Add(time.Second)}))
+	assert.NoError(t, store.Append(QueuedRequest{ID: "a", Method: http.MethodPut, Body: []byte("body"), Queued: now}))
+	assert.NoError(t, store.Update(QueuedRequest{ID: "a", Method: http.MethodPut, Body: []byte("body"), Queued: now, Attempts: 2}))
+	assert.EqualError(t, store.Update(QueuedRequest{ID: "c"}), "No queued request c")
+
+	// The requests survive a restart
+	store, _ = NewFileQueueStore(dir)
+	requests, err := store.List()
+	assert.NoError(t, err)
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, "a", requests[0].ID)
+		assert.Equal(t, []byte("body"), requests[0].Body)
+		assert.Equal(t, 2, requests[0].Attempts)
+		assert.Equal(t, "b", requests[1].ID)
+	}
+
+	assert.NoError(t, store.Remove("a"))
+	assert.NoError(t, store.Remove("a"))
+	requests, _ = store.List()
+	assert.Len(t, requests, 1)
+}
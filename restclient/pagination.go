@@ -0,0 +1,40 @@
+package restclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PageValue returns the value of the field of a JSON response which is used to request the next page
+// of a paginated response. Fields of nested objects are separated by a dot, for example meta.next_cursor.
+// The empty string is returned when the field is absent or null.
+func PageValue(data []byte, field string) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return "", err
+	}
+
+	for _, key := range strings.Split(field, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return "", nil
+		}
+		value = object[key]
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number, bool:
+		return fmt.Sprintf("%v", v), nil
+	default:
+		return "", fmt.Errorf("Pagination field %s must be a string, number or boolean", field)
+	}
+}
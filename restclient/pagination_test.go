@@ -0,0 +1,37 @@
+package restclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageValue(t *testing.T) {
+	data := []byte(`{"next_page":3,"more":true,"meta":{"next_cursor":"abc","last":null},"items":[],"cursor":{"id":1}}`)
+
+	for field, expected := range map[string]string{
+		"next_page":        "3",
+		"more":             "true",
+		"meta.next_cursor": "abc",
+		"meta.last":        "",
+		"missing":          "",
+		"meta.missing":     "",
+		"next_page.id":     "",
+	} {
+		value, err := PageValue(data, field)
+		assert.NoError(t, err, field)
+		assert.Equal(t, expected, value, field)
+	}
+
+	// Large numbers are kept as they are encoded
+	value, err := PageValue([]byte(`{"next_id":12345678901234567890}`), "next_id")
+	assert.NoError(t, err)
+	assert.Equal(t, "12345678901234567890", value)
+
+	_, err = PageValue(data, "cursor")
+	assert.EqualError(t, err, "Pagination field cursor must be a string, number or boolean")
+	_, err = PageValue(data, "items")
+	assert.Error(t, err)
+	_, err = PageValue([]byte(`{"next_page":`), "next_page")
+	assert.Error(t, err)
+}
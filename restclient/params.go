@@ -0,0 +1,32 @@
+package restclient
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+)
+
+// FormatParam returns the string representation of a request parameter.
+// Values implementing encoding.TextMarshaler are marshalled, values implementing fmt.Stringer
+// are converted with their String method and all other values are formatted with the %v verb.
+// A nil pointer is represented by the empty string.
+func FormatParam(value interface{}) (string, error) {
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr && v.IsNil() {
+		return "", nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return string(text), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	default:
+		return fmt.Sprintf("%v", v), nil
+	}
+}
@@ -0,0 +1,53 @@
+package restclient
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type visibility int
+
+func (v visibility) String() string {
+	if v == 0 {
+		return "private"
+	}
+	return "public"
+}
+
+type invalidText struct{}
+
+func (invalidText) MarshalText() ([]byte, error) {
+	return nil, errors.New("Invalid text")
+}
+
+func TestFormatParam(t *testing.T) {
+	var nilTime *time.Time
+	date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, test := range []struct {
+		value    interface{}
+		expected string
+	}{
+		{"sunset", "sunset"},
+		{42, "42"},
+		{1.5, "1.5"},
+		{true, "true"},
+		{date, "2020-01-02T03:04:05Z"},
+		{&date, "2020-01-02T03:04:05Z"},
+		{nilTime, ""},
+		{net.ParseIP("127.0.0.1"), "127.0.0.1"},
+		{visibility(1), "public"},
+		{time.Second, "1s"},
+	} {
+		value, err := FormatParam(test.value)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, value)
+	}
+
+	_, err := FormatParam(invalidText{})
+	assert.EqualError(t, err, "Invalid text")
+}
@@ -0,0 +1,124 @@
+package restclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MaxPollInterval is the longest time waited between two polls of an operation
+const MaxPollInterval = time.Minute
+
+// PollPolicy describes how a long running operation is polled until it reaches a terminal state.
+type PollPolicy struct {
+	// StatusField is the field of the JSON response containing the state of the operation.
+	// Fields of nested objects are separated by a dot, for example metadata.state.
+	StatusField string
+	// Until are the terminal states of the operation
+	Until []string
+	// Interval is the time waited before the first poll, which grows by half after each poll
+	// up to MaxPollInterval. Defaults to a second. The interval is not randomized, so operations
+	// started at the same time are polled at the same time.
+	Interval time.Duration
+	// Timeout is the time after which polling gives up, polling continues until the context is done when 0
+	Timeout time.Duration
+}
+
+// OperationURL returns the URL polled for the state of the operation started by the request of
+// response, whose body is data. The URL is taken from the response field when field is set,
+// otherwise from the Location header of the response. The URL of the request is polled when the
+// response has neither.
+func OperationURL(response *http.Response, data []byte, field string) (string, error) {
+	location := response.Header.Get("Location")
+	if field != "" {
+		value, err := PageValue(data, field)
+		if err != nil {
+			return "", err
+		}
+		if value == "" {
+			return "", fmt.Errorf("Response has no operation URL in field %s", field)
+		}
+		location = value
+	}
+	if location == "" {
+		return response.Request.URL.String(), nil
+	}
+
+	operation, err := response.Request.URL.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("Invalid operation URL %s: %v", location, err)
+	}
+	return operation.String(), nil
+}
+
+// sensitiveHeaders are the headers which are not sent to the host of an operation when it differs
+// from the host of the request which started it, as net/http does when following redirects
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Cookie2", "Idempotency-Key"}
+
+// NewPollRequest returns a GET request of url with the headers of the request which started the
+// operation, other than the headers describing its body. The credentials of the request are only
+// sent when url has the host of the request, so that they do not leak to another host.
+func NewPollRequest(request *http.Request, url string) (*http.Request, error) {
+	poll, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	poll.Header = request.Header.Clone()
+	for _, key := range []string{"Content-Type", "Content-Length", "Content-Encoding"} {
+		poll.Header.Del(key)
+	}
+	if !strings.EqualFold(poll.URL.Host, request.URL.Host) {
+		for _, key := range sensitiveHeaders {
+			poll.Header.Del(key)
+		}
+	}
+	return poll, nil
+}
+
+// Poll polls an operation until its state is one of the terminal states of the policy and returns
+// the body of the final response. data is the body of the response which started the operation,
+// which is returned right away when the operation has already finished. poll requests the state
+// of the operation and returns the body of the response.
+func Poll(ctx context.Context, data []byte, poll func(ctx context.Context) ([]byte, error), policy PollPolicy) ([]byte, error) {
+	if policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		defer cancel()
+	}
+
+	interval := policy.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	for {
+		status, err := PageValue(data, policy.StatusField)
+		if err != nil {
+			return nil, err
+		}
+		for _, until := range policy.Until {
+			if status == until {
+				return data, nil
+			}
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if policy.Timeout > 0 && ctx.Err() == context.DeadlineExceeded {
+				return nil, fmt.Errorf("Operation did not reach %s within %v, its state is %q", strings.Join(policy.Until, " or "), policy.Timeout, status)
+			}
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if data, err = poll(ctx); err != nil {
+			return nil, err
+		}
+		if interval += interval / 2; interval > MaxPollInterval {
+			interval = MaxPollInterval
+		}
+	}
+}
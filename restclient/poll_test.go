@@ -0,0 +1,111 @@
+package restclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationURL(t *testing.T) {
+	requestURL, _ := url.Parse("https://api.example.com/v1/instances")
+	response := func(location string) *http.Response {
+		response := &http.Response{Header: http.Header{}, Request: &http.Request{URL: requestURL}}
+		if location != "" {
+			response.Header.Set("Location", location)
+		}
+		return response
+	}
+
+	operation, err := OperationURL(response("/v1/operations/1"), nil, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.example.com/v1/operations/1", operation)
+
+	operation, err = OperationURL(response(""), nil, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.example.com/v1/instances", operation)
+
+	operation, err = OperationURL(response("/ignored"), []byte(`{"operation":{"self":"operations/2"}}`), "operation.self")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.example.com/v1/operations/2", operation)
+
+	_, err = OperationURL(response(""), []byte(`{}`), "operation.self")
+	assert.EqualError(t, err, "Response has no operation URL in field operation.self")
+}
+
+func TestNewPollRequest(t *testing.T) {
+	request, _ := http.NewRequest(http.MethodPost, "https://api.example.com/v1/instances", nil)
+	request.Header.Set("Authorization", "Bearer token")
+	request.Header.Set("Content-Type", "application/json")
+
+	poll, err := NewPollRequest(request, "https://api.example.com/v1/operations/1")
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodGet, poll.Method)
+	assert.Equal(t, "Bearer token", poll.Header.Get("Authorization"))
+	assert.Empty(t, poll.Header.Get("Content-Type"))
+}
+
+func TestNewPollRequestOtherHost(t *testing.T) {
+	request, _ := http.NewRequest(http.MethodPost, "https://api.example.com/v1/instances", nil)
+	request.Header.Set("Authorization", "Bearer token")
+	request.Header.Set("Cookie", "session=1")
+	request.Header.Set("Idempotency-Key", "key")
+	request.Header.Set("Accept", "application/json")
+
+	poll, err := NewPollRequest(request, "https://operations.example.net/1")
+	assert.NoError(t, err)
+	assert.Empty(t, poll.Header.Get("Authorization"))
+	assert.Empty(t, poll.Header.Get("Cookie"))
+	assert.Empty(t, poll.Header.Get("Idempotency-Key"))
+	assert.Equal(t, "application/json", poll.Header.Get("Accept"))
+	assert.Equal(t, "Bearer token", request.Header.Get("Authorization"))
+
+	poll, err = NewPollRequest(request, "https://API.example.com/v1/operations/1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer token", poll.Header.Get("Authorization"))
+}
+
+func TestPoll(t *testing.T) {
+	policy := PollPolicy{StatusField: "metadata.state", Until: []string{"DONE", "FAILED"}, Interval: time.Millisecond}
+
+	// An operation which has already finished is not polled
+	data, err := Poll(context.Background(), []byte(`{"metadata":{"state":"DONE"}}`), func(ctx context.Context) ([]byte, error) {
+		t.Fatal("unexpected poll")
+		return nil, nil
+	}, policy)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"metadata":{"state":"DONE"}}`, string(data))
+
+	polls := 0
+	data, err = Poll(context.Background(), []byte(`{"metadata":{"state":"RUNNING"}}`), func(ctx context.Context) ([]byte, error) {
+		polls++
+		if polls < 3 {
+			return []byte(`{"metadata":{"state":"RUNNING"}}`), nil
+		}
+		return []byte(`{"metadata":{"state":"FAILED"},"error":"quota"}`), nil
+	}, policy)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, polls)
+	assert.JSONEq(t, `{"metadata":{"state":"FAILED"},"error":"quota"}`, string(data))
+
+	_, err = Poll(context.Background(), []byte(`{"metadata":{"state":"RUNNING"}}`), func(ctx context.Context) ([]byte, error) {
+		return nil, fmt.Errorf("unavailable")
+	}, policy)
+	assert.EqualError(t, err, "unavailable")
+
+	running := func(ctx context.Context) ([]byte, error) {
+		return []byte(`{"metadata":{"state":"RUNNING"}}`), nil
+	}
+	policy.Timeout = 20 * time.Millisecond
+	_, err = Poll(context.Background(), []byte(`{"metadata":{"state":"RUNNING"}}`), running, policy)
+	assert.EqualError(t, err, `Operation did not reach DONE or FAILED within 20ms, its state is "RUNNING"`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Poll(ctx, []byte(`{"metadata":{"state":"RUNNING"}}`), running, policy)
+	assert.Equal(t, context.Canceled, err)
+}
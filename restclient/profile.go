@@ -0,0 +1,74 @@
+package restclient
+
+import (
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	allocatedBytesMetric   = "/gc/heap/allocs:bytes"
+	allocatedObjectsMetric = "/gc/heap/allocs:objects"
+	heapBytesMetric        = "/memory/classes/heap/objects:bytes"
+)
+
+// AllocationStats describes the memory allocated while running a request.
+// The runtime only counts allocations per process, so the allocations of other goroutines running
+// at the same time are included. The stats are therefore most accurate for sequential requests.
+type AllocationStats struct {
+	// Endpoint is the name of the request builder
+	Endpoint string
+	// AllocatedBytes is the number of bytes allocated on the heap
+	AllocatedBytes uint64
+	// Allocations is the number of objects allocated on the heap
+	Allocations uint64
+	// HeapBytes is the size of the heap when the request completed
+	HeapBytes uint64
+	// Duration is the time taken to run the request
+	Duration time.Duration
+}
+
+// AllocationHook receives the allocation stats of each request.
+type AllocationHook func(stats AllocationStats)
+
+var allocationHook atomic.Value
+
+// SetAllocationHook enables the allocation profiling of requests and reports the stats of
+// each request to hook. Supplying nil disables the allocation profiling.
+func SetAllocationHook(hook AllocationHook) {
+	allocationHook.Store(hook)
+}
+
+// ProfileAllocations starts profiling the allocations of a request to the endpoint and returns a
+// function which stops profiling and reports the stats to the allocation hook.
+// Profiling is free when no allocation hook is set.
+func ProfileAllocations(endpoint string) func() {
+	hook, _ := allocationHook.Load().(AllocationHook)
+	if hook == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	before := readAllocationMetrics()
+	return func() {
+		after := readAllocationMetrics()
+		stats := AllocationStats{
+			Endpoint:       endpoint,
+			AllocatedBytes: after[0].Value.Uint64() - before[0].Value.Uint64(),
+			Allocations:    after[1].Value.Uint64() - before[1].Value.Uint64(),
+			HeapBytes:      after[2].Value.Uint64(),
+			Duration:       time.Since(start),
+		}
+		hook(stats)
+	}
+}
+
+func readAllocationMetrics() []metrics.Sample {
+	samples := []metrics.Sample{
+		{Name: allocatedBytesMetric},
+		{Name: allocatedObjectsMetric},
+		{Name: heapBytesMetric},
+	}
+	metrics.Read(samples)
+	return samples
+}
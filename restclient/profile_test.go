@@ -0,0 +1,36 @@
+package restclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var allocationSink [][]byte
+
+func TestProfileAllocations(t *testing.T) {
+	defer SetAllocationHook(nil)
+
+	var reported []AllocationStats
+	stop := ProfileAllocations("GetPhotoDetailsRequestBuilder")
+	stop()
+	assert.Empty(t, reported)
+
+	SetAllocationHook(func(stats AllocationStats) {
+		reported = append(reported, stats)
+	})
+	stop = ProfileAllocations("GetPhotoDetailsRequestBuilder")
+	for i := 0; i < 16; i++ {
+		allocationSink = append(allocationSink, make([]byte, 64<<10))
+	}
+	stop()
+	allocationSink = nil
+
+	if assert.Len(t, reported, 1) {
+		assert.Equal(t, "GetPhotoDetailsRequestBuilder", reported[0].Endpoint)
+		assert.GreaterOrEqual(t, reported[0].AllocatedBytes, uint64(16*64<<10))
+		assert.GreaterOrEqual(t, reported[0].Allocations, uint64(16))
+		assert.NotZero(t, reported[0].HeapBytes)
+		assert.Positive(t, reported[0].Duration)
+	}
+}
@@ -0,0 +1,85 @@
+package restclient
+
+import (
+	"io"
+	"net/http"
+)
+
+// ProgressFunc receives the number of bytes of a request or response body transferred so far along
+// with the size of the body, which is -1 when the size is unknown.
+type ProgressFunc func(transferred int64, total int64)
+
+// TrackUploadProgress reports the progress of sending the body of the request to fn.
+// The progress is reported as the body is read by the transport, so it is reported again from the
+// start when the body is sent again, for example when following a redirect.
+func TrackUploadProgress(req *http.Request, fn ProgressFunc) {
+	if fn == nil || req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+
+	total := req.ContentLength
+	if total <= 0 {
+		total = -1
+	}
+	req.Body = &progressReader{ReadCloser: req.Body, total: total, fn: fn}
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return &progressReader{ReadCloser: body, total: total, fn: fn}, nil
+		}
+	}
+}
+
+// progressReader counts the bytes read from a request body
+type progressReader struct {
+	io.ReadCloser
+	sent  int64
+	total int64
+	fn    ProgressFunc
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.sent += int64(n)
+		r.fn(r.sent, r.total)
+	}
+	return n, err
+}
+
+// DownloadResponse streams the body of the response in to w and returns the number of bytes
+// written. The progress of the download is reported to fn, if any, as the body is written.
+// Responses with a status other than 2xx are not written and return an *HTTPError instead.
+func DownloadResponse(response *http.Response, w io.Writer, fn ProgressFunc) (int64, error) {
+	if err := CheckStatus(response); err != nil {
+		return 0, err
+	}
+	if fn != nil {
+		total := response.ContentLength
+		if total < 0 {
+			total = -1
+		}
+		w = &progressWriter{Writer: w, total: total, fn: fn}
+	}
+	return io.Copy(w, response.Body)
+}
+
+// progressWriter counts the bytes written from a response body
+type progressWriter struct {
+	io.Writer
+	received int64
+	total    int64
+	fn       ProgressFunc
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	if n > 0 {
+		w.received += int64(n)
+		w.fn(w.received, w.total)
+	}
+	return n, err
+}
@@ -0,0 +1,85 @@
+package restclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type progress struct {
+	transferred []int64
+	total       int64
+}
+
+func (p *progress) track(transferred int64, total int64) {
+	p.transferred = append(p.transferred, transferred)
+	p.total = total
+}
+
+func TestTrackUploadProgress(t *testing.T) {
+	request, _ := http.NewRequest("POST", "https://api.example.com/photos", strings.NewReader("0123456789"))
+	var p progress
+	TrackUploadProgress(request, p.track)
+
+	buffer := make([]byte, 4)
+	for {
+		if _, err := request.Body.Read(buffer); err == io.EOF {
+			break
+		}
+	}
+	assert.Equal(t, []int64{4, 8, 10}, p.transferred)
+	assert.Equal(t, int64(10), p.total)
+
+	// The progress starts again when the body is sent again
+	p = progress{}
+	body, err := request.GetBody()
+	assert.NoError(t, err)
+	io.ReadAll(body)
+	assert.Equal(t, int64(10), p.transferred[len(p.transferred)-1])
+
+	// Bodies of unknown size report a total of -1
+	request, _ = http.NewRequest("POST", "https://api.example.com/photos", io.MultiReader(strings.NewReader("01234")))
+	p = progress{}
+	TrackUploadProgress(request, p.track)
+	io.ReadAll(request.Body)
+	assert.Equal(t, []int64{5}, p.transferred)
+	assert.Equal(t, int64(-1), p.total)
+
+	// Requests without a body are left unchanged
+	request, _ = http.NewRequest("GET", "https://api.example.com/photos", nil)
+	TrackUploadProgress(request, p.track)
+	assert.Nil(t, request.Body)
+}
+
+func TestDownloadResponse(t *testing.T) {
+	response := &http.Response{
+		StatusCode:    200,
+		Body:          io.NopCloser(strings.NewReader("0123456789")),
+		ContentLength: 10,
+	}
+	var p progress
+	var buffer bytes.Buffer
+	n, err := DownloadResponse(response, &buffer, p.track)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), n)
+	assert.Equal(t, "0123456789", buffer.String())
+	assert.Equal(t, int64(10), p.transferred[len(p.transferred)-1])
+	assert.Equal(t, int64(10), p.total)
+
+	response = &http.Response{
+		StatusCode:    404,
+		Status:        "404 Not Found",
+		Body:          io.NopCloser(strings.NewReader("Not found")),
+		ContentLength: -1,
+		Request:       &http.Request{Method: "GET"},
+	}
+	buffer.Reset()
+	_, err = DownloadResponse(response, &buffer, p.track)
+	var httpError *HTTPError
+	assert.ErrorAs(t, err, &httpError)
+	assert.Equal(t, 0, buffer.Len())
+}
@@ -0,0 +1,117 @@
+package restclient
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// QueryValues encodes the fields of a struct in to query parameters.
+// The name of the query parameter is taken from the url tag of the field, for example
+// `url:"per_page,omitempty"`, and defaults to the name of the field. Fields tagged with "-"
+// and unexported fields are skipped, and fields with the omitempty option are skipped when they
+// hold the zero value. Every element of a slice or array field is added as a separate value.
+// The fields of embedded structs are encoded as if they were fields of the outer struct.
+// Values are converted to strings with FormatParam. A nil pointer, including a nil embedded
+// struct, encodes no parameters.
+func QueryValues(v interface{}) (url.Values, error) {
+	values := url.Values{}
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return values, nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Query parameters must be encoded from a struct, got %T", v)
+	}
+	if err := addQueryValues(values, value); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// addQueryValues adds the fields of the struct value to values
+func addQueryValues(values url.Values, value reflect.Value) error {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("url")
+		if tag == "-" {
+			continue
+		}
+
+		name, options := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, options = tag[:i], tag[i+1:]
+		}
+
+		fieldValue := value.Field(i)
+		if field.Anonymous && name == "" {
+			embedded := fieldValue
+			for embedded.Kind() == reflect.Ptr && !embedded.IsNil() {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Ptr && !isFormatter(embedded.Type()) {
+				// A nil embedded struct has no fields to encode
+				continue
+			}
+			if embedded.Kind() == reflect.Struct && !isFormatter(embedded.Type()) {
+				if err := addQueryValues(values, embedded); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			// Unexported field
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		if hasOption(options, "omitempty") && fieldValue.IsZero() {
+			continue
+		}
+
+		if kind := fieldValue.Kind(); (kind == reflect.Slice || kind == reflect.Array) && !isFormatter(fieldValue.Type()) {
+			for j := 0; j < fieldValue.Len(); j++ {
+				s, err := FormatParam(fieldValue.Index(j).Interface())
+				if err != nil {
+					return fmt.Errorf("Failed to encode field %s: %v", field.Name, err)
+				}
+				values.Add(name, s)
+			}
+			continue
+		}
+
+		s, err := FormatParam(fieldValue.Interface())
+		if err != nil {
+			return fmt.Errorf("Failed to encode field %s: %v", field.Name, err)
+		}
+		values.Add(name, s)
+	}
+	return nil
+}
+
+// isFormatter returns true if values of the type are converted to strings with their own method
+func isFormatter(t reflect.Type) bool {
+	return t.Implements(textMarshalerType) || t.Implements(stringerType)
+}
+
+// hasOption returns true if the comma separated options of a tag contain option
+func hasOption(options string, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
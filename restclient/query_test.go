@@ -0,0 +1,80 @@
+package restclient
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Paging struct {
+	Page    int `url:"page,omitempty"`
+	PerPage int `url:"per_page"`
+}
+
+type Sort struct {
+	Order string `url:"order"`
+}
+
+func TestQueryValues(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var testCases = []struct {
+		input  interface{}
+		output url.Values
+	}{
+		{
+			struct {
+				Q       string `url:"q"`
+				Feature string `url:"feature,omitempty"`
+				Secret  string `url:"-"`
+				Name    string
+				private string
+			}{Q: "cats", Secret: "s", Name: "n", private: "p"},
+			url.Values{"q": {"cats"}, "Name": {"n"}},
+		},
+		{
+			struct {
+				Tags  []string `url:"tag"`
+				Sizes [2]int   `url:"size"`
+				Empty []string `url:"empty,omitempty"`
+			}{Tags: []string{"a", "b"}, Sizes: [2]int{1, 2}},
+			url.Values{"tag": {"a", "b"}, "size": {"1", "2"}},
+		},
+		{
+			struct {
+				Paging
+				*Sort
+				Q string `url:"q"`
+			}{Paging: Paging{PerPage: 10}, Sort: &Sort{Order: "asc"}, Q: "cats"},
+			url.Values{"per_page": {"10"}, "order": {"asc"}, "q": {"cats"}},
+		},
+		{
+			struct {
+				*Sort
+				Q string `url:"q"`
+			}{Q: "cats"},
+			url.Values{"q": {"cats"}},
+		},
+		{
+			struct {
+				Since time.Time  `url:"since"`
+				Until *time.Time `url:"until,omitempty"`
+			}{Since: since},
+			url.Values{"since": {"2024-01-01T00:00:00Z"}},
+		},
+		{
+			(*Paging)(nil),
+			url.Values{},
+		},
+	}
+
+	for _, tc := range testCases {
+		values, err := QueryValues(tc.input)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.output, values)
+	}
+
+	_, err := QueryValues("cats")
+	assert.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package restclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SingleflightTransport is a http.RoundTripper which shares a single round trip between identical
+// GET and HEAD requests running at the same time, which protects an endpoint from a stampede of
+// requests such as when a cache expires. Requests are identical when their URL and headers are
+// identical. The body of the shared response is read once and every caller receives its own copy,
+// so each caller decodes its own response.
+// A shared round trip runs with the context of the request which started it, so cancelling that
+// request fails the requests waiting for it as well. Requests upgrading the connection, such as
+// WebSocket handshakes, are never shared since the body of their response is the connection.
+type SingleflightTransport struct {
+	// Transport sends the requests, http.DefaultTransport is used when nil
+	Transport http.RoundTripper
+
+	mu       sync.Mutex
+	inflight map[string]*flight
+}
+
+// flight is a round trip shared by identical requests
+type flight struct {
+	done     chan struct{}
+	response *http.Response
+	body     []byte
+	err      error
+}
+
+func (t *SingleflightTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if request.Method != http.MethodGet && request.Method != http.MethodHead ||
+		request.Body != nil && request.Body != http.NoBody || isUpgrade(request) {
+		return transport.RoundTrip(request)
+	}
+
+	key := flightKey(request)
+	t.mu.Lock()
+	if f, ok := t.inflight[key]; ok {
+		t.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.responseTo(request)
+		case <-request.Context().Done():
+			return nil, request.Context().Err()
+		}
+	}
+	f := &flight{done: make(chan struct{})}
+	if t.inflight == nil {
+		t.inflight = make(map[string]*flight)
+	}
+	t.inflight[key] = f
+	t.mu.Unlock()
+
+	defer func() {
+		if f.response == nil && f.err == nil {
+			// The round trip panicked
+			f.err = fmt.Errorf("Shared round trip of %s %s failed", request.Method, request.URL)
+		}
+		t.mu.Lock()
+		delete(t.inflight, key)
+		t.mu.Unlock()
+		close(f.done)
+	}()
+
+	f.response, f.err = transport.RoundTrip(request)
+	if f.err == nil {
+		f.body, f.err = io.ReadAll(f.response.Body)
+		f.response.Body.Close()
+	}
+
+	return f.responseTo(request)
+}
+
+// responseTo returns a copy of the shared response to request
+func (f *flight) responseTo(request *http.Request) (*http.Response, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	response := *f.response
+	response.Header = f.response.Header.Clone()
+	response.Body = io.NopCloser(bytes.NewReader(f.body))
+	response.Request = request
+	return &response, nil
+}
+
+// isUpgrade returns true if the request asks to upgrade the connection to another protocol
+func isUpgrade(request *http.Request) bool {
+	for _, value := range request.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flightKey identifies the requests which are identical
+func flightKey(request *http.Request) string {
+	var key strings.Builder
+	key.WriteString(request.Method)
+	key.WriteString(" ")
+	key.WriteString(request.URL.String())
+
+	names := make([]string, 0, len(request.Header))
+	for name := range request.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key.WriteString("\n")
+		key.WriteString(name)
+		key.WriteString(": ")
+		key.WriteString(strings.Join(request.Header[name], ", "))
+	}
+	return key.String()
+}
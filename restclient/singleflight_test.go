@@ -0,0 +1,184 @@
+package restclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// roundTripFunc is a http.RoundTripper implemented by a function
+type roundTripFunc func(request *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+// waitForFlight waits until a round trip of the transport is in flight
+func waitForFlight(t *testing.T, transport *SingleflightTransport) {
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+		transport.mu.Lock()
+		n := len(transport.inflight)
+		transport.mu.Unlock()
+		if n > 0 {
+			return
+		}
+	}
+	t.Fatal("No round trip in flight")
+}
+
+func TestSingleflightTransport(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		w.Header().Set("X-Photo", "1")
+		io.WriteString(w, "photo")
+	}))
+	defer server.Close()
+
+	transport := &SingleflightTransport{Transport: server.Client().Transport}
+	client := &http.Client{Transport: transport}
+	responses := make([]*http.Response, 5)
+	errs := make([]error, 5)
+	var wg sync.WaitGroup
+	get := func(i int) {
+		defer wg.Done()
+		responses[i], errs[i] = client.Get(server.URL + "/photos/1")
+	}
+
+	wg.Add(len(responses))
+	go get(0)
+	waitForFlight(t, transport)
+	for i := 1; i < len(responses); i++ {
+		go get(i)
+	}
+	// Let the other requests join the round trip in flight
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	for i, response := range responses {
+		if !assert.NoError(t, errs[i]) {
+			continue
+		}
+		// Every response has its own body and headers
+		body, err := io.ReadAll(response.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "photo", string(body))
+		assert.Equal(t, "1", response.Header.Get("X-Photo"))
+		response.Header.Set("X-Photo", "changed")
+		response.Body.Close()
+	}
+}
+
+func TestSingleflightTransportHeaders(t *testing.T) {
+	arrived := make(chan struct{}, 2)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		io.WriteString(w, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &SingleflightTransport{Transport: server.Client().Transport}}
+	bodies := make([]string, 2)
+	var wg sync.WaitGroup
+	wg.Add(len(bodies))
+	for i, token := range []string{"a", "b"} {
+		go func(i int, token string) {
+			defer wg.Done()
+			request, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+			request.Header.Set("Authorization", token)
+			response, err := client.Do(request)
+			if assert.NoError(t, err) {
+				body, _ := io.ReadAll(response.Body)
+				bodies[i] = string(body)
+				response.Body.Close()
+			}
+		}(i, token)
+	}
+
+	// Requests with different headers are sent separately
+	for i := 0; i < 2; i++ {
+		select {
+		case <-arrived:
+		case <-time.After(time.Second):
+			t.Fatal("Requests with different headers were shared")
+		}
+	}
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []string{"a", "b"}, bodies)
+}
+
+func TestSingleflightTransportCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	transport := &SingleflightTransport{Transport: server.Client().Transport}
+	client := &http.Client{Transport: transport}
+	ctx, cancel := context.WithCancel(context.Background())
+	var leaderErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		request, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		_, leaderErr = client.Do(request)
+	}()
+	waitForFlight(t, transport)
+
+	// Cancelling the request which started the round trip fails the requests waiting for it
+	followerCtx, followerCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer followerCancel()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	request, _ := http.NewRequestWithContext(followerCtx, http.MethodGet, server.URL, nil)
+	_, err := client.Do(request)
+	assert.True(t, errors.Is(err, context.Canceled), "unexpected error %v", err)
+	<-done
+	assert.True(t, errors.Is(leaderErr, context.Canceled), "unexpected error %v", leaderErr)
+}
+
+func TestSingleflightTransportPanic(t *testing.T) {
+	release := make(chan struct{})
+	transport := &SingleflightTransport{Transport: roundTripFunc(func(request *http.Request) (*http.Response, error) {
+		<-release
+		panic("transport failed")
+	})}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			assert.Equal(t, "transport failed", recover())
+		}()
+		request, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		transport.RoundTrip(request)
+	}()
+	waitForFlight(t, transport)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+
+	// The requests waiting for a round trip which panicked fail rather than wait forever
+	request, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	_, err := transport.RoundTrip(request)
+	assert.Error(t, err)
+	<-done
+}
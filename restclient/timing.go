@@ -0,0 +1,169 @@
+package restclient
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RequestTimings is the breakdown of the time taken by a request, traced with net/http/httptrace.
+// A phase which did not happen, such as the DNS lookup of a reused connection, has a duration of 0.
+type RequestTimings struct {
+	// Endpoint is the name of the request builder
+	Endpoint string
+	Method   string
+	URL      string
+	// DNS is the time taken to look up the host
+	DNS time.Duration
+	// Connect is the time taken to open the TCP connection
+	Connect time.Duration
+	// TLS is the time taken by the TLS handshake
+	TLS time.Duration
+	// TTFB is the time from sending the request until the first byte of the response is received
+	TTFB time.Duration
+	// Total is the time from sending the request until the body of the response is read or closed
+	Total time.Duration
+	// Reused is true when the request was sent over a connection kept alive by a previous request
+	Reused bool
+	// Err is the error of a request which failed without a response
+	Err error
+}
+
+// TimingHook receives the timings of each request.
+type TimingHook func(timings RequestTimings)
+
+var timingHook atomic.Value
+
+// SetTimingHook enables the tracing of requests and reports the timings of each request to hook.
+// Supplying nil disables the tracing.
+func SetTimingHook(hook TimingHook) {
+	timingHook.Store(hook)
+}
+
+// TraceTimings returns the request to the endpoint traced by net/http/httptrace, along with a
+// function which is called with the result of sending the request. The timings are reported to
+// the timing hook once the body of the response is read to the end or closed.
+// Tracing is free when no timing hook is set.
+func TraceTimings(endpoint string, request *http.Request) (*http.Request, func(*http.Response, error)) {
+	hook, _ := timingHook.Load().(TimingHook)
+	if hook == nil {
+		return request, func(*http.Response, error) {}
+	}
+
+	t := &requestTrace{
+		hook:    hook,
+		start:   time.Now(),
+		timings: RequestTimings{Endpoint: endpoint, Method: request.Method, URL: request.URL.String()},
+	}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mark(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.measure(&t.timings.DNS, &t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mark(&t.connectStart)
+		},
+		ConnectDone: func(string, string, error) {
+			t.measure(&t.timings.Connect, &t.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			t.mark(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.measure(&t.timings.TLS, &t.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.timings.Reused = t.timings.Reused || info.Reused
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.measure(&t.timings.TTFB, &t.start)
+		},
+	}
+	request = request.WithContext(httptrace.WithClientTrace(request.Context(), trace))
+
+	return request, func(response *http.Response, err error) {
+		if err != nil {
+			t.mu.Lock()
+			t.timings.Err = err
+			t.mu.Unlock()
+			t.done()
+			return
+		}
+		if response.StatusCode == http.StatusSwitchingProtocols {
+			// The body is the connection of another protocol, such as a WebSocket
+			t.done()
+			return
+		}
+		response.Body = &tracedBody{ReadCloser: response.Body, trace: t}
+	}
+}
+
+// requestTrace collects the timings of a request. Hedged requests share the trace, so only the
+// first occurrence of each phase is kept.
+type requestTrace struct {
+	hook  TimingHook
+	start time.Time
+
+	mu           sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timings      RequestTimings
+	once         sync.Once
+}
+
+// mark records the start of a phase
+func (t *requestTrace) mark(start *time.Time) {
+	t.mu.Lock()
+	if start.IsZero() {
+		*start = time.Now()
+	}
+	t.mu.Unlock()
+}
+
+// measure records the duration of a phase which started at start
+func (t *requestTrace) measure(d *time.Duration, start *time.Time) {
+	t.mu.Lock()
+	if *d == 0 && !start.IsZero() {
+		*d = time.Since(*start)
+	}
+	t.mu.Unlock()
+}
+
+func (t *requestTrace) done() {
+	t.once.Do(func() {
+		t.mu.Lock()
+		timings := t.timings
+		t.mu.Unlock()
+		timings.Total = time.Since(t.start)
+		t.hook(timings)
+	})
+}
+
+// tracedBody is the body of a traced response, which reports the timings once it is read to the
+// end or closed
+type tracedBody struct {
+	io.ReadCloser
+	trace *requestTrace
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.trace.done()
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	b.trace.done()
+	return b.ReadCloser.Close()
+}
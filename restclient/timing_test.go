@@ -0,0 +1,67 @@
+package restclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceTimings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"id":"abc"}`)
+	}))
+	defer server.Close()
+
+	// Without a hook the request is left as is
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/photos/abc", nil)
+	traced, _ := TraceTimings("GetPhotoRequestBuilder", request)
+	assert.Equal(t, request, traced)
+
+	var reported []RequestTimings
+	SetTimingHook(func(timings RequestTimings) {
+		reported = append(reported, timings)
+	})
+	defer SetTimingHook(nil)
+
+	for i := 0; i < 2; i++ {
+		request, done := TraceTimings("GetPhotoRequestBuilder", request)
+		response, err := server.Client().Do(request)
+		done(response, err)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Empty(t, reported[i:], "the timings are reported once the body is read")
+		io.ReadAll(response.Body)
+		response.Body.Close()
+	}
+
+	if assert.Len(t, reported, 2) {
+		first := reported[0]
+		assert.Equal(t, "GetPhotoRequestBuilder", first.Endpoint)
+		assert.Equal(t, http.MethodGet, first.Method)
+		assert.Equal(t, server.URL+"/photos/abc", first.URL)
+		assert.False(t, first.Reused)
+		assert.True(t, first.Connect > 0)
+		assert.True(t, first.TLS > 0)
+		assert.True(t, first.TTFB > 0)
+		assert.True(t, first.Total >= first.TTFB)
+		assert.NoError(t, first.Err)
+
+		// The second request reuses the connection of the first
+		second := reported[1]
+		assert.True(t, second.Reused)
+		assert.Zero(t, second.Connect)
+		assert.Zero(t, second.TLS)
+	}
+
+	failed := errors.New("connection refused")
+	_, done := TraceTimings("GetPhotoRequestBuilder", request)
+	done(nil, failed)
+	if assert.Len(t, reported, 3) {
+		assert.Equal(t, failed, reported[2].Err)
+	}
+}
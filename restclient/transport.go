@@ -0,0 +1,129 @@
+package restclient
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ClientOption tunes the transport of the requests sent by a client, see NewDefaultClient
+type ClientOption func(transport *http.Transport)
+
+// WithMaxIdleConnsPerHost sets the number of idle connections kept open to each host, which
+// defaults to 2. Clients sending many requests at the same time to a single API should raise it.
+func WithMaxIdleConnsPerHost(n int) ClientOption {
+	return func(transport *http.Transport) {
+		transport.MaxIdleConnsPerHost = n
+		if transport.MaxIdleConns != 0 && transport.MaxIdleConns < n {
+			transport.MaxIdleConns = n
+		}
+	}
+}
+
+// WithIdleConnTimeout sets how long an idle connection is kept open
+func WithIdleConnTimeout(timeout time.Duration) ClientOption {
+	return func(transport *http.Transport) {
+		transport.IdleConnTimeout = timeout
+	}
+}
+
+// WithTLSHandshakeTimeout sets how long to wait for a TLS handshake
+func WithTLSHandshakeTimeout(timeout time.Duration) ClientOption {
+	return func(transport *http.Transport) {
+		transport.TLSHandshakeTimeout = timeout
+	}
+}
+
+// WithHTTP2 sets whether HTTP/2 is attempted, which it is by default
+func WithHTTP2(enabled bool) ClientOption {
+	return func(transport *http.Transport) {
+		transport.ForceAttemptHTTP2 = enabled
+	}
+}
+
+// WithKeepAlives sets whether connections are reused for several requests, which they are by default
+func WithKeepAlives(enabled bool) ClientOption {
+	return func(transport *http.Transport) {
+		transport.DisableKeepAlives = !enabled
+	}
+}
+
+// WithTLSConfig sets the configuration of TLS connections, such as the certificate authorities
+// trusted by the client or a client certificate
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return func(transport *http.Transport) {
+		transport.TLSClientConfig = config.Clone()
+	}
+}
+
+// WithUnixSocket dials the Unix domain socket at path for every request, whatever the host of its
+// URL. Requests are not sent through a proxy.
+func WithUnixSocket(path string) ClientOption {
+	return func(transport *http.Transport) {
+		var dialer net.Dialer
+		transport.DialContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		}
+		transport.Proxy = nil
+	}
+}
+
+// WithResolve connects to address instead of host, like the --resolve option of curl, which points a
+// client at a canary instance or a local stub without changing its base URL. The URL, and so the
+// Host header and the server name of TLS connections, are left unchanged.
+// The host and address may omit the port, in which case any port of the host is resolved and the
+// port of the URL is kept. Example: WithResolve("api.example.com", "10.0.0.12")
+func WithResolve(host string, address string) ClientOption {
+	return func(transport *http.Transport) {
+		dial := transport.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{}).DialContext
+		}
+		transport.DialContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			hostname, port, err := net.SplitHostPort(addr)
+			if err == nil && (addr == host || hostname == host) {
+				addr = address
+				if _, _, err := net.SplitHostPort(address); err != nil {
+					addr = net.JoinHostPort(address, port)
+				}
+			}
+			return dial(ctx, network, addr)
+		}
+	}
+}
+
+// NewTransport returns a copy of http.DefaultTransport tuned with the options. It is used to tune
+// a transport which is wrapped by another transport, such as SingleflightTransport.
+func NewTransport(options ...ClientOption) *http.Transport {
+	return tuneTransport(http.DefaultTransport.(*http.Transport), options)
+}
+
+// tuneTransport returns a copy of the transport tuned with the options
+func tuneTransport(transport *http.Transport, options []ClientOption) *http.Transport {
+	tuned := transport.Clone()
+	for _, option := range options {
+		option(tuned)
+	}
+	return tuned
+}
+
+// tuneClient returns a copy of client whose transport is tuned with the options, leaving client
+// unchanged. A nil client is tuned as if it was the zero http.Client.
+func tuneClient(client *http.Client, options []ClientOption) *http.Client {
+	tuned := &http.Client{}
+	if client != nil {
+		*tuned = *client
+	}
+	switch transport := tuned.Transport.(type) {
+	case nil:
+		tuned.Transport = NewTransport(options...)
+	case *http.Transport:
+		tuned.Transport = tuneTransport(transport, options)
+	default:
+		panic(fmt.Sprintf("Client options can only tune a *http.Transport, got %T. Tune the transport it wraps with NewTransport instead", transport))
+	}
+	return tuned
+}
@@ -0,0 +1,107 @@
+package restclient
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDefaultClientOptions(t *testing.T) {
+	base := &http.Client{Timeout: time.Second}
+	client := NewDefaultClient("http://example.com", false, base,
+		WithMaxIdleConnsPerHost(64),
+		WithIdleConnTimeout(time.Minute),
+		WithTLSHandshakeTimeout(3*time.Second),
+		WithHTTP2(false),
+		WithKeepAlives(false),
+	)
+
+	// The client is copied with a tuned copy of the default transport
+	assert.Nil(t, base.Transport)
+	assert.Equal(t, time.Second, client.HttpClient().Timeout)
+	transport, ok := client.HttpClient().Transport.(*http.Transport)
+	if assert.True(t, ok) {
+		assert.Equal(t, 64, transport.MaxIdleConnsPerHost)
+		assert.True(t, transport.MaxIdleConns >= 64)
+		assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+		assert.Equal(t, 3*time.Second, transport.TLSHandshakeTimeout)
+		assert.False(t, transport.ForceAttemptHTTP2)
+		assert.True(t, transport.DisableKeepAlives)
+		assert.NotNil(t, transport.Proxy)
+	}
+	assert.Equal(t, 0, http.DefaultTransport.(*http.Transport).MaxIdleConnsPerHost)
+
+	// Without options the client is used as is
+	assert.Same(t, base, NewDefaultClient("http://example.com", false, base).HttpClient())
+
+	assert.Panics(t, func() {
+		NewDefaultClient("http://example.com", false, &http.Client{Transport: &SingleflightTransport{}}, WithHTTP2(false))
+	})
+}
+
+func TestUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "api.sock")
+	listener, err := net.Listen("unix", socket)
+	if !assert.NoError(t, err) {
+		return
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.Path)
+	}))
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	client := NewDefaultClient("unix://"+socket+":/v1/", false, nil)
+	assert.Equal(t, "http://localhost/v1", client.BaseURL())
+	response, err := client.HttpClient().Get(client.BaseURL() + "/containers")
+	if assert.NoError(t, err) {
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		assert.Equal(t, "/v1/containers", string(body))
+	}
+
+	client = NewDefaultClient("unix://"+socket, false, nil)
+	assert.Equal(t, "http://localhost", client.BaseURL())
+}
+
+func TestResolve(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Host)
+	}))
+	defer server.Close()
+	address := server.Listener.Addr().String()
+	_, port, _ := net.SplitHostPort(address)
+
+	for _, tc := range []struct {
+		host    string
+		address string
+	}{
+		{"api.example.com", address},
+		{"api.example.com:" + port, address},
+		{"api.example.com", "127.0.0.1"},
+	} {
+		client := NewDefaultClient("http://api.example.com:"+port, false, nil, WithResolve(tc.host, tc.address))
+		response, err := client.HttpClient().Get(client.BaseURL() + "/photos")
+		if assert.NoError(t, err) {
+			// The Host header is left unchanged
+			body, _ := io.ReadAll(response.Body)
+			response.Body.Close()
+			assert.Equal(t, "api.example.com:"+port, string(body))
+		}
+	}
+
+	// Other hosts are resolved as usual
+	client := NewDefaultClient(server.URL, false, nil, WithResolve("api.example.com", "192.0.2.1:1"))
+	response, err := client.HttpClient().Get(client.BaseURL())
+	if assert.NoError(t, err) {
+		response.Body.Close()
+	}
+}
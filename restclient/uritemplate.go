@@ -0,0 +1,280 @@
+package restclient
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/jsaund/gorest/internal/uritemplate"
+)
+
+// URITemplate is a URI template (RFC 6570), such as /users/{id}/photos{?fields*}, whose
+// expressions are expanded with the values of their variables. The endpoints of request builders
+// are URI templates whose variables are set by their @PATH methods.
+type URITemplate struct {
+	template *uritemplate.Template
+}
+
+// TemplateVariable is a variable of an expression of a URI template
+type TemplateVariable = uritemplate.Variable
+
+// Encoded is a value of a URI template variable which is already percent-encoded by the caller,
+// and expanded as is
+type Encoded string
+
+// templateOperator describes the expansion of the expressions of an operator, per section 3.2.1 of RFC 6570
+type templateOperator struct {
+	first         string
+	separator     string
+	named         bool
+	ifEmpty       string
+	allowReserved bool
+}
+
+var templateOperators = map[byte]templateOperator{
+	0:   {first: "", separator: ","},
+	'+': {first: "", separator: ",", allowReserved: true},
+	'.': {first: ".", separator: "."},
+	'/': {first: "/", separator: "/"},
+	';': {first: ";", separator: ";", named: true},
+	'?': {first: "?", separator: "&", named: true, ifEmpty: "="},
+	'&': {first: "&", separator: "&", named: true, ifEmpty: "="},
+	'#': {first: "#", separator: ",", allowReserved: true},
+}
+
+// ParseURITemplate parses a URI template such as /users/{id}/photos{?fields*,page}
+func ParseURITemplate(template string) (*URITemplate, error) {
+	t, err := uritemplate.Parse(template)
+	if err != nil {
+		return nil, err
+	}
+	return &URITemplate{template: t}, nil
+}
+
+// Variables returns the variables of the expressions of the template
+func (t *URITemplate) Variables() []TemplateVariable {
+	return t.template.Variables()
+}
+
+// Expand returns the URI of the template with its expressions expanded with values. A value is
+// either a string, an Encoded string, a slice or an array expanded as a list, a map expanded as
+// pairs sorted by key, or any other value formatted with FormatParam. Variables without a value,
+// or with an empty list or map, are undefined and omitted from the expansion.
+func (t *URITemplate) Expand(values map[string]interface{}) (string, error) {
+	var b strings.Builder
+	for i, e := range t.template.Expressions {
+		b.WriteString(t.template.Literals[i])
+		if err := expandExpression(&b, e, values); err != nil {
+			return "", err
+		}
+	}
+	b.WriteString(t.template.Literals[len(t.template.Literals)-1])
+	return b.String(), nil
+}
+
+// expandExpression writes the expansion of the expression e with values to b
+func expandExpression(b *strings.Builder, e uritemplate.Expression, values map[string]interface{}) error {
+	op := templateOperators[e.Operator]
+	first := true
+	for _, v := range e.Variables {
+		value, err := templateValue(values[v.Name])
+		if err != nil {
+			return fmt.Errorf("Failed to format parameter %s: %w", v.Name, err)
+		}
+		if value == nil {
+			continue
+		}
+		if first {
+			b.WriteString(op.first)
+			first = false
+		} else {
+			b.WriteString(op.separator)
+		}
+
+		switch value := value.(type) {
+		case string:
+			if v.Prefix > 0 && utf8.RuneCountInString(value) > v.Prefix {
+				value = string([]rune(value)[:v.Prefix])
+			}
+			writeNamed(b, op, v.Name, value == "")
+			b.WriteString(templateEscape(value, op.allowReserved))
+		case Encoded:
+			writeNamed(b, op, v.Name, value == "")
+			b.WriteString(string(value))
+		case []string:
+			if !v.Explode {
+				writeNamed(b, op, v.Name, false)
+				for i, item := range value {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					b.WriteString(templateEscape(item, op.allowReserved))
+				}
+				continue
+			}
+			for i, item := range value {
+				if i > 0 {
+					b.WriteString(op.separator)
+				}
+				if op.named {
+					writeNamed(b, op, v.Name, item == "")
+				}
+				b.WriteString(templateEscape(item, op.allowReserved))
+			}
+		case [][2]string:
+			if !v.Explode {
+				writeNamed(b, op, v.Name, false)
+				for i, pair := range value {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					b.WriteString(templateEscape(pair[0], op.allowReserved) + "," + templateEscape(pair[1], op.allowReserved))
+				}
+				continue
+			}
+			for i, pair := range value {
+				if i > 0 {
+					b.WriteString(op.separator)
+				}
+				b.WriteString(templateEscape(pair[0], op.allowReserved))
+				if pair[1] == "" && op.named {
+					b.WriteString(op.ifEmpty)
+				} else {
+					b.WriteString("=" + templateEscape(pair[1], op.allowReserved))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeNamed writes the name of a variable of a named expression, such as fields= in {?fields}
+func writeNamed(b *strings.Builder, op templateOperator, name string, empty bool) {
+	if !op.named {
+		return
+	}
+	b.WriteString(name)
+	if empty {
+		b.WriteString(op.ifEmpty)
+	} else {
+		b.WriteByte('=')
+	}
+}
+
+// templateValue returns the value of a variable as a string, an Encoded string, a list of strings
+// or a list of pairs, or nil when the variable is undefined
+func templateValue(value interface{}) (interface{}, error) {
+	if v := reflect.ValueOf(value); value == nil || v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, nil
+	}
+	switch value.(type) {
+	case string, Encoded:
+		return value, nil
+	case encoding.TextMarshaler, fmt.Stringer:
+		// Lists which format themselves are expanded as a string
+		return FormatParam(value)
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		if v.Len() == 0 {
+			return nil, nil
+		}
+		list := make([]string, v.Len())
+		for i := range list {
+			s, err := FormatParam(v.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			list[i] = s
+		}
+		return list, nil
+	case reflect.Map:
+		if v.Len() == 0 {
+			return nil, nil
+		}
+		pairs := make([][2]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			k, err := FormatParam(key.Interface())
+			if err != nil {
+				return nil, err
+			}
+			s, err := FormatParam(v.MapIndex(key).Interface())
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, [2]string{k, s})
+		}
+		sort.Slice(pairs, func(i, j int) bool {
+			return pairs[i][0] < pairs[j][0]
+		})
+		return pairs, nil
+	}
+	return FormatParam(value)
+}
+
+// templateEscape percent-encodes the characters of s other than the unreserved characters, along
+// with the reserved characters and percent-encoded triplets when allowReserved is true
+func templateEscape(s string, allowReserved bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case isAlphaNumeric(c) || strings.IndexByte("-._~", c) >= 0:
+			b.WriteByte(c)
+		case allowReserved && strings.IndexByte(":/?#[]@!$&'()*+,;=", c) >= 0:
+			b.WriteByte(c)
+		case allowReserved && c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b.WriteString(s[i : i+3])
+			i += 2
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func isAlphaNumeric(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+var uriTemplates sync.Map
+
+// ExpandURITemplate expands the URI template with the values of its variables, see URITemplate.Expand.
+// Templates are parsed once and cached, as the templates of request builders are constant.
+func ExpandURITemplate(template string, values map[string]interface{}) (string, error) {
+	t, ok := uriTemplates.Load(template)
+	if !ok {
+		parsed, err := ParseURITemplate(template)
+		if err != nil {
+			return "", err
+		}
+		t, _ = uriTemplates.LoadOrStore(template, parsed)
+	}
+	return t.(*URITemplate).Expand(values)
+}
+
+// AddRawQuery appends the encoded query parameters rawQuery to the query of the request, such as
+// the query expanded from the URI template of its endpoint
+func AddRawQuery(request *http.Request, rawQuery string) {
+	if rawQuery == "" {
+		return
+	}
+	if request.URL.RawQuery != "" {
+		rawQuery = request.URL.RawQuery + "&" + rawQuery
+	}
+	request.URL.RawQuery = rawQuery
+}
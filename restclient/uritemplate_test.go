@@ -0,0 +1,73 @@
+package restclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandURITemplate(t *testing.T) {
+	// The variables and expansions of section 3.2 of RFC 6570
+	values := map[string]interface{}{
+		"count": []string{"one", "two", "three"},
+		"dom":   []string{"example", "com"},
+		"hello": "Hello World!",
+		"path":  "/foo/bar",
+		"var":   "value",
+		"x":     1024,
+		"y":     768,
+		"empty": "",
+		"keys":  map[string]string{"semi": ";", "dot": ".", "comma": ","},
+		"list":  []string{"red", "green", "blue"},
+		"none":  []string(nil),
+		"id":    Encoded("a%2Fb"),
+	}
+	testCases := []struct {
+		template string
+		expected string
+	}{
+		{"/photos/{var}", "/photos/value"},
+		{"{hello}", "Hello%20World%21"},
+		{"{+hello}", "Hello%20World!"},
+		{"{+path}/here", "/foo/bar/here"},
+		{"{#path:6}/here", "#/foo/b/here"},
+		{"{x,y}", "1024,768"},
+		{"{var:3}", "val"},
+		{"{list}", "red,green,blue"},
+		{"{keys}", "comma,%2C,dot,.,semi,%3B"},
+		{"{keys*}", "comma=%2C,dot=.,semi=%3B"},
+		{"{+keys*}", "comma=,,dot=.,semi=;"},
+		{"X{.dom*}", "X.example.com"},
+		{"{/list*,path:4}", "/red/green/blue/%2Ffoo"},
+		{"{;x,y,empty}", ";x=1024;y=768;empty"},
+		{"{;list*}", ";list=red;list=green;list=blue"},
+		{"{?x,y,empty}", "?x=1024&y=768&empty="},
+		{"{?list*}", "?list=red&list=green&list=blue"},
+		{"{?keys*}", "?comma=%2C&dot=.&semi=%3B"},
+		{"?fixed=yes{&x}", "?fixed=yes&x=1024"},
+		{"/photos{?none,undefined}", "/photos"},
+		{"/files/{id}", "/files/a%2Fb"},
+	}
+	for _, tc := range testCases {
+		expanded, err := ExpandURITemplate(tc.template, values)
+		assert.NoError(t, err, tc.template)
+		assert.Equal(t, tc.expected, expanded, tc.template)
+	}
+}
+
+func TestParseURITemplate(t *testing.T) {
+	template, err := ParseURITemplate("/users/{id}/photos{?fields*,page}")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []TemplateVariable{
+		{Name: "id"},
+		{Name: "fields", Operator: '?', Explode: true},
+		{Name: "page", Operator: '?'},
+	}, template.Variables())
+	assert.False(t, template.Variables()[0].Optional())
+	assert.True(t, template.Variables()[2].Optional())
+
+	_, err = ParseURITemplate("/users/{id")
+	assert.EqualError(t, err, "Unterminated expression {id")
+}
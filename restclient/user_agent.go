@@ -0,0 +1,42 @@
+package restclient
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+const modulePath = "github.com/jsaund/gorest"
+
+// DefaultUserAgent is the User-Agent header of requests sent with a client which does not set
+// one, such as gorest/v1.2.0 go/go1.22.1. The version of gorest is read from the build information
+// of the binary and is devel when gorest is not built as a module dependency.
+var DefaultUserAgent = "gorest/" + moduleVersion() + " go/" + runtime.Version()
+
+// moduleVersion returns the version of the gorest module the binary is built with
+func moduleVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Path == modulePath && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			return info.Main.Version
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				if dep.Replace != nil && dep.Replace.Version != "" {
+					return dep.Replace.Version
+				}
+				return dep.Version
+			}
+		}
+	}
+	return "devel"
+}
+
+// UserAgent returns the User-Agent header of requests sent with the client, which is the
+// UserAgent of clients implementing interface{ UserAgent() string } and DefaultUserAgent otherwise.
+func UserAgent(client Client) string {
+	if c, ok := client.(interface{ UserAgent() string }); ok {
+		if userAgent := c.UserAgent(); userAgent != "" {
+			return userAgent
+		}
+	}
+	return DefaultUserAgent
+}
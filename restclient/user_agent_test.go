@@ -0,0 +1,22 @@
+package restclient
+
+import (
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserAgent(t *testing.T) {
+	assert.True(t, strings.HasPrefix(DefaultUserAgent, "gorest/"))
+	assert.True(t, strings.HasSuffix(DefaultUserAgent, " go/"+runtime.Version()))
+
+	client := NewDefaultClient("http://example.com", false, http.DefaultClient)
+	assert.Equal(t, DefaultUserAgent, UserAgent(client))
+	client.SetUserAgent("photos/1.0")
+	assert.Equal(t, "photos/1.0", UserAgent(client))
+	client.SetUserAgent("")
+	assert.Equal(t, DefaultUserAgent, UserAgent(client))
+}
@@ -0,0 +1,254 @@
+package restclient
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"unicode/utf8"
+)
+
+// websocketGUID is appended to the key of the handshake to compute the accept key, see RFC 6455
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// MaxWebSocketMessageSize is the size in bytes of the largest message received by a WebSocket
+const MaxWebSocketMessageSize = 32 << 20
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// WebSocketCloseError is returned by Receive when the server closes the connection with a status
+// other than a normal closure.
+type WebSocketCloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *WebSocketCloseError) Error() string {
+	return fmt.Sprintf("WebSocket closed with status %d: %s", e.Code, e.Reason)
+}
+
+// UpgradeWebSocket adds the headers requesting the upgrade of request to a WebSocket connection.
+// The request is sent with the http.Client of the rest client, so the connection uses the same
+// transport, TLS configuration and headers as any other request.
+func UpgradeWebSocket(request *http.Request) {
+	var key [16]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		panic(fmt.Sprintf("Failed to generate WebSocket key: %v", err))
+	}
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	request.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key[:]))
+}
+
+// WebSocket is a connection to a WebSocket endpoint which sends messages of type T and receives
+// messages of type U. Messages are framed as JSON text messages.
+// Send and Receive may each be called by one goroutine at a time, while Close may be called from
+// any goroutine to end the connection.
+type WebSocket[T any, U any] struct {
+	conn   io.ReadWriteCloser
+	reader *bufio.Reader
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// NewWebSocket returns the connection of response, the response to a request upgraded with
+// UpgradeWebSocket. Returns an *HTTPError when the server did not switch protocols.
+func NewWebSocket[T any, U any](response *http.Response) (*WebSocket[T, U], error) {
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		defer response.Body.Close()
+		if err := CheckStatus(response); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("WebSocket handshake failed with status %s", response.Status)
+	}
+	conn, ok := response.Body.(io.ReadWriteCloser)
+	if !ok {
+		response.Body.Close()
+		return nil, fmt.Errorf("WebSocket handshake failed, the connection cannot be written to")
+	}
+
+	accept := sha1.Sum([]byte(response.Request.Header.Get("Sec-WebSocket-Key") + websocketGUID))
+	if response.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(accept[:]) {
+		conn.Close()
+		return nil, fmt.Errorf("WebSocket handshake failed, the server did not accept the key")
+	}
+	return &WebSocket[T, U]{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// Send sends the message encoded as JSON
+func (ws *WebSocket[T, U]) Send(message T) error {
+	data, err := MarshalJSON(message)
+	if err != nil {
+		return err
+	}
+	return ws.writeFrame(opText, data)
+}
+
+// Receive waits for the next message and decodes it from JSON.
+// Returns io.EOF once the server closes the connection normally.
+func (ws *WebSocket[T, U]) Receive() (message U, err error) {
+	data, err := ws.readMessage()
+	if err != nil {
+		return message, err
+	}
+	if err := UnmarshalJSON(data, &message); err != nil {
+		return message, &DecodeError{Type: "WebSocket message", Err: err}
+	}
+	return message, nil
+}
+
+// Close sends a normal closure to the server and closes the connection
+func (ws *WebSocket[T, U]) Close() error {
+	ws.writeFrame(opClose, closePayload(1000, ""))
+	return ws.conn.Close()
+}
+
+// readMessage returns the payload of the next data message, answering control frames on the way
+func (ws *WebSocket[T, U]) readMessage() ([]byte, error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case opPing:
+			if err := ws.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+		case opPong:
+		case opClose:
+			// The status of the close frame is echoed, a close frame without a status has the status 1005
+			code, reason, echo := 1005, "", []byte(nil)
+			if len(payload) >= 2 {
+				code, reason, echo = int(binary.BigEndian.Uint16(payload)), string(payload[2:]), payload[:2]
+			}
+			ws.writeFrame(opClose, echo)
+			ws.conn.Close()
+			if code == 1000 || code == 1001 || code == 1005 {
+				return nil, io.EOF
+			}
+			return nil, &WebSocketCloseError{Code: code, Reason: reason}
+		case opText, opBinary, opContinuation:
+			if opcode == opContinuation && message == nil || opcode != opContinuation && message != nil {
+				return nil, fmt.Errorf("WebSocket message is fragmented incorrectly")
+			}
+			if len(message)+len(payload) > MaxWebSocketMessageSize {
+				return nil, fmt.Errorf("WebSocket message exceeds %d bytes", MaxWebSocketMessageSize)
+			}
+			message = append(message, payload...)
+			if message == nil {
+				message = []byte{}
+			}
+			if fin {
+				if opcode == opText && !utf8.Valid(message) {
+					return nil, fmt.Errorf("WebSocket text message is not valid UTF-8")
+				}
+				return message, nil
+			}
+		default:
+			return nil, fmt.Errorf("WebSocket frame has the unknown opcode %d", opcode)
+		}
+	}
+}
+
+// readFrame reads a single frame
+func (ws *WebSocket[T, U]) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(ws.reader, header[:]); err != nil {
+		return
+	}
+	fin, opcode = header[0]&0x80 != 0, header[0]&0x0f
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err = io.ReadFull(ws.reader, extended[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err = io.ReadFull(ws.reader, extended[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if length > MaxWebSocketMessageSize {
+		err = fmt.Errorf("WebSocket message exceeds %d bytes", MaxWebSocketMessageSize)
+		return
+	}
+
+	var mask [4]byte
+	masked := header[1]&0x80 != 0
+	if masked {
+		if _, err = io.ReadFull(ws.reader, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(ws.reader, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+// writeFrame writes a single masked frame, as every frame sent by a client must be masked
+func (ws *WebSocket[T, U]) writeFrame(opcode byte, payload []byte) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	if ws.closed {
+		return fmt.Errorf("WebSocket is closed")
+	}
+	if opcode == opClose {
+		ws.closed = true
+	}
+
+	frame := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, 0x80|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := ws.conn.Write(frame)
+	return err
+}
+
+// closePayload returns the payload of a close frame with the status code and reason
+func closePayload(code int, reason string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(code)), reason...)
+}
@@ -0,0 +1,158 @@
+package restclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// webSocketServer upgrades the request and calls serve with the connection, which writes the frames
+// of the server unmasked
+func webSocketServer(t *testing.T, serve func(conn *WebSocket[string, string], write func(opcode byte, payload []byte))) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" {
+			http.Error(w, "not a websocket", http.StatusBadRequest)
+			return
+		}
+		accept := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + websocketGUID))
+		netConn, rw, err := w.(http.Hijacker).Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer netConn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(accept[:]) + "\r\n\r\n")
+		rw.Flush()
+
+		write := func(opcode byte, payload []byte) {
+			frame := append([]byte{0x80 | opcode, byte(len(payload))}, payload...)
+			netConn.Write(frame)
+		}
+		serve(&WebSocket[string, string]{conn: netConn, reader: bufio.NewReader(rw)}, write)
+	}))
+}
+
+func dialWebSocket(t *testing.T, ctx context.Context, server *httptest.Server) (*WebSocket[string, string], error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	UpgradeWebSocket(request)
+	response, err := server.Client().Do(request)
+	if err != nil {
+		return nil, err
+	}
+	return NewWebSocket[string, string](response)
+}
+
+func TestWebSocket(t *testing.T) {
+	server := webSocketServer(t, func(conn *WebSocket[string, string], write func(opcode byte, payload []byte)) {
+		for {
+			_, opcode, payload, err := conn.readFrame()
+			if err != nil || opcode == opClose {
+				write(opClose, closePayload(4000, "bye"))
+				return
+			}
+			// Echo the message after a ping, split in to two fragments
+			write(opPing, []byte("ping"))
+			if _, opcode, _, err := conn.readFrame(); err != nil || opcode != opPong {
+				return
+			}
+			frame := append([]byte{opcode, byte(len(payload) / 2)}, payload[:len(payload)/2]...)
+			frame = append(frame, 0x80|opContinuation, byte(len(payload)-len(payload)/2))
+			conn.conn.Write(append(frame, payload[len(payload)/2:]...))
+		}
+	})
+	defer server.Close()
+
+	// The connection outlives the context of the handshake
+	ctx, cancel := context.WithCancel(context.Background())
+	ws, err := dialWebSocket(t, ctx, server)
+	cancel()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, ws.Send("hello"))
+	message, err := ws.Receive()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", message)
+
+	assert.NoError(t, ws.writeFrame(opClose, closePayload(1000, "")))
+	_, err = ws.Receive()
+	assert.Equal(t, &WebSocketCloseError{Code: 4000, Reason: "bye"}, err)
+	assert.Error(t, ws.Send("closed"))
+}
+
+func TestWebSocketSingleflight(t *testing.T) {
+	server := webSocketServer(t, func(conn *WebSocket[string, string], write func(opcode byte, payload []byte)) {
+		_, _, payload, err := conn.readFrame()
+		if err == nil {
+			write(opText, payload)
+		}
+	})
+	defer server.Close()
+
+	// The handshake is not shared, so the connection is handed to the caller
+	client := &http.Client{Transport: &SingleflightTransport{Transport: server.Client().Transport}}
+	request, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	UpgradeWebSocket(request)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		response, err := client.Do(request)
+		if !assert.NoError(t, err) {
+			return
+		}
+		ws, err := NewWebSocket[string, string](response)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer ws.Close()
+		assert.NoError(t, ws.Send("hello"))
+		message, err := ws.Receive()
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", message)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("WebSocket handshake blocked on the shared round trip")
+	}
+}
+
+func TestWebSocketNormalClosure(t *testing.T) {
+	server := webSocketServer(t, func(conn *WebSocket[string, string], write func(opcode byte, payload []byte)) {
+		write(opText, []byte(`"last"`))
+		write(opClose, closePayload(1000, ""))
+		conn.readFrame()
+	})
+	defer server.Close()
+
+	ws, err := dialWebSocket(t, context.Background(), server)
+	if !assert.NoError(t, err) {
+		return
+	}
+	message, err := ws.Receive()
+	assert.NoError(t, err)
+	assert.Equal(t, "last", message)
+	_, err = ws.Receive()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestWebSocketHandshakeFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := dialWebSocket(t, context.Background(), server)
+	if httpErr, ok := err.(*HTTPError); assert.True(t, ok) {
+		assert.Equal(t, http.StatusUnauthorized, httpErr.StatusCode)
+	}
+}
@@ -0,0 +1,47 @@
+// Package zstddict implements restclient.Dictionary with zstd dictionaries.
+package zstddict
+
+import (
+	"github.com/jsaund/gorest/restclient"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Dictionary compresses and decompresses bodies with a zstd dictionary
+type Dictionary struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// New returns the zstd dictionary of data. The dictionary should be trained on samples of the
+// payloads of an API, for example with `zstd --train`.
+func New(data []byte) (*Dictionary, error) {
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderDict(data))
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderDicts(data))
+	if err != nil {
+		return nil, err
+	}
+	return &Dictionary{encoder, decoder}, nil
+}
+
+// Register registers the zstd dictionary of data under name with restclient.RegisterDictionary
+func Register(name string, data []byte) error {
+	d, err := New(data)
+	if err != nil {
+		return err
+	}
+	restclient.RegisterDictionary(name, d)
+	return nil
+}
+
+// Encode compresses data with the dictionary
+func (d *Dictionary) Encode(data []byte) []byte {
+	return d.encoder.EncodeAll(data, nil)
+}
+
+// Decode decompresses data compressed with the dictionary
+func (d *Dictionary) Decode(data []byte) ([]byte, error) {
+	return d.decoder.DecodeAll(data, nil)
+}
@@ -0,0 +1,34 @@
+package zstddict
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/klauspost/compress/dict"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDictionary(t *testing.T) {
+	var samples [][]byte
+	for i := 0; i < 500; i++ {
+		samples = append(samples, []byte(fmt.Sprintf(`{"id":"%d","title":"photo number %d","tags":["nature"]}`, i, i*7)))
+	}
+	data, err := dict.BuildZstdDict(samples, dict.Options{MaxDictSize: 4096, HashBytes: 6, ZstdDictID: 1})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	d, err := New(data)
+	if !assert.NoError(t, err) {
+		return
+	}
+	payload := []byte(`{"id":"1000","title":"photo number 7000","tags":["nature"]}`)
+	compressed := d.Encode(payload)
+	assert.True(t, len(compressed) < len(payload))
+	decompressed, err := d.Decode(compressed)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, decompressed)
+
+	_, err = New([]byte("not a dictionary"))
+	assert.Error(t, err)
+}
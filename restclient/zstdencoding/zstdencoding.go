@@ -0,0 +1,26 @@
+// Package zstdencoding decompresses responses with the zstd content encoding with restclient.DecompressTransport.
+package zstdencoding
+
+import (
+	"io"
+
+	"github.com/jsaund/gorest/restclient"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Encoding is the content encoding of bodies compressed with zstd
+const Encoding = "zstd"
+
+// Register registers the zstd decoder with restclient.RegisterContentDecoder
+func Register() {
+	restclient.RegisterContentDecoder(Encoding, NewReader)
+}
+
+// NewReader returns a reader of the zstd compressed body r
+func NewReader(r io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
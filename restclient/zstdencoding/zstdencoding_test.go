@@ -0,0 +1,38 @@
+package zstdencoding
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jsaund/gorest/restclient"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecompressTransport(t *testing.T) {
+	payload := []byte(`{"id":"1","title":"photo"}`)
+	encoder, _ := zstd.NewWriter(nil)
+	compressed := encoder.EncodeAll(payload, nil)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "zstd, gzip", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", Encoding)
+		w.Write(compressed)
+	}))
+	defer server.Close()
+
+	Register()
+	client := &http.Client{Transport: &restclient.DecompressTransport{Encodings: []string{"zstd", "br", "gzip"}}}
+	response, err := client.Get(server.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, body)
+	assert.Empty(t, response.Header.Get("Content-Encoding"))
+	assert.True(t, response.Uncompressed)
+}